@@ -1,17 +1,55 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel"
+	"io"
 	"log/slog"
 	"main.go/internal/config"
+	Dellete "main.go/internal/http-server/handlers/Delete"
+	"main.go/internal/http-server/handlers/admin"
+	"main.go/internal/http-server/handlers/apikeys"
 	"main.go/internal/http-server/handlers/redirect"
+	"main.go/internal/http-server/handlers/url/list"
+	"main.go/internal/http-server/handlers/url/qr"
+	urlQuota "main.go/internal/http-server/handlers/url/quota"
+	"main.go/internal/http-server/handlers/url/rules"
 	"main.go/internal/http-server/handlers/url/save"
-	"main.go/internal/lib/logger/handlers/slogpretty"
+	"main.go/internal/http-server/handlers/url/stats"
+	"main.go/internal/http-server/handlers/users"
+	mwAuth "main.go/internal/http-server/middleware/auth"
+	mwClientIP "main.go/internal/http-server/middleware/clientip"
+	mwEnumGuard "main.go/internal/http-server/middleware/enumguard"
+	mwLogger "main.go/internal/http-server/middleware/logger"
+	mwMetrics "main.go/internal/http-server/middleware/metrics"
+	mwRateLimit "main.go/internal/http-server/middleware/ratelimit"
+	mwTracing "main.go/internal/http-server/middleware/tracing"
+	"main.go/internal/lib/aliassig"
+	"main.go/internal/lib/auth"
+	"main.go/internal/lib/clientip"
+	"main.go/internal/lib/enumguard"
+	"main.go/internal/lib/logger/handlers/sloglevel"
+	"main.go/internal/lib/logger/handlers/slogmulti"
+	"main.go/internal/lib/logger/handlers/slogredact"
+	"main.go/internal/lib/logger/sink"
 	"main.go/internal/lib/logger/sl"
+	"main.go/internal/lib/metrics"
+	"main.go/internal/lib/quota"
+	"main.go/internal/lib/ratelimit"
+	"main.go/internal/lib/selflink"
+	"main.go/internal/lib/tracing"
+	"main.go/internal/lib/urlpolicy"
 	"main.go/internal/storage/sqlite"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 )
 
 const (
@@ -28,7 +66,12 @@ func main() {
 
 	cfg := config.MustLoad()
 
-	log := setupLogger(cfg.Env)
+	log, logLevel, closeLog, err := setupLogger(cfg.Env, cfg.Log)
+	if err != nil {
+		slog.Error("failed to init logger", sl.Err(err))
+		os.Exit(1)
+	}
+	defer closeLog()
 
 	log.Info("starting url-shortener",
 		slog.String("env", cfg.Env),
@@ -36,7 +79,28 @@ func main() {
 	)
 	log.Debug("debug messages are enabled")
 
-	storage, err := sqlite.New(cfg.StoragePath)
+	// Трассировка: span на запрос и на каждый вызов хранилища
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		ServiceName: cfg.Tracing.ServiceName,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		log.Error("failed to init tracing", sl.Err(err))
+		os.Exit(1)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			log.Error("failed to flush traces", sl.Err(err))
+		}
+	}()
+
+	// Метрики собираются всегда, отдаются только если включен эндпоинт
+	m := metrics.New()
+
+	storage, err := sqlite.New(cfg.StoragePath, sqlite.WithObserver(m))
 	if err != nil {
 		log.Error("failed to init storage", sl.Err(err))
 		os.Exit(1)
@@ -44,26 +108,204 @@ func main() {
 
 	_ = storage
 
+	policy, err := urlpolicy.New(urlpolicy.Config{
+		AllowedSchemes: cfg.URLPolicy.AllowedSchemes,
+		AllowDomains:   cfg.URLPolicy.AllowDomains,
+		DenyDomains:    cfg.URLPolicy.DenyDomains,
+		BlockPrivate:   cfg.URLPolicy.BlockPrivate,
+		BlocklistPath:  cfg.URLPolicy.BlocklistPath,
+	})
+	if err != nil {
+		log.Error("failed to init url policy", sl.Err(err))
+		os.Exit(1)
+	}
+	go policy.Watch(context.Background(), log, cfg.URLPolicy.BlocklistReload) // подхватываем изменения блоклиста без рестарта
+
+	// Свои адреса: из конфига, адрес сервера и публичный адрес
+	ownHosts := append([]string{cfg.Address}, cfg.SelfLinks.Hosts...)
+	if u, err := url.Parse(cfg.HTTPServer.PublicURL); err == nil && u.Host != "" {
+		ownHosts = append(ownHosts, u.Host)
+	}
+	selfLinks := selflink.New(ownHosts, cfg.SelfLinks.MaxDepth)
+
 	// Инициализируем роутер. Устанавливаем пакет chi
 	// middleware - это цепочки когда наш handler обрабатывает запрос и основной называется handler запроса, а другие middleware
 	// Он проверяет авторизацию и не дает пройти, если неправильно
 
+	// IP клиента за прокси: для лимитов, логов и трассировки
+	clientIPs, err := clientip.New(cfg.HTTPServer.TrustedProxies)
+	if err != nil {
+		log.Error("failed to init client ip resolver", sl.Err(err))
+		os.Exit(1)
+	}
+
 	router := chi.NewRouter()
 	router.Use(middleware.RequestID) // Он добавляет к каждому запросу RequestID - это уникальный идентификатор для каждого запроса в системе (для ошибок, логирование )
-	router.Use(middleware.Logger)    // логирует все входящие запросы
-	//router.Use(mwLogger.New(log))
+	router.Use(mwClientIP.New(clientIPs))
+	// span на запрос, traceparent от вызывающего. После RequestID, чтобы request_id попал в атрибуты
+	router.Use(mwTracing.New(otel.GetTracerProvider()))
+	// логирует все входящие запросы и кладет в контекст логер запроса (request_id, trace_id) для обработчиков
+	router.Use(mwLogger.New(log))
+	router.Use(mwMetrics.New(m))     // количество и время запросов по маршрутам
 	router.Use(middleware.Recoverer) // Паника
 	router.Use(middleware.URLFormat) // Красивые URL
 
+	// Уровни логов из конфига: по SIGHUP и через POST /admin/reload
+	reloadLogLevel := func() error {
+		newCfg, err := config.Load(os.Getenv("CONFIG_PATH"))
+		if err != nil {
+			return err
+		}
+		levels, err := logLevels(newCfg.Env, newCfg.Log)
+		if err != nil {
+			return err
+		}
+		if !logLevel.SetDefaults(levels) {
+			return errors.New("log sinks changed, restart required")
+		}
+		return nil
+	}
+	go watchSIGHUP(log, reloadLogLevel)
+
+	// Что можно перечитать без рестарта через POST /admin/reload
+	reloaders := map[string]func() error{"url_policy": policy.Reload, "log_level": reloadLogLevel}
+
+	// Клиенты API авторизуются API-ключами или JWT от SSO, права на маршруты - по роли и scopes
+	authenticators := []auth.Authenticator{auth.NewAPIKeys(storage, cfg.Auth.BootstrapKey)}
+	if cfg.Auth.JWT.JWKSPath != "" {
+		jwtAuth, err := auth.NewJWT(auth.JWTConfig{
+			JWKSPath:    cfg.Auth.JWT.JWKSPath,
+			Issuer:      cfg.Auth.JWT.Issuer,
+			Audience:    cfg.Auth.JWT.Audience,
+			ClockSkew:   cfg.Auth.JWT.ClockSkew,
+			ScopeClaim:  cfg.Auth.JWT.ScopeClaim,
+			ScopeMap:    cfg.Auth.JWT.ScopeMap,
+			UserIDClaim: cfg.Auth.JWT.UserIDClaim,
+			RoleClaim:   cfg.Auth.JWT.RoleClaim,
+		})
+		if err != nil {
+			log.Error("failed to init jwt auth", sl.Err(err))
+			os.Exit(1)
+		}
+		go jwtAuth.Watch(context.Background(), log, cfg.Auth.JWT.ReloadInterval) // ротация ключей без рестарта
+		authenticators = append(authenticators, jwtAuth)
+		reloaders["jwks"] = jwtAuth.Reload
+	}
+	authenticate := mwAuth.New(log, authenticators...)
+	allow := func(perm auth.Permission) func(http.Handler) http.Handler {
+		return mwAuth.Authorize(log, perm)
+	}
+
+	// Лимиты частоты: у каждой группы свое хранилище ведер
+	limit := func(name string, group config.RateLimitGroup, key mwRateLimit.KeyFunc) func(http.Handler) http.Handler {
+		return mwRateLimit.New(log, name, ratelimit.NewMemoryStore(), ratelimit.Limit{
+			Requests: group.Requests,
+			Per:      group.Per,
+			Burst:    group.Burst,
+		}, key)
+	}
+	createLimit := limit("create", cfg.RateLimit.Create, mwRateLimit.ByPrincipal)
+
+	// Приватные ссылки открываются только по alias с подписью
+	var signer *aliassig.Signer
+	if cfg.Enumeration.SigningSecret != "" {
+		signer = aliassig.New([]byte(cfg.Enumeration.SigningSecret), cfg.Enumeration.SignatureLength)
+	}
+
+	quotas := quota.New(quota.Config{
+		Default:  cfg.Quota.Default,
+		Users:    cfg.Quota.Users,
+		Subjects: cfg.Quota.Subjects,
+	})
+
+	// Alias с именем маршрута верхнего уровня был бы недоступен для редиректа
+	reserved := []string{"url", "users", "keys", "admin"}
+	if cfg.Metrics.Enabled {
+		router.Handle(cfg.Metrics.Path, m.Handler())
+		first, _, _ := strings.Cut(strings.TrimPrefix(cfg.Metrics.Path, "/"), "/")
+		reserved = append(reserved, first)
+	}
+
 	router.Route("/url", func(r chi.Router) { // 1 - общий префикс url
-		r.Use(middleware.BasicAuth("url-shortener", map[string]string{
-			cfg.HTTPServer.User: cfg.HTTPServer.Password,
-		}))
-		r.Post("/", save.New(log, storage))
-		//r.Delete("/{alias}", Dellete.New(log, storage))
+		r.Use(authenticate)
+		r.With(allow(auth.PermLinksRead)).Get("/", list.New(log, storage)) // свои ссылки, admin - все с ?owner=all
+		r.With(allow(auth.PermLinksCreate), createLimit).Post("/", save.New(log, storage, save.WithCampaigns(cfg.Campaigns), save.WithDestinationChecker(policy), save.WithSelfLinks(save.SelfLinks{
+			Resolver: selfLinks,
+			Getter:   storage,
+			Reject:   cfg.SelfLinks.Reject,
+		}), save.WithQuota(save.Quota{Quotas: quotas, Counter: storage}), save.WithSigner(signer), save.WithReservedAliases(reserved...)))
+		r.With(allow(auth.PermLinksRead)).Get("/quota", urlQuota.New(log, quotas, storage)) // лимиты и сколько уже использовано
+
+		// Управлять ссылкой может только ее владелец или admin
+		r.Route("/{alias}", func(r chi.Router) {
+			// Сначала право, потом владелец: иначе по 403/404 без права можно узнать, есть ли alias
+			owner := mwAuth.RequireOwner(log, storage)
+			r.With(allow(auth.PermLinksRead), owner).Get("/rules", rules.NewList(log, storage)) // условные редиректы ссылки
+			ruleOpts := []rules.Option{rules.WithDestinationChecker(policy), rules.WithSelfLinks(rules.SelfLinks{
+				Resolver: selfLinks,
+				Getter:   storage,
+				Reject:   cfg.SelfLinks.Reject,
+			})}
+			r.With(allow(auth.PermLinksCreate), owner).Post("/rules", rules.NewCreate(log, storage, ruleOpts...))
+			r.With(allow(auth.PermLinksCreate), owner).Put("/rules/{id}", rules.NewUpdate(log, storage, ruleOpts...)) // правка и перенос правила (position)
+			r.With(allow(auth.PermLinksDelete), owner).Delete("/rules/{id}", rules.NewDelete(log, storage))
+			r.With(allow(auth.PermLinksRead), owner).Get("/stats", stats.New(log, storage))                                            // переходы, в том числе по вариантам A/B-теста
+			r.With(allow(auth.PermLinksRead), owner).Get("/qr", qr.New(log, storage, cfg.HTTPServer.PublicURL, qr.WithSigner(signer))) // PNG или SVG с короткой ссылкой
+			r.With(allow(auth.PermLinksDelete), owner).Delete("/", Dellete.New(log, storage))
+		})
+	})
+
+	router.Route("/users", func(r chi.Router) { // пользователи, ключи им выпускаются через /keys
+		r.Use(authenticate, allow(auth.PermUsersManage))
+		r.Post("/", users.NewCreate(log, storage))
+		r.Get("/", users.NewList(log, storage))
+		r.Put("/{id}/role", users.NewSetRole(log, storage)) // viewer, editor, admin
+	})
+
+	router.Route("/keys", func(r chi.Router) { // выпуск и отзыв API-ключей
+		r.Use(authenticate, allow(auth.PermKeysManage))
+		r.Post("/", apikeys.NewCreate(log, storage))
+		r.Get("/", apikeys.NewList(log, storage))
+		r.Delete("/{id}", apikeys.NewRevoke(log, storage))
 	})
 
-	router.Get("/{alias}", redirect.New(log, storage)) // 1 - имя параметра, что бы дальше получить его в handler
+	router.Route("/admin", func(r chi.Router) { // операции только для роли admin
+		r.Use(authenticate)
+		r.With(allow(auth.PermLinksBulkDelete)).Post("/links/delete", admin.NewBulkDelete(log, storage))
+		r.With(allow(auth.PermLinksExport)).Get("/links/export", admin.NewExport(log, storage))
+		r.With(allow(auth.PermConfigReload)).Post("/reload", admin.NewReload(log, reloaders))
+		r.Route("/log-level", func(r chi.Router) { // уровень логов без рестарта, в том числе для одного op на время
+			r.Use(allow(auth.PermLogLevel))
+			r.Get("/", admin.NewGetLogLevel(logLevel))
+			r.Put("/", admin.NewSetLogLevel(log, logLevel))
+			r.Delete("/", admin.NewResetLogLevel(log, logLevel))
+		})
+	})
+
+	redirectHandler := redirect.New(log, storage, redirect.WithPassword(redirect.PasswordConfig{
+		Secret:        []byte(cfg.ProtectedLinks.CookieSecret),
+		CookieTTL:     cfg.ProtectedLinks.CookieTTL,
+		MaxAttempts:   cfg.ProtectedLinks.MaxAttempts,
+		AttemptWindow: cfg.ProtectedLinks.AttemptWindow,
+	}), redirect.WithSchedule(redirect.ScheduleConfig{
+		NotStartedURL: cfg.Schedule.NotStartedURL,
+		EndedURL:      cfg.Schedule.EndedURL,
+	}), redirect.WithInterstitial(cfg.AlwaysInterstitial), redirect.WithSelfLinks(selfLinks), redirect.WithSigner(signer), redirect.WithObserver(m))
+	router.Group(func(r chi.Router) {
+		r.Use(limit("redirect", cfg.RateLimit.Redirect, mwRateLimit.ByClientIP))
+		r.Use(mwEnumGuard.New(log, enumguard.New(enumguard.Config{ // промахи мимо существующих alias
+			Window:     cfg.Enumeration.Window,
+			SlowAfter:  cfg.Enumeration.SlowAfter,
+			Delay:      cfg.Enumeration.Delay,
+			MaxDelay:   cfg.Enumeration.MaxDelay,
+			BlockAfter: cfg.Enumeration.BlockAfter,
+			BlockFor:   cfg.Enumeration.BlockFor,
+		})))
+		r.Get("/{alias}", redirectHandler)   // 1 - имя параметра, что бы дальше получить его в handler
+		r.Get("/{alias}/*", redirectHandler) // хвост пути после alias (для ссылок с forward_path)
+		r.Post("/{alias}", redirectHandler)  // форма ввода пароля для защищенных ссылок
+		r.Post("/{alias}/*", redirectHandler)
+	})
 
 	log.Info("starting server", slog.String("address", cfg.Address))
 
@@ -84,34 +326,118 @@ func main() {
 
 }
 
-func setupLogger(env string) *slog.Logger {
-	var log *slog.Logger // Объявляем логер
+// setupLogger builds the logger from sinks of the config. closeAll закрывает файлы логов,
+// через levels уровни приемников меняются без рестарта.
+func setupLogger(env string, cfg config.Log) (_ *slog.Logger, levels *sloglevel.Controller, closeAll func() error, err error) {
+	sinks := logSinks(cfg)
+
+	sinkLevels, err := logLevels(env, cfg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Формат по окружению, если в sink он не задан
+	format := sink.FormatJSON
+	if env == envLocal {
+		format = sink.FormatPretty
+	}
 
-	switch env {
-	case envLocal:
-		log = setupPrettySlog()
-	case envDev:
-		log = slog.New(
+	levels = sloglevel.NewController()
 
-			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}), // Обработчик логов в формате json
-		)
-	case envProd:
-		log = slog.New(
-			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}),
-		)
+	var handlers []slog.Handler
+	var closers []io.Closer
+	closeAll = func() error {
+		var errs []error
+		for _, c := range closers {
+			errs = append(errs, c.Close())
+		}
+		return errors.Join(errs...)
 	}
 
-	return log
+	for i, s := range sinks {
+		sc := sink.Config{
+			Type:        s.Type,
+			Format:      s.Format,
+			Level:       levels.Register(sinkLevels[i]),
+			Path:        s.Path,
+			MaxSizeMB:   s.MaxSizeMB,
+			RotateEvery: s.RotateEvery,
+			MaxBackups:  s.MaxBackups,
+			MaxAgeDays:  s.MaxAgeDays,
+			Compress:    s.Compress,
+		}
+		if sc.Format == "" {
+			sc.Format = format
+			if sc.Type == sink.TypeFile {
+				sc.Format = sink.FormatJSON // pretty только для терминала
+			}
+		}
+
+		h, c, err := sink.New(sc)
+		if err != nil {
+			_ = closeAll()
+			return nil, nil, nil, err
+		}
+		// Секреты скрываются до любого приемника
+		h = slogredact.RedactHandlerOptions{
+			Keys:        cfg.Redact.Keys,
+			QueryParams: cfg.Redact.QueryParams,
+		}.NewRedactHandler(h)
+		// Снаружи всех: уровень для отдельных op, заданный через /admin/log-level
+		h = levels.Wrap(h)
+
+		handlers = append(handlers, h)
+		closers = append(closers, c)
+	}
+
+	if len(handlers) == 1 {
+		return slog.New(handlers[0]), levels, closeAll, nil
+	}
+
+	// Несколько приемников: каждый со своим уровнем
+	return slog.New(slogmulti.NewFanoutHandler(handlers...)), levels, closeAll, nil
 }
 
-func setupPrettySlog() *slog.Logger {
-	opts := slogpretty.PrettyHandlerOptions{
-		SlogOpts: &slog.HandlerOptions{
-			Level: slog.LevelDebug,
-		},
+func logSinks(cfg config.Log) []config.LogSink {
+	if len(cfg.Sinks) == 0 {
+		return []config.LogSink{{Type: sink.TypeStdout}}
 	}
 
-	handler := opts.NewPrettyHandler(os.Stdout)
+	return cfg.Sinks
+}
 
-	return slog.New(handler)
+// logLevels returns the configured level of every sink. Пустой уровень - по окружению.
+func logLevels(env string, cfg config.Log) ([]slog.Level, error) {
+	def := slog.LevelDebug
+	if env == envProd {
+		def = slog.LevelInfo
+	}
+
+	sinks := logSinks(cfg)
+	levels := make([]slog.Level, len(sinks))
+	for i, s := range sinks {
+		levels[i] = def
+		if s.Level == "" {
+			continue
+		}
+		if err := levels[i].UnmarshalText([]byte(s.Level)); err != nil {
+			return nil, fmt.Errorf("log sink %d: %w", i, err)
+		}
+	}
+
+	return levels, nil
+}
+
+// watchSIGHUP calls reload on every SIGHUP (kill -HUP <pid>).
+func watchSIGHUP(log *slog.Logger, reload func() error) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	for range hup {
+		if err := reload(); err != nil {
+			log.Error("failed to reload log level", sl.Err(err))
+			continue
+		}
+		log.Info("log level reloaded")
+	}
 }
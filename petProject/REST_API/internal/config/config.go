@@ -3,6 +3,8 @@ package config
 import (
 	"github.com/ilyakaznacheev/cleanenv"
 	"log"
+	"main.go/internal/lib/quota"
+	"main.go/internal/lib/utm"
 	"os"
 	"time"
 )
@@ -14,17 +16,158 @@ type Config struct {
 	// yaml - то есть это какое имя у yaml
 	// env-required- если забыли установить какй-то параметр, то не запустилось
 	// env-required:"true" - без этого параметра программа не запустится
-	Env         string `yaml:"environment" env-default:"local" env-required:"true"`
-	StoragePath string `yaml:"storage_path" env-required:"true"`
-	HTTPServer  `yaml:"http_server"`
+	Env            string `yaml:"environment" env-default:"local" env-required:"true"`
+	Log            Log    `yaml:"log"`
+	StoragePath    string `yaml:"storage_path" env-required:"true"`
+	HTTPServer     `yaml:"http_server"`
+	Campaigns      map[string]utm.Params `yaml:"campaigns"` // именованные пресеты UTM-меток для save.Request.Campaign
+	ProtectedLinks ProtectedLinks        `yaml:"protected_links"`
+	Schedule       Schedule              `yaml:"schedule"`
+	URLPolicy      URLPolicy             `yaml:"url_policy"`
+	SelfLinks      SelfLinks             `yaml:"self_links"`
+	Auth           Auth                  `yaml:"auth"`
+	RateLimit      RateLimit             `yaml:"rate_limit"`
+	Quota          Quota                 `yaml:"quota"`
+	Enumeration    Enumeration           `yaml:"enumeration"`
+	Metrics        Metrics               `yaml:"metrics"`
+	Tracing        Tracing               `yaml:"tracing"`
+	// Всегда показывать страницу предпросмотра перед редиректом (для ссылок от непроверенных пользователей)
+	AlwaysInterstitial bool `yaml:"always_interstitial" env-default:"false"`
 }
 
 type HTTPServer struct {
 	Address     string        `yaml:"address" env-default:"localhost:8080"`
 	Timeout     time.Duration `yaml:"timeout" env-default:"10s"` // библиотека, которая помогает легче работать с временем
 	IdleTimeout time.Duration `yaml:"idleTimeout" env-default:"60s"`
-	User        string        `yaml:"user" env-required:"true"`
-	Password    string        `yaml:"password" env-required:"true" env:"HTTP_SERVER_PASSWORD"`
+	PublicURL   string        `yaml:"public_url" env:"HTTP_SERVER_PUBLIC_URL"` // адрес коротких ссылок снаружи (для QR-кодов), пустой - из запроса
+	// Прокси перед сервисом (адреса или CIDR): только от них берем IP клиента из X-Forwarded-For/X-Real-IP
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+// Auth - доступ к API. Клиенты ходят с API-ключами: "Authorization: Bearer <key>" или X-API-Key
+type Auth struct {
+	// Ключ с правами admin из конфига, чтобы выпустить первые ключи через POST /keys.
+	// Должен начинаться с "sk_", как выданные ключи. Пустой - отключен
+	BootstrapKey string `yaml:"bootstrap_key" env:"AUTH_BOOTSTRAP_KEY"`
+	JWT          JWT    `yaml:"jwt"`
+}
+
+// JWT - токены от SSO вместо API-ключей. Пустой jwks_path - отключено
+type JWT struct {
+	JWKSPath       string              `yaml:"jwks_path"` // локальный файл JWKS, изменения подхватываются без рестарта
+	Issuer         string              `yaml:"issuer"`
+	Audience       string              `yaml:"audience"`
+	ClockSkew      time.Duration       `yaml:"clock_skew" env-default:"30s"`
+	ScopeClaim     string              `yaml:"scope_claim" env-default:"scope"`
+	ScopeMap       map[string][]string `yaml:"scope_map"`                        // значение claim -> наши scopes (create, read, delete, admin)
+	UserIDClaim    string              `yaml:"user_id_claim" env-default:"uid"`  // id пользователя в нашей базе
+	RoleClaim      string              `yaml:"role_claim" env-default:"role"`    // viewer, editor, admin; нет claim - роль по scopes
+	ReloadInterval time.Duration       `yaml:"reload_interval" env-default:"1m"` // как часто проверять, не изменился ли файл
+}
+
+// ProtectedLinks настраивает ссылки под паролем
+type ProtectedLinks struct {
+	CookieSecret  string        `yaml:"cookie_secret" env:"PROTECTED_LINKS_COOKIE_SECRET"` // пустой - ключ генерируется при старте
+	CookieTTL     time.Duration `yaml:"cookie_ttl" env-default:"10m"`                      // после ввода пароля не спрашиваем повторно
+	MaxAttempts   int           `yaml:"max_attempts" env-default:"5"`                      // неверных паролей на alias за attempt_window
+	AttemptWindow time.Duration `yaml:"attempt_window" env-default:"15m"`
+}
+
+// Schedule - куда отправлять по ссылкам вне окна not_before / not_after.
+// Пустые значения - встроенные страницы "coming soon" и "campaign ended"
+type Schedule struct {
+	NotStartedURL string `yaml:"not_started_url"`
+	EndedURL      string `yaml:"ended_url"`
+}
+
+// URLPolicy - какие адреса можно сокращать. Домены: "example.com" или "*.example.com" (поддомены)
+type URLPolicy struct {
+	AllowedSchemes  []string      `yaml:"allowed_schemes" env-default:"http,https"`
+	AllowDomains    []string      `yaml:"allow_domains"` // непустой - сокращаем только эти домены
+	DenyDomains     []string      `yaml:"deny_domains"`
+	BlockPrivate    bool          `yaml:"block_private" env-default:"true"`  // localhost, 127.0.0.1, 10.0.0.0/8 и т.п.
+	BlocklistPath   string        `yaml:"blocklist_path"`                    // список фишинговых и вредоносных доменов
+	BlocklistReload time.Duration `yaml:"blocklist_reload" env-default:"1m"` // как часто проверять, не изменился ли файл
+}
+
+// SelfLinks - ссылки на наш же сервис. Адрес сервера и public_url добавляются к hosts автоматически
+type SelfLinks struct {
+	Hosts    []string `yaml:"hosts"`                      // другие имена сервиса: "sho.rt", "localhost:8080"
+	MaxDepth int      `yaml:"max_depth" env-default:"5"`  // сколько ссылок подряд раскрываем при редиректе
+	Reject   bool     `yaml:"reject" env-default:"false"` // true - запрещать сокращать свои ссылки, false - сохранять конечный адрес
+}
+
+// RateLimit - token bucket на группы маршрутов. requests: 0 - без ограничений
+type RateLimit struct {
+	Create   RateLimitGroup `yaml:"create"`   // POST /url, по ключу или пользователю
+	Redirect RateLimitGroup `yaml:"redirect"` // GET /{alias}, по IP клиента
+}
+
+type RateLimitGroup struct {
+	Requests int           `yaml:"requests"` // сколько запросов восстанавливается за per
+	Per      time.Duration `yaml:"per" env-default:"1m"`
+	Burst    int           `yaml:"burst"` // сколько можно подряд, 0 - равно requests
+}
+
+// Quota - сколько ссылок можно иметь и создавать. Переопределения для ключа важнее, чем для пользователя
+type Quota struct {
+	Default  quota.Limits            `yaml:"default"`  // 0 - без ограничения
+	Users    map[int64]quota.Limits  `yaml:"users"`    // id пользователя -> лимиты
+	Subjects map[string]quota.Limits `yaml:"subjects"` // "key:12", "jwt:alice" -> лимиты
+}
+
+// Enumeration - защита от перебора alias: промахи (404) на редиректе по IP клиента
+type Enumeration struct {
+	Window     time.Duration `yaml:"window" env-default:"1m"`
+	SlowAfter  int           `yaml:"slow_after" env-default:"10"` // 0 - не замедлять
+	Delay      time.Duration `yaml:"delay" env-default:"200ms"`   // за каждый промах сверх slow_after
+	MaxDelay   time.Duration `yaml:"max_delay" env-default:"3s"`
+	BlockAfter int           `yaml:"block_after" env-default:"50"` // 0 - не блокировать
+	BlockFor   time.Duration `yaml:"block_for" env-default:"15m"`
+	// Секрет подписи alias приватных ссылок. Пустой - приватные ссылки отключены
+	SigningSecret   string `yaml:"signing_secret" env:"ENUMERATION_SIGNING_SECRET"`
+	SignatureLength int    `yaml:"signature_length" env-default:"8"`
+}
+
+// Metrics - эндпоинт для Prometheus. Alias с таким же именем (первым сегментом пути) сохранить нельзя
+type Metrics struct {
+	Enabled bool   `yaml:"enabled" env-default:"true"`
+	Path    string `yaml:"path" env-default:"/metrics"`
+}
+
+// Tracing - OpenTelemetry. Входящий traceparent учитывается при любом экспортере
+type Tracing struct {
+	Exporter    string  `yaml:"exporter" env:"TRACING_EXPORTER" env-default:"none"` // none, stdout, otlp
+	Endpoint    string  `yaml:"endpoint" env:"TRACING_ENDPOINT"`                    // host:port коллектора OTLP/HTTP
+	Insecure    bool    `yaml:"insecure" env-default:"false"`
+	ServiceName string  `yaml:"service_name" env-default:"url-shortener"`
+	SampleRatio float64 `yaml:"sample_ratio" env-default:"1"`
+}
+
+// Log - куда писать логи. Без sinks - stdout в формате и с уровнем окружения
+type Log struct {
+	Sinks  []LogSink `yaml:"sinks"`
+	Redact Redact    `yaml:"redact"`
+}
+
+// Redact - что скрывать в логах всех sinks. Пароли, токены, Authorization и параметры token/key/password/sig скрываются всегда
+type Redact struct {
+	Keys        []string `yaml:"keys"`         // ключи атрибутов и полей, значение которых скрывается целиком
+	QueryParams []string `yaml:"query_params"` // параметры в URL
+}
+
+// LogSink - один приемник логов. Пустые format и level берутся по окружению
+type LogSink struct {
+	Type   string `yaml:"type"`   // stdout, file
+	Format string `yaml:"format"` // pretty (только stdout), json, text
+	Level  string `yaml:"level"`  // debug, info, warn, error
+
+	Path        string        `yaml:"path"`
+	MaxSizeMB   int           `yaml:"max_size_mb"`  // ротация по размеру, 0 - 100 МБ
+	RotateEvery time.Duration `yaml:"rotate_every"` // ротация по времени, 0 - только по размеру
+	MaxBackups  int           `yaml:"max_backups"`  // сколько старых файлов хранить, 0 - все
+	MaxAgeDays  int           `yaml:"max_age_days"` // 0 - без ограничения
+	Compress    bool          `yaml:"compress"`     // gzip для старых файлов
 }
 
 func MustLoad() *Config {
@@ -43,14 +186,24 @@ func MustLoad() *Config {
 		log.Fatalf("config file does not exist: %s", configPath)
 	}
 
+	cfg, err := Load(configPath)
+	if err != nil {
+		log.Fatalf("cannot read config: %s", err)
+	}
+
+	return cfg
+}
+
+// Load reads the config without exiting on errors: для перечитывания на работающем сервере (SIGHUP).
+func Load(configPath string) (*Config, error) {
 	var cfg Config
 
 	// Загружаем конфиг с помощью cleanenv.ReadConfig()
 	// cleanenv.ReadConfig(configPath, &cfg) читает YAML-файл и заполняет структуру cfg
 
 	if err := cleanenv.ReadConfig(configPath, &cfg); err != nil {
-		log.Fatalf("cannot read config: %s", err)
+		return nil, err
 	}
 
-	return &cfg
+	return &cfg, nil
 }
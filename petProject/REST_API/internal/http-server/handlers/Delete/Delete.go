@@ -1,9 +1,9 @@
 package Dellete
 
 import (
+	"context"
 	"errors"
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"log/slog"
 	resp "main.go/internal/lib/api/response"
@@ -12,17 +12,19 @@ import (
 	"net/http"
 )
 
-type URLDelete interface {
-	DelURL(alias string) (string, error)
+// URLDeleter is an interface for deleting a link by alias.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLDeleter
+type URLDeleter interface {
+	DeleteURL(ctx context.Context, alias string) error
 }
 
-func New(log *slog.Logger, urlDelete URLDelete) http.HandlerFunc {
+func New(log *slog.Logger, urlDeleter URLDeleter) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.Delete.delete.Del"
 
-		log := log.With(
+		log := sl.FromContext(r.Context(), log).With(
 			slog.String("op", op),
-			slog.String("delete_id", middleware.GetReqID(r.Context())),
 		)
 
 		alias := chi.URLParam(r, "alias")
@@ -33,7 +35,7 @@ func New(log *slog.Logger, urlDelete URLDelete) http.HandlerFunc {
 			return
 		}
 
-		_, err := urlDelete.DelURL(alias)
+		err := urlDeleter.DeleteURL(r.Context(), alias)
 		if errors.Is(err, storage.ErrURLNotFound) {
 			log.Info("url not found", "alias", alias)
 			render.JSON(w, r, resp.Error("not found"))
@@ -41,13 +43,14 @@ func New(log *slog.Logger, urlDelete URLDelete) http.HandlerFunc {
 			return
 		}
 		if err != nil {
-			log.Error("failed to get url", sl.Err(err))
+			log.Error("failed to delete url", sl.Err(err))
 			render.JSON(w, r, resp.Error("internal error"))
 
 			return
 		}
 
 		log.Info("Url delete", slog.String("url", alias))
-		w.WriteHeader(http.StatusOK)
+
+		render.JSON(w, r, resp.OK())
 	}
 }
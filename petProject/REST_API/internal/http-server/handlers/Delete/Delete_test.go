@@ -0,0 +1,44 @@
+package Dellete_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	Dellete "main.go/internal/http-server/handlers/Delete"
+	"main.go/internal/http-server/handlers/Delete/mocks"
+	"main.go/internal/lib/logger/handlers/slogdiscard"
+	"main.go/internal/storage"
+)
+
+func TestDeleteHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		mockError error
+		want      string
+	}{
+		{name: "Success", want: `{"status": "Ok"}`},
+		{name: "Not found", mockError: storage.ErrURLNotFound, want: `{"status": "Error", "error": "not found"}`},
+		{name: "Storage error", mockError: errors.New("unexpected error"), want: `{"status": "Error", "error": "internal error"}`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlDeleterMock := mocks.NewURLDeleter(t)
+			urlDeleterMock.On("DeleteURL", mock.Anything, "test_alias").Return(tc.mockError).Once()
+
+			r := chi.NewRouter()
+			r.Delete("/url/{alias}", Dellete.New(slogdiscard.NewDiscardLogger(), urlDeleterMock))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/url/test_alias", nil))
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.JSONEq(t, tc.want, rr.Body.String())
+		})
+	}
+}
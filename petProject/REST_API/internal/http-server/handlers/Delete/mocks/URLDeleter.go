@@ -0,0 +1,46 @@
+// Code generated by mockery v2.53.7. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// URLDeleter is an autogenerated mock type for the URLDeleter type
+type URLDeleter struct {
+	mock.Mock
+}
+
+// DeleteURL provides a mock function with given fields: ctx, alias
+func (_m *URLDeleter) DeleteURL(ctx context.Context, alias string) error {
+	ret := _m.Called(ctx, alias)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteURL")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, alias)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewURLDeleter creates a new instance of URLDeleter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewURLDeleter(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *URLDeleter {
+	mock := &URLDeleter{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,200 @@
+// Операции только для admin: массовое удаление, выгрузка ссылок, перечитывание конфигурации
+
+package admin
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"main.go/internal/http-server/handlers/url/list"
+	resp "main.go/internal/lib/api/response"
+	"main.go/internal/lib/auth"
+	"main.go/internal/lib/logger/sl"
+	"main.go/internal/storage"
+)
+
+const (
+	maxBulkDelete = 1000
+	exportPage    = 500
+)
+
+type BulkDeleteRequest struct {
+	Aliases []string `json:"aliases" validate:"required,min=1,max=1000,dive,required"`
+}
+
+type BulkDeleteResponse struct {
+	resp.Response
+	Deleted int64 `json:"deleted"`
+}
+
+type ExportResponse struct {
+	resp.Response
+	Links []list.Link `json:"links"`
+}
+
+type ReloadResponse struct {
+	resp.Response
+	Reloaded []string       `json:"reloaded"`
+	Results  []ReloadResult `json:"results"`
+}
+
+// ReloadResult is the outcome of reloading one source.
+type ReloadResult struct {
+	Source string `json:"source"`
+	Status string `json:"status"` // Ok или Error, как в resp.Response
+	Error  string `json:"error,omitempty"`
+}
+
+// URLsDeleter is an interface for deleting links in bulk.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLsDeleter
+type URLsDeleter interface {
+	DeleteURLs(ctx context.Context, aliases []string) (int64, error)
+}
+
+// URLLister is an interface for listing all links.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLLister
+type URLLister interface {
+	ListURLs(ctx context.Context, filter storage.ListFilter) ([]storage.URL, error)
+}
+
+// NewBulkDelete deletes links by aliases regardless of owner. Несуществующие alias пропускаются.
+func NewBulkDelete(log *slog.Logger, urlsDeleter URLsDeleter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.NewBulkDelete"
+
+		log := sl.FromContext(r.Context(), log).With(
+			slog.String("op", op),
+		)
+
+		var req BulkDeleteRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			var validateErrs validator.ValidationErrors
+			if errors.As(err, &validateErrs) {
+				log.Info("invalid request", sl.Err(err))
+				render.JSON(w, r, resp.ValidationError(validateErrs))
+				return
+			}
+
+			log.Error("failed to validate request", sl.Err(err))
+			render.JSON(w, r, resp.Error("invalid request"))
+			return
+		}
+
+		deleted, err := urlsDeleter.DeleteURLs(r.Context(), req.Aliases)
+		if err != nil {
+			log.Error("failed to delete urls", sl.Err(err))
+			render.JSON(w, r, resp.Error("internal error"))
+			return
+		}
+
+		p, _ := auth.FromContext(r.Context())
+		log.Info("urls deleted",
+			slog.Int("requested", len(req.Aliases)),
+			slog.Int64("deleted", deleted),
+			slog.String("by", p.Subject),
+		)
+
+		render.JSON(w, r, BulkDeleteResponse{
+			Response: resp.OK(),
+			Deleted:  deleted,
+		})
+	}
+}
+
+// NewExport returns all links of all users as a JSON attachment.
+func NewExport(log *slog.Logger, urlLister URLLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.NewExport"
+
+		log := sl.FromContext(r.Context(), log).With(
+			slog.String("op", op),
+		)
+
+		// Забираем постранично, чтобы не держать в storage один огромный запрос
+		var urls []storage.URL
+		for offset := 0; ; offset += exportPage {
+			page, err := urlLister.ListURLs(r.Context(), storage.ListFilter{AllOwners: true, Limit: exportPage, Offset: offset})
+			if err != nil {
+				log.Error("failed to list urls", sl.Err(err))
+				render.JSON(w, r, resp.Error("internal error"))
+				return
+			}
+
+			urls = append(urls, page...)
+			if len(page) < exportPage {
+				break
+			}
+		}
+
+		p, _ := auth.FromContext(r.Context())
+		log.Info("urls exported", slog.Int("count", len(urls)), slog.String("by", p.Subject))
+
+		w.Header().Set("Content-Disposition", `attachment; filename="links.json"`)
+		render.JSON(w, r, ExportResponse{
+			Response: resp.OK(),
+			Links:    list.Links(urls),
+		})
+	}
+}
+
+// NewReload re-reads reloadable configuration (блоклист, JWKS и т.п.) without restart.
+// Ключ map - имя источника для ответа и логов. Ошибка одного источника не мешает
+// перечитать остальные, результат каждого возвращается в results.
+func NewReload(log *slog.Logger, reloaders map[string]func() error) http.HandlerFunc {
+	names := make([]string, 0, len(reloaders))
+	for name := range reloaders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.NewReload"
+
+		log := sl.FromContext(r.Context(), log).With(
+			slog.String("op", op),
+		)
+
+		p, _ := auth.FromContext(r.Context())
+
+		reloaded := make([]string, 0, len(names))
+		results := make([]ReloadResult, 0, len(names))
+		var failed []string
+		for _, name := range names {
+			if err := reloaders[name](); err != nil {
+				log.Error("failed to reload", slog.String("source", name), sl.Err(err))
+				results = append(results, ReloadResult{Source: name, Status: resp.StatusError, Error: err.Error()})
+				failed = append(failed, name)
+				continue
+			}
+			reloaded = append(reloaded, name)
+			results = append(results, ReloadResult{Source: name, Status: resp.StatusOk})
+		}
+
+		log.Info("configuration reloaded", slog.Any("sources", reloaded), slog.Any("failed", failed), slog.String("by", p.Subject))
+
+		status := resp.OK()
+		if len(failed) > 0 {
+			status = resp.Error("failed to reload " + strings.Join(failed, ", "))
+		}
+
+		render.JSON(w, r, ReloadResponse{
+			Response: status,
+			Reloaded: reloaded,
+			Results:  results,
+		})
+	}
+}
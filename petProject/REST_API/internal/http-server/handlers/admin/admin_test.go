@@ -0,0 +1,183 @@
+package admin_test
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"main.go/internal/http-server/handlers/admin"
+	"main.go/internal/http-server/handlers/admin/mocks"
+	"main.go/internal/lib/logger/handlers/slogdiscard"
+	"main.go/internal/lib/logger/handlers/sloglevel"
+	"main.go/internal/storage"
+)
+
+func TestBulkDeleteHandler(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "Success",
+			input: `{"aliases": ["a", "b", "missing"]}`,
+			want:  `{"status": "Ok", "deleted": 2}`,
+		},
+		{
+			name:  "Empty list",
+			input: `{"aliases": []}`,
+			want:  `{"status": "Error", "error": "field Aliases is not valid"}`,
+		},
+		{
+			name:  "Empty alias",
+			input: `{"aliases": ["a", ""]}`,
+			want:  `{"status": "Error", "error": "failed Aliases[1] is a reuired field"}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			deleterMock := mocks.NewURLsDeleter(t)
+			if tc.name == "Success" {
+				deleterMock.On("DeleteURLs", mock.Anything, []string{"a", "b", "missing"}).Return(int64(2), nil).Once()
+			}
+
+			rr := httptest.NewRecorder()
+			admin.NewBulkDelete(slogdiscard.NewDiscardLogger(), deleterMock).
+				ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/admin/links/delete", strings.NewReader(tc.input)))
+
+			assert.JSONEq(t, tc.want, rr.Body.String())
+		})
+	}
+}
+
+func TestExportHandler(t *testing.T) {
+	// Первая страница полная, значит нужна вторая
+	first := make([]storage.URL, 500)
+	for i := range first {
+		first[i] = storage.URL{Alias: fmt.Sprintf("a%d", i), URL: "https://example.com"}
+	}
+	second := []storage.URL{{Alias: "last", URL: "https://example.com/last", URLOptions: storage.URLOptions{OwnerID: 3}}}
+
+	listerMock := mocks.NewURLLister(t)
+	listerMock.On("ListURLs", mock.Anything, storage.ListFilter{AllOwners: true, Limit: 500}).Return(first, nil).Once()
+	listerMock.On("ListURLs", mock.Anything, storage.ListFilter{AllOwners: true, Limit: 500, Offset: 500}).Return(second, nil).Once()
+
+	rr := httptest.NewRecorder()
+	admin.NewExport(slogdiscard.NewDiscardLogger(), listerMock).
+		ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/links/export", nil))
+
+	assert.Contains(t, rr.Header().Get("Content-Disposition"), "attachment")
+	assert.Contains(t, rr.Body.String(), `{"alias":"last","url":"https://example.com/last","owner_id":3}`)
+	assert.Equal(t, 501, strings.Count(rr.Body.String(), `"alias"`))
+}
+
+func TestReloadHandler(t *testing.T) {
+	var calls []string
+	ok := func(name string) func() error {
+		return func() error { calls = append(calls, name); return nil }
+	}
+
+	rr := httptest.NewRecorder()
+	admin.NewReload(slogdiscard.NewDiscardLogger(), map[string]func() error{"url_policy": ok("url_policy"), "jwks": ok("jwks")}).
+		ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/admin/reload", nil))
+
+	require.JSONEq(t, `{"status": "Ok", "reloaded": ["jwks", "url_policy"], "results": [
+		{"source": "jwks", "status": "Ok"},
+		{"source": "url_policy", "status": "Ok"}
+	]}`, rr.Body.String())
+	assert.Equal(t, []string{"jwks", "url_policy"}, calls)
+
+	// Ошибка одного источника не мешает перечитать остальные
+	calls = nil
+	rr = httptest.NewRecorder()
+	admin.NewReload(slogdiscard.NewDiscardLogger(), map[string]func() error{
+		"jwks":       func() error { return errors.New("bad jwks") },
+		"log_level":  func() error { return errors.New("bad config") },
+		"url_policy": ok("url_policy"),
+	}).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/admin/reload", nil))
+
+	assert.JSONEq(t, `{"status": "Error", "error": "failed to reload jwks, log_level", "reloaded": ["url_policy"], "results": [
+		{"source": "jwks", "status": "Error", "error": "bad jwks"},
+		{"source": "log_level", "status": "Error", "error": "bad config"},
+		{"source": "url_policy", "status": "Ok"}
+	]}`, rr.Body.String())
+	assert.Equal(t, []string{"url_policy"}, calls)
+}
+
+func TestSetLogLevelHandler(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		setup func(m *mocks.LevelController)
+		want  string
+	}{
+		{
+			name:  "All sinks",
+			input: `{"level": "debug"}`,
+			setup: func(m *mocks.LevelController) {
+				m.On("Set", slog.LevelDebug, time.Duration(0)).Once()
+			},
+			want: `{"status": "Ok", "levels": {"sinks": ["DEBUG"]}}`,
+		},
+		{
+			name:  "Component for duration",
+			input: `{"level": "debug", "op": "handlers.url", "duration": "15m"}`,
+			setup: func(m *mocks.LevelController) {
+				m.On("SetOp", "handlers.url", slog.LevelDebug, 15*time.Minute).Once()
+			},
+			want: `{"status": "Ok", "levels": {"sinks": ["DEBUG"]}}`,
+		},
+		{
+			name:  "Empty level",
+			input: `{}`,
+			want:  `{"status": "Error", "error": "failed Level is a reuired field"}`,
+		},
+		{
+			name:  "Unknown level",
+			input: `{"level": "loud"}`,
+			want:  `{"status": "Error", "error": "invalid level"}`,
+		},
+		{
+			name:  "Invalid duration",
+			input: `{"level": "warn", "duration": "-1m"}`,
+			want:  `{"status": "Error", "error": "invalid duration"}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			levelsMock := mocks.NewLevelController(t)
+			if tc.setup != nil {
+				tc.setup(levelsMock)
+				levelsMock.On("State").Return(sloglevel.State{Sinks: []slog.Level{slog.LevelDebug}}).Once()
+			}
+
+			rr := httptest.NewRecorder()
+			admin.NewSetLogLevel(slogdiscard.NewDiscardLogger(), levelsMock).
+				ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/admin/log-level", strings.NewReader(tc.input)))
+
+			assert.JSONEq(t, tc.want, rr.Body.String())
+		})
+	}
+}
+
+func TestResetLogLevelHandler(t *testing.T) {
+	levelsMock := mocks.NewLevelController(t)
+	levelsMock.On("Reset").Once()
+	levelsMock.On("State").Return(sloglevel.State{Sinks: []slog.Level{slog.LevelInfo}}).Once()
+
+	rr := httptest.NewRecorder()
+	admin.NewResetLogLevel(slogdiscard.NewDiscardLogger(), levelsMock).
+		ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/admin/log-level", nil))
+
+	assert.JSONEq(t, `{"status": "Ok", "levels": {"sinks": ["INFO"]}}`, rr.Body.String())
+}
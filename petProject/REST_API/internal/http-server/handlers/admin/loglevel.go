@@ -0,0 +1,135 @@
+package admin
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	resp "main.go/internal/lib/api/response"
+	"main.go/internal/lib/auth"
+	"main.go/internal/lib/logger/handlers/sloglevel"
+	"main.go/internal/lib/logger/sl"
+)
+
+type LogLevelRequest struct {
+	Level    string `json:"level" validate:"required"` // debug, info, warn, error
+	Op       string `json:"op,omitempty"`              // только для компонента: "handlers.url" или "handlers.url.save.New"
+	Duration string `json:"duration,omitempty"`        // "15m", пустой - до сброса
+}
+
+type LogLevelResponse struct {
+	resp.Response
+	Levels sloglevel.State `json:"levels"`
+}
+
+// LevelController is an interface for changing log levels at runtime.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=LevelController
+type LevelController interface {
+	Set(level slog.Level, d time.Duration)
+	SetOp(op string, level slog.Level, d time.Duration)
+	Reset()
+	State() sloglevel.State
+}
+
+// NewGetLogLevel returns the current log levels and overrides.
+func NewGetLogLevel(levels LevelController) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, LogLevelResponse{
+			Response: resp.OK(),
+			Levels:   levels.State(),
+		})
+	}
+}
+
+// NewSetLogLevel changes the level of all sinks or of one component (op), optionally for a duration.
+func NewSetLogLevel(log *slog.Logger, levels LevelController) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.NewSetLogLevel"
+
+		log := sl.FromContext(r.Context(), log).With(
+			slog.String("op", op),
+		)
+
+		var req LogLevelRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			var validateErrs validator.ValidationErrors
+			if errors.As(err, &validateErrs) {
+				log.Info("invalid request", sl.Err(err))
+				render.JSON(w, r, resp.ValidationError(validateErrs))
+				return
+			}
+
+			log.Error("failed to validate request", sl.Err(err))
+			render.JSON(w, r, resp.Error("invalid request"))
+			return
+		}
+
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(req.Level)); err != nil {
+			log.Info("invalid level", sl.Err(err))
+			render.JSON(w, r, resp.Error("invalid level"))
+			return
+		}
+
+		var d time.Duration
+		if req.Duration != "" {
+			var err error
+			d, err = time.ParseDuration(req.Duration)
+			if err != nil || d <= 0 {
+				log.Info("invalid duration", slog.String("duration", req.Duration))
+				render.JSON(w, r, resp.Error("invalid duration"))
+				return
+			}
+		}
+
+		// Пишем до изменения: после повышения уровня Info уже может не попасть в лог
+		p, _ := auth.FromContext(r.Context())
+		log.Info("log level changed",
+			slog.String("level", level.String()),
+			slog.String("target_op", req.Op),
+			slog.Duration("duration", d),
+			slog.String("by", p.Subject),
+		)
+
+		if req.Op != "" {
+			levels.SetOp(req.Op, level, d)
+		} else {
+			levels.Set(level, d)
+		}
+
+		render.JSON(w, r, LogLevelResponse{
+			Response: resp.OK(),
+			Levels:   levels.State(),
+		})
+	}
+}
+
+// NewResetLogLevel returns all sinks to the configured levels and drops overrides.
+func NewResetLogLevel(log *slog.Logger, levels LevelController) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.NewResetLogLevel"
+
+		levels.Reset()
+
+		p, _ := auth.FromContext(r.Context())
+		sl.FromContext(r.Context(), log).Info("log level reset",
+			slog.String("op", op),
+			slog.String("by", p.Subject),
+		)
+
+		render.JSON(w, r, LogLevelResponse{
+			Response: resp.OK(),
+			Levels:   levels.State(),
+		})
+	}
+}
@@ -0,0 +1,65 @@
+// Code generated by mockery v2.53.7. DO NOT EDIT.
+
+package mocks
+
+import (
+	slog "log/slog"
+
+	mock "github.com/stretchr/testify/mock"
+
+	sloglevel "main.go/internal/lib/logger/handlers/sloglevel"
+
+	time "time"
+)
+
+// LevelController is an autogenerated mock type for the LevelController type
+type LevelController struct {
+	mock.Mock
+}
+
+// Reset provides a mock function with no fields
+func (_m *LevelController) Reset() {
+	_m.Called()
+}
+
+// Set provides a mock function with given fields: level, d
+func (_m *LevelController) Set(level slog.Level, d time.Duration) {
+	_m.Called(level, d)
+}
+
+// SetOp provides a mock function with given fields: op, level, d
+func (_m *LevelController) SetOp(op string, level slog.Level, d time.Duration) {
+	_m.Called(op, level, d)
+}
+
+// State provides a mock function with no fields
+func (_m *LevelController) State() sloglevel.State {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for State")
+	}
+
+	var r0 sloglevel.State
+	if rf, ok := ret.Get(0).(func() sloglevel.State); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(sloglevel.State)
+	}
+
+	return r0
+}
+
+// NewLevelController creates a new instance of LevelController. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewLevelController(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *LevelController {
+	mock := &LevelController{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,56 @@
+// Code generated by mockery v2.53.7. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// URLsDeleter is an autogenerated mock type for the URLsDeleter type
+type URLsDeleter struct {
+	mock.Mock
+}
+
+// DeleteURLs provides a mock function with given fields: ctx, aliases
+func (_m *URLsDeleter) DeleteURLs(ctx context.Context, aliases []string) (int64, error) {
+	ret := _m.Called(ctx, aliases)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteURLs")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) (int64, error)); ok {
+		return rf(ctx, aliases)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) int64); ok {
+		r0 = rf(ctx, aliases)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, aliases)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewURLsDeleter creates a new instance of URLsDeleter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewURLsDeleter(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *URLsDeleter {
+	mock := &URLsDeleter{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
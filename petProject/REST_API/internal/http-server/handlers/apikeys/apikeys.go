@@ -0,0 +1,211 @@
+// Выпуск и отзыв API-ключей: /keys
+
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	resp "main.go/internal/lib/api/response"
+	"main.go/internal/lib/auth"
+	"main.go/internal/lib/logger/sl"
+	"main.go/internal/storage"
+)
+
+type Request struct {
+	UserID    int64     `json:"user_id,omitempty"` // чей ключ, по умолчанию - того, кто его выпускает
+	Name      string    `json:"name" validate:"required,max=64"`
+	Scopes    []string  `json:"scopes" validate:"required,min=1,dive,oneof=create read delete admin"`
+	ExpiresAt time.Time `json:"expires_at,omitzero"` // RFC 3339, пустое - бессрочный ключ
+}
+
+type CreateResponse struct {
+	resp.Response
+	ID        int64     `json:"id,omitempty"`
+	Key       string    `json:"key,omitempty"` // показывается только один раз
+	Prefix    string    `json:"prefix,omitempty"`
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+}
+
+type ListResponse struct {
+	resp.Response
+	Keys []storage.APIKey `json:"keys"`
+}
+
+// KeySaver is an interface for storing a new API key.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=KeySaver
+type KeySaver interface {
+	SaveAPIKey(ctx context.Context, key storage.APIKey, hash string) (int64, error)
+}
+
+// KeyLister is an interface for listing issued API keys.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=KeyLister
+type KeyLister interface {
+	ListAPIKeys(ctx context.Context) ([]storage.APIKey, error)
+}
+
+// KeyRevoker is an interface for revoking an API key.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=KeyRevoker
+type KeyRevoker interface {
+	RevokeAPIKey(ctx context.Context, id int64, at time.Time) error
+}
+
+// NewCreate issues a new API key.
+func NewCreate(log *slog.Logger, keySaver KeySaver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.apikeys.NewCreate"
+
+		log := sl.FromContext(r.Context(), log).With(
+			slog.String("op", op),
+		)
+
+		var req Request
+		err := render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+			render.JSON(w, r, resp.Error("empty request"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			var validateErrs validator.ValidationErrors
+			if errors.As(err, &validateErrs) {
+				log.Info("invalid request", sl.Err(err))
+				render.JSON(w, r, resp.ValidationError(validateErrs))
+				return
+			}
+
+			log.Error("failed to validate request", sl.Err(err))
+			render.JSON(w, r, resp.Error("invalid request"))
+			return
+		}
+
+		now := time.Now()
+		if !req.ExpiresAt.IsZero() && !req.ExpiresAt.After(now) {
+			log.Info("expiration in the past")
+			render.JSON(w, r, resp.Error("expires_at must be in the future"))
+			return
+		}
+
+		key, prefix, hash, err := auth.GenerateKey()
+		if err != nil {
+			log.Error("failed to generate key", sl.Err(err))
+			render.JSON(w, r, resp.Error("failed to create key"))
+			return
+		}
+
+		p, _ := auth.FromContext(r.Context())
+
+		userID := req.UserID
+		if userID == 0 {
+			userID = p.UserID
+		}
+
+		id, err := keySaver.SaveAPIKey(r.Context(), storage.APIKey{
+			UserID:    userID,
+			Name:      req.Name,
+			Prefix:    prefix,
+			Scopes:    req.Scopes,
+			CreatedAt: now,
+			ExpiresAt: req.ExpiresAt,
+		}, hash)
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("user not found", slog.Int64("user_id", userID))
+			render.JSON(w, r, resp.Error("user not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to save key", sl.Err(err))
+			render.JSON(w, r, resp.Error("failed to create key"))
+			return
+		}
+
+		log.Info("api key created", slog.Int64("id", id), slog.String("name", req.Name), slog.String("by", p.Subject))
+
+		render.JSON(w, r, CreateResponse{
+			Response:  resp.OK(),
+			ID:        id,
+			Key:       key,
+			Prefix:    prefix,
+			ExpiresAt: req.ExpiresAt,
+		})
+	}
+}
+
+// NewList returns issued keys without the keys themselves.
+func NewList(log *slog.Logger, keyLister KeyLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.apikeys.NewList"
+
+		log := sl.FromContext(r.Context(), log).With(
+			slog.String("op", op),
+		)
+
+		keys, err := keyLister.ListAPIKeys(r.Context())
+		if err != nil {
+			log.Error("failed to list keys", sl.Err(err))
+			render.JSON(w, r, resp.Error("internal error"))
+			return
+		}
+
+		if keys == nil {
+			keys = []storage.APIKey{} // в JSON пустой список, а не null
+		}
+
+		render.JSON(w, r, ListResponse{
+			Response: resp.OK(),
+			Keys:     keys,
+		})
+	}
+}
+
+// NewRevoke revokes a key by its id.
+func NewRevoke(log *slog.Logger, keyRevoker KeyRevoker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.apikeys.NewRevoke"
+
+		log := sl.FromContext(r.Context(), log).With(
+			slog.String("op", op),
+		)
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			log.Info("invalid key id", sl.Err(err))
+			render.JSON(w, r, resp.Error("invalid key id"))
+			return
+		}
+
+		err = keyRevoker.RevokeAPIKey(r.Context(), id, time.Now())
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			log.Info("key not found", slog.Int64("id", id))
+			render.JSON(w, r, resp.Error("not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to revoke key", sl.Err(err))
+			render.JSON(w, r, resp.Error("internal error"))
+			return
+		}
+
+		p, _ := auth.FromContext(r.Context())
+		log.Info("api key revoked", slog.Int64("id", id), slog.String("by", p.Subject))
+
+		render.JSON(w, r, resp.OK())
+	}
+}
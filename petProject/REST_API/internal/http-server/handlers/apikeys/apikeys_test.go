@@ -0,0 +1,132 @@
+package apikeys_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"main.go/internal/http-server/handlers/apikeys"
+	"main.go/internal/http-server/handlers/apikeys/mocks"
+	"main.go/internal/lib/auth"
+	"main.go/internal/lib/logger/handlers/slogdiscard"
+	"main.go/internal/storage"
+)
+
+func TestCreateHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		input     string
+		respError string
+	}{
+		{
+			name:  "Success",
+			input: `{"name": "ci", "scopes": ["create", "read"]}`,
+		},
+		{
+			name:      "Unknown user",
+			input:     `{"name": "ci", "scopes": ["read"], "user_id": 42}`,
+			respError: "user not found",
+		},
+		{
+			name:      "Unknown scope",
+			input:     `{"name": "ci", "scopes": ["write"]}`,
+			respError: "field Scopes[0] is not valid",
+		},
+		{
+			name:      "No scopes",
+			input:     `{"name": "ci", "scopes": []}`,
+			respError: "field Scopes is not valid",
+		},
+		{
+			name:      "Expired",
+			input:     `{"name": "ci", "scopes": ["read"], "expires_at": "2020-01-01T00:00:00Z"}`,
+			respError: "expires_at must be in the future",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			keySaverMock := mocks.NewKeySaver(t)
+
+			var savedHash string
+			if tc.respError == "user not found" {
+				keySaverMock.On("SaveAPIKey", mock.Anything, mock.MatchedBy(func(k storage.APIKey) bool { return k.UserID == 42 }), mock.AnythingOfType("string")).
+					Return(int64(0), storage.ErrUserNotFound).
+					Once()
+			}
+			if tc.respError == "" {
+				keySaverMock.On("SaveAPIKey", mock.Anything, mock.MatchedBy(func(k storage.APIKey) bool {
+					// Без user_id ключ достается пользователю, который его выпускает
+					return k.Name == "ci" && k.UserID == 5 && strings.HasPrefix(k.Prefix, "sk_")
+				}), mock.AnythingOfType("string")).
+					Run(func(args mock.Arguments) { savedHash = args.String(2) }).
+					Return(int64(7), nil).
+					Once()
+			}
+
+			handler := apikeys.NewCreate(slogdiscard.NewDiscardLogger(), keySaverMock)
+
+			req := httptest.NewRequest(http.MethodPost, "/keys", bytes.NewReader([]byte(tc.input)))
+			req = req.WithContext(auth.WithPrincipal(req.Context(), auth.Principal{UserID: 5, Scopes: []string{auth.ScopeAdmin}}))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var resp apikeys.CreateResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+
+			if tc.respError == "" {
+				// В базу уходит только хеш выданного ключа
+				assert.Equal(t, int64(7), resp.ID)
+				assert.Equal(t, auth.HashKey(resp.Key), savedHash)
+				assert.True(t, strings.HasPrefix(resp.Key, resp.Prefix))
+			}
+		})
+	}
+}
+
+func TestListHandler(t *testing.T) {
+	keyListerMock := mocks.NewKeyLister(t)
+	keyListerMock.On("ListAPIKeys", mock.Anything, mock.Anything).Return([]storage.APIKey{{
+		ID:        1,
+		Name:      "ci",
+		Prefix:    "sk_abcdefgh",
+		Scopes:    []string{"read"},
+		CreatedAt: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+	}}, nil).Once()
+
+	rr := httptest.NewRecorder()
+	apikeys.NewList(slogdiscard.NewDiscardLogger(), keyListerMock).
+		ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/keys", nil))
+
+	require.JSONEq(t, `{"status": "Ok", "keys": [{"id": 1, "name": "ci", "prefix": "sk_abcdefgh", "scopes": ["read"], "created_at": "2025-03-01T00:00:00Z"}]}`,
+		rr.Body.String())
+}
+
+func TestRevokeHandler(t *testing.T) {
+	keyRevokerMock := mocks.NewKeyRevoker(t)
+	keyRevokerMock.On("RevokeAPIKey", mock.Anything, int64(7), mock.AnythingOfType("time.Time")).Return(nil).Once()
+	keyRevokerMock.On("RevokeAPIKey", mock.Anything, int64(8), mock.AnythingOfType("time.Time")).Return(storage.ErrAPIKeyNotFound).Once()
+
+	r := chi.NewRouter()
+	r.Delete("/keys/{id}", apikeys.NewRevoke(slogdiscard.NewDiscardLogger(), keyRevokerMock))
+
+	for path, want := range map[string]string{
+		"/keys/7":   `{"status": "Ok"}`,
+		"/keys/8":   `{"status": "Error", "error": "not found"}`,
+		"/keys/abc": `{"status": "Error", "error": "invalid key id"}`,
+	} {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, path, nil))
+		assert.JSONEq(t, want, rr.Body.String(), path)
+	}
+}
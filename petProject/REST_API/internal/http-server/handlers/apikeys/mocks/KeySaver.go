@@ -0,0 +1,57 @@
+// Code generated by mockery v2.53.7. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+	storage "main.go/internal/storage"
+)
+
+// KeySaver is an autogenerated mock type for the KeySaver type
+type KeySaver struct {
+	mock.Mock
+}
+
+// SaveAPIKey provides a mock function with given fields: ctx, key, hash
+func (_m *KeySaver) SaveAPIKey(ctx context.Context, key storage.APIKey, hash string) (int64, error) {
+	ret := _m.Called(ctx, key, hash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveAPIKey")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, storage.APIKey, string) (int64, error)); ok {
+		return rf(ctx, key, hash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, storage.APIKey, string) int64); ok {
+		r0 = rf(ctx, key, hash)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, storage.APIKey, string) error); ok {
+		r1 = rf(ctx, key, hash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewKeySaver creates a new instance of KeySaver. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewKeySaver(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *KeySaver {
+	mock := &KeySaver{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
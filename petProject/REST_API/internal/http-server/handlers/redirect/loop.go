@@ -0,0 +1,41 @@
+// Защита от цепочек и циклов через наши же короткие ссылки
+
+package redirect
+
+import (
+	"context"
+	"errors"
+
+	"main.go/internal/lib/selflink"
+)
+
+// WithSelfLinks makes the handler follow destinations on our own hosts internally
+// and answer 508 Loop Detected instead of sending the client in circles.
+func WithSelfLinks(resolver *selflink.Resolver) Option {
+	return func(o *options) {
+		o.selfLinks = resolver
+	}
+}
+
+// resolveSelf follows static links on our own hosts starting from alias.
+// Ссылки с паролем, расписанием, правилами и т.п. не раскрываем: клиент
+// перейдет на них сам, и их проверки сработают как обычно.
+func resolveSelf(ctx context.Context, resolver *selflink.Resolver, urlGetter URLGetter, alias, dest string) (string, error) {
+	if resolver == nil {
+		return dest, nil
+	}
+
+	res, err := resolver.Resolve(alias, dest, func(next string) (string, error) {
+		link, err := urlGetter.GetURL(ctx, next)
+		if err != nil || !link.Static() {
+			return "", selflink.ErrStop
+		}
+
+		return link.URL, nil
+	})
+	if errors.Is(err, selflink.ErrNotAlias) {
+		return dest, nil
+	}
+
+	return res, err
+}
@@ -1,35 +1,59 @@
-// Code generated by mockery v2.52.2. DO NOT EDIT.
+// Code generated by mockery v2.53.7. DO NOT EDIT.
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	storage "main.go/internal/storage"
+)
 
 // URLGetter is an autogenerated mock type for the URLGetter type
 type URLGetter struct {
 	mock.Mock
 }
 
-// GetURL provides a mock function with given fields: alias
-func (_m *URLGetter) GetURL(alias string) (string, error) {
-	ret := _m.Called(alias)
+// ConsumeClick provides a mock function with given fields: ctx, alias
+func (_m *URLGetter) ConsumeClick(ctx context.Context, alias string) error {
+	ret := _m.Called(ctx, alias)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ConsumeClick")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, alias)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetURL provides a mock function with given fields: ctx, alias
+func (_m *URLGetter) GetURL(ctx context.Context, alias string) (storage.URL, error) {
+	ret := _m.Called(ctx, alias)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetURL")
 	}
 
-	var r0 string
+	var r0 storage.URL
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string) (string, error)); ok {
-		return rf(alias)
+	if rf, ok := ret.Get(0).(func(context.Context, string) (storage.URL, error)); ok {
+		return rf(ctx, alias)
 	}
-	if rf, ok := ret.Get(0).(func(string) string); ok {
-		r0 = rf(alias)
+	if rf, ok := ret.Get(0).(func(context.Context, string) storage.URL); ok {
+		r0 = rf(ctx, alias)
 	} else {
-		r0 = ret.Get(0).(string)
+		r0 = ret.Get(0).(storage.URL)
 	}
 
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(alias)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, alias)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -37,6 +61,24 @@ func (_m *URLGetter) GetURL(alias string) (string, error) {
 	return r0, r1
 }
 
+// SaveClick provides a mock function with given fields: ctx, alias, variant
+func (_m *URLGetter) SaveClick(ctx context.Context, alias string, variant string) error {
+	ret := _m.Called(ctx, alias, variant)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveClick")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, alias, variant)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewURLGetter creates a new instance of URLGetter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewURLGetter(t interface {
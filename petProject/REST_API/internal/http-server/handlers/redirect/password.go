@@ -0,0 +1,243 @@
+// Защита коротких ссылок паролем
+
+package redirect
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/render"
+	"golang.org/x/crypto/bcrypt"
+	resp "main.go/internal/lib/api/response"
+	"main.go/internal/storage"
+)
+
+const (
+	// PasswordHeader lets API clients send the link password without the HTML form.
+	PasswordHeader = "X-Link-Password"
+
+	passwordField  = "password"
+	passwordCookie = "link_access"
+)
+
+// PasswordConfig configures password-protected links.
+type PasswordConfig struct {
+	Secret        []byte        // Ключ для подписи cookie. Если пустой - генерируется при старте
+	CookieTTL     time.Duration // Сколько живет cookie после ввода правильного пароля
+	MaxAttempts   int           // Сколько неверных паролей можно ввести за AttemptWindow
+	AttemptWindow time.Duration
+}
+
+// WithPassword configures cookie signing and attempt limiting for password-protected links.
+func WithPassword(cfg PasswordConfig) Option {
+	return func(o *options) {
+		o.password = cfg
+	}
+}
+
+var passwordForm = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Password required</title></head>
+<body>
+<form method="post">
+<p>This link is protected by a password.</p>
+{{if .}}<p style="color:red">{{.}}</p>{{end}}
+<input type="password" name="password" autofocus>
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+type passwordGuard struct {
+	cfg      PasswordConfig
+	attempts *attemptLimiter
+	now      func() time.Time
+}
+
+func newPasswordGuard(cfg PasswordConfig, now func() time.Time) *passwordGuard {
+	if len(cfg.Secret) == 0 {
+		cfg.Secret = make([]byte, 32)
+		_, _ = rand.Read(cfg.Secret)
+	}
+	if cfg.CookieTTL == 0 {
+		cfg.CookieTTL = 10 * time.Minute
+	}
+	if cfg.MaxAttempts == 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.AttemptWindow == 0 {
+		cfg.AttemptWindow = 15 * time.Minute
+	}
+
+	return &passwordGuard{
+		cfg:      cfg,
+		attempts: newAttemptLimiter(cfg.MaxAttempts, cfg.AttemptWindow, now),
+		now:      now,
+	}
+}
+
+// unlock reports whether the request may be redirected to the protected link.
+// Если нет, ответ (форма или ошибка) уже записан в w.
+func (g *passwordGuard) unlock(log *slog.Logger, w http.ResponseWriter, r *http.Request, link storage.URL) bool {
+	if c, err := r.Cookie(passwordCookie); err == nil && g.verifyCookie(c.Value, link) {
+		return true
+	}
+
+	password, fromHeader := r.Header.Get(PasswordHeader), true
+	if password == "" && r.Method == http.MethodPost {
+		password, fromHeader = r.PostFormValue(passwordField), false
+	}
+
+	if password == "" {
+		g.renderForm(w, http.StatusUnauthorized, "")
+		return false
+	}
+
+	// Попытка засчитывается до проверки пароля, иначе параллельные запросы получат больше MaxAttempts попыток
+	attempt, retry, ok := g.attempts.reserve(link.Alias)
+	if !ok {
+		log.Info("too many password attempts", slog.String("alias", link.Alias))
+
+		w.Header().Set("Retry-After", strconv.Itoa(int(retry.Seconds())+1))
+		render.Status(r, http.StatusTooManyRequests)
+		render.JSON(w, r, resp.Error("too many attempts"))
+		return false
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)) != nil {
+		log.Info("wrong link password", slog.String("alias", link.Alias))
+
+		if fromHeader {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Error("invalid password"))
+		} else {
+			g.renderForm(w, http.StatusUnauthorized, "Invalid password")
+		}
+		return false
+	}
+
+	g.attempts.release(link.Alias, attempt)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     passwordCookie,
+		Value:    g.signCookie(link, g.now().Add(g.cfg.CookieTTL)),
+		Path:     "/" + url.PathEscape(link.Alias),
+		MaxAge:   int(g.cfg.CookieTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return true
+}
+
+func (g *passwordGuard) renderForm(w http.ResponseWriter, status int, errMsg string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = passwordForm.Execute(w, errMsg)
+}
+
+// signCookie returns "<expires unix>.<mac>". В подпись входит хеш пароля,
+// поэтому после смены пароля старые cookie перестают работать.
+func (g *passwordGuard) signCookie(link storage.URL, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+
+	return exp + "." + base64.RawURLEncoding.EncodeToString(g.mac(link, exp))
+}
+
+func (g *passwordGuard) verifyCookie(value string, link storage.URL) bool {
+	exp, sig, ok := strings.Cut(value, ".")
+	if !ok {
+		return false
+	}
+
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || g.now().Unix() >= expires {
+		return false
+	}
+
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+
+	return hmac.Equal(got, g.mac(link, exp))
+}
+
+func (g *passwordGuard) mac(link storage.URL, exp string) []byte {
+	m := hmac.New(sha256.New, g.cfg.Secret)
+	m.Write([]byte(link.Alias + "\n" + exp + "\n" + link.PasswordHash))
+
+	return m.Sum(nil)
+}
+
+// attemptLimiter counts password attempts per alias in a fixed window. Удачные попытки не учитываются.
+type attemptLimiter struct {
+	mu     sync.Mutex
+	max    int
+	window time.Duration
+	now    func() time.Time
+	failed map[string]*attemptWindow
+}
+
+type attemptWindow struct {
+	count int
+	start time.Time
+}
+
+func newAttemptLimiter(max int, window time.Duration, now func() time.Time) *attemptLimiter {
+	return &attemptLimiter{
+		max:    max,
+		window: window,
+		now:    now,
+		failed: make(map[string]*attemptWindow),
+	}
+}
+
+// reserve counts an attempt for key if the limit allows it. Если нет, возвращает, сколько ждать.
+// Проверка и учет - один шаг под блокировкой: параллельные запросы не обгонят лимит.
+// Удачную попытку возвращают через release.
+func (l *attemptLimiter) reserve(key string) (*attemptWindow, time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	a, ok := l.failed[key]
+	if !ok || now.Sub(a.start) >= l.window {
+		a = &attemptWindow{start: now}
+		l.failed[key] = a
+	}
+
+	if a.count >= l.max {
+		return nil, l.window - now.Sub(a.start), false
+	}
+
+	a.count++
+
+	return a, 0, true
+}
+
+// release returns an attempt reserved for the correct password.
+func (l *attemptLimiter) release(key string, a *attemptWindow) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if a.count > 0 {
+		a.count--
+	}
+	if a.count == 0 && l.failed[key] == a {
+		delete(l.failed, key) // неудачных попыток нет - окно хранить незачем
+	}
+}
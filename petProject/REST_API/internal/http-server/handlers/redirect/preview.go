@@ -0,0 +1,136 @@
+// Страница предпросмотра ссылки вместо редиректа
+
+package redirect
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"main.go/internal/storage"
+)
+
+const (
+	// previewParam: ?preview=1 показывает страницу предпросмотра, ?preview=0 пропускает необязательную
+	previewParam  = "preview"
+	previewSuffix = "+" // /{alias}+ - то же самое, что ?preview=1
+	// previewTokenParam несет подпись кнопки Continue: без нее ?preview=0 не пропускает обязательный предпросмотр
+	previewTokenParam = "preview_token"
+	previewTokenTTL   = 10 * time.Minute
+)
+
+// WithInterstitial makes every link show the preview page before redirecting.
+// Нужно для сервисов, где ссылки создают непроверенные пользователи.
+func WithInterstitial(always bool) Option {
+	return func(o *options) {
+		o.alwaysInterstitial = always
+	}
+}
+
+var previewPage = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="robots" content="noindex"><title>Link preview</title></head>
+<body>
+<h1>You are about to leave for</h1>
+<p><code>{{.Destination}}</code></p>
+{{if not .Created.IsZero}}<p>Short link created {{.Created.UTC.Format "2006-01-02 15:04 MST"}}.</p>{{end}}
+<p><a href="{{.Continue}}">Continue</a></p>
+</body>
+</html>
+`))
+
+// splitPreviewAlias отрезает от alias суффикс предпросмотра.
+func splitPreviewAlias(alias string) (string, bool) {
+	return strings.CutSuffix(alias, previewSuffix)
+}
+
+// previewTokens signs the Continue link of the preview page.
+type previewTokens struct {
+	secret []byte
+	now    func() time.Time
+}
+
+func newPreviewTokens(secret []byte, now func() time.Time) *previewTokens {
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		_, _ = rand.Read(secret)
+	}
+
+	return &previewTokens{secret: secret, now: now}
+}
+
+// sign returns "<expires unix>.<mac>" for alias.
+func (t *previewTokens) sign(alias string) string {
+	exp := strconv.FormatInt(t.now().Add(previewTokenTTL).Unix(), 10)
+
+	return exp + "." + base64.RawURLEncoding.EncodeToString(t.mac(alias, exp))
+}
+
+func (t *previewTokens) verify(value string, alias string) bool {
+	exp, sig, ok := strings.Cut(value, ".")
+	if !ok {
+		return false
+	}
+
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || t.now().Unix() >= expires {
+		return false
+	}
+
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+
+	return hmac.Equal(got, t.mac(alias, exp))
+}
+
+func (t *previewTokens) mac(alias string, exp string) []byte {
+	m := hmac.New(sha256.New, t.secret)
+	m.Write([]byte("preview\n" + alias + "\n" + exp))
+
+	return m.Sum(nil)
+}
+
+// wantPreview reports whether the preview page should be shown instead of the redirect.
+// Обязательный предпросмотр (глобальный или у ссылки) пропускается только по подписанной кнопке Continue.
+func (t *previewTokens) wantPreview(r *http.Request, link storage.URL, suffix bool, always bool) bool {
+	q := r.URL.Query()
+	forced := always || link.Interstitial
+
+	switch q.Get(previewParam) {
+	case "1":
+		return true
+	case "0":
+		if !forced || t.verify(q.Get(previewTokenParam), link.Alias) {
+			return false
+		}
+	}
+
+	return suffix || forced
+}
+
+func (t *previewTokens) render(w http.ResponseWriter, r *http.Request, link storage.URL, destination string) {
+	// Кнопка ведет обратно на короткую ссылку, чтобы сработали счетчики кликов и статистика
+	q := r.URL.Query()
+	q.Set(previewParam, "0")
+	q.Set(previewTokenParam, t.sign(link.Alias))
+	cont := "/" + url.PathEscape(link.Alias) + pathSuffix(r) + "?" + q.Encode()
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Robots-Tag", "noindex")
+	w.WriteHeader(http.StatusOK)
+	_ = previewPage.Execute(w, struct {
+		Destination string
+		Created     time.Time
+		Continue    string
+	}{destination, link.CreatedAt, cont})
+}
@@ -0,0 +1,25 @@
+// Приватные ссылки: редирект только по alias с подписью HMAC
+
+package redirect
+
+import (
+	"main.go/internal/lib/aliassig"
+	"main.go/internal/storage"
+)
+
+// WithSigner enables private links. Без него приватные ссылки не открываются вовсе.
+func WithSigner(signer *aliassig.Signer) Option {
+	return func(o *options) {
+		o.signer = signer
+	}
+}
+
+// checkSignature reports whether the link may be opened by the alias from the URL.
+// Приватная ссылка - только с верной подписью, обычная - только без подписи.
+func checkSignature(signer *aliassig.Signer, link storage.URL, sig string, signed bool) bool {
+	if !link.Private {
+		return !signed
+	}
+
+	return signed && signer != nil && signer.Verify(link.Alias, sig)
+}
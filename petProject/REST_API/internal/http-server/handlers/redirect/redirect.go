@@ -3,15 +3,22 @@
 package redirect
 
 import (
+	"context"
 	"errors"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"log/slog"
+	"main.go/internal/lib/aliassig"
 	resp "main.go/internal/lib/api/response"
 	"main.go/internal/lib/logger/sl"
+	"main.go/internal/lib/routing"
+	"main.go/internal/lib/selflink"
+	"main.go/internal/lib/utm"
 	"main.go/internal/storage"
 )
 
@@ -19,20 +26,59 @@ import (
 //
 //go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLGetter
 type URLGetter interface {
-	GetURL(alias string) (string, error)
+	GetURL(ctx context.Context, alias string) (storage.URL, error)
+	// ConsumeClick засчитывает редирект для ссылки с ограничением кликов
+	ConsumeClick(ctx context.Context, alias string) error
+	// SaveClick записывает редирект в статистику вместе с выбранным вариантом A/B-теста
+	SaveClick(ctx context.Context, alias string, variant string) error
 }
 
-func New(log *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
+// Observer counts redirect results: hit, miss, gone, preview (для метрик).
+type Observer interface {
+	ObserveRedirect(result string)
+}
+
+// WithObserver reports the result of every redirect request to observer.
+func WithObserver(observer Observer) Option {
+	return func(o *options) {
+		o.observer = observer
+	}
+}
+
+// Option configures the redirect handler.
+type Option func(*options)
+
+type options struct {
+	password           PasswordConfig
+	schedule           ScheduleConfig
+	alwaysInterstitial bool
+	selfLinks          *selflink.Resolver
+	signer             *aliassig.Signer
+	observer           Observer
+	now                func() time.Time
+}
+
+func New(log *slog.Logger, urlGetter URLGetter, opts ...Option) http.HandlerFunc {
+	o := options{now: time.Now, observer: nopObserver{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	guard := newPasswordGuard(o.password, o.now)
+	previews := newPreviewTokens(o.password.Secret, o.now)
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.redirect.New"
 
-		log := log.With(
+		log := sl.FromContext(r.Context(), log).With(
 			slog.String("op", op),
-			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
 		// Здесь получаем параметр alias из нашего роутора
 		alias := chi.URLParam(r, "alias") // а тут мы получаем из мейна (59)
+		alias, suffix := splitPreviewAlias(alias)
+		pathAlias := alias
+		alias, sig, signed := aliassig.Split(alias)
 
 		if alias == "" {
 			log.Info("alias is empty")
@@ -43,11 +89,12 @@ func New(log *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
 		}
 
 		// Тут мы обращаемся к sqlStorage
-		resURL, err := urlGetter.GetURL(alias)
+		link, err := urlGetter.GetURL(r.Context(), alias)
 		if errors.Is(err, storage.ErrURLNotFound) {
 			log.Info("url not found", "alias", alias)
 
-			render.JSON(w, r, resp.Error("not found"))
+			o.observer.ObserveRedirect("miss")
+			responseNotFound(w, r)
 
 			return
 		}
@@ -59,9 +106,224 @@ func New(log *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
 			return
 		}
 
-		log.Info("got url", slog.String("url", resURL))
+		// Неверная подпись неотличима от несуществующей ссылки
+		if !checkSignature(o.signer, link, sig, signed) {
+			log.Info("invalid alias signature", "alias", alias)
+
+			o.observer.ObserveRedirect("miss")
+			responseNotFound(w, r)
+
+			return
+		}
+		// Cookie пароля и ссылка со страницы предпросмотра должны вести на alias из URL (с подписью)
+		link.Alias = pathAlias
+
+		if link.Exhausted() {
+			log.Info("url click limit reached", "alias", alias)
+
+			o.observer.ObserveRedirect("gone")
+			responseGone(w, r)
+
+			return
+		}
+
+		if !checkSchedule(log, o.schedule, w, r, link, o.now()) {
+			return
+		}
+
+		// Ссылка под паролем: редиректим только после правильного пароля
+		if link.PasswordHash != "" && !guard.unlock(log, w, r, link) {
+			return
+		}
+
+		// Первое подходящее правило заменяет основной URL, иначе выбираем вариант A/B-теста
+		dest, variant := link.URL, ""
+		remember := false // новый вариант, cookie для него ставится вместе с редиректом
+		if rule := routing.First(link.Rules, r, o.now()); rule != nil {
+			log.Debug("redirect rule matched", slog.Int64("rule_id", rule.ID), slog.String("type", rule.Type))
+			dest = rule.Target
+		} else if len(link.Variants) > 0 {
+			v, fresh := pickVariant(r, link)
+			dest, variant, remember = v.URL, v.Name, fresh
+		}
+
+		dest, err = resolveSelf(r.Context(), o.selfLinks, urlGetter, alias, dest)
+		if err != nil {
+			log.Error("redirect loop detected", slog.String("alias", alias), sl.Err(err))
+
+			render.Status(r, http.StatusLoopDetected)
+			render.JSON(w, r, resp.Error("redirect loop detected"))
+
+			return
+		}
+
+		resURL, err := targetURL(dest, link, r)
+		if err != nil {
+			log.Error("failed to build target url", sl.Err(err))
+
+			render.JSON(w, r, resp.Error("internal error"))
+
+			return
+		}
+
+		// Предпросмотр не считается переходом: клики и статистику не трогаем
+		if previews.wantPreview(r, link, suffix, o.alwaysInterstitial) {
+			log.Info("preview shown", slog.String("alias", alias))
+
+			o.observer.ObserveRedirect("preview")
+			previews.render(w, r, link, resURL)
+
+			return
+		}
+
+		if link.MaxClicks > 0 {
+			err := urlGetter.ConsumeClick(r.Context(), alias)
+			if errors.Is(err, storage.ErrURLGone) || errors.Is(err, storage.ErrURLNotFound) {
+				// Последний клик забрал параллельный запрос (или ссылку удалили)
+				log.Info("url click limit reached", "alias", alias)
+
+				o.observer.ObserveRedirect("gone")
+				responseGone(w, r)
+
+				return
+			}
+			if err != nil {
+				log.Error("failed to consume click", sl.Err(err))
+
+				render.JSON(w, r, resp.Error("internal error"))
+
+				return
+			}
+
+			// Ответ одноразовой ссылки не должен кешироваться
+			w.Header().Set("Cache-Control", "no-store")
+		}
+
+		// Статистика не должна ломать редирект, поэтому ошибку только логируем
+		if err := urlGetter.SaveClick(r.Context(), alias, variant); err != nil {
+			log.Error("failed to save click", sl.Err(err))
+		}
+
+		log.Info("got url", slog.String("url", resURL), slog.String("variant", variant))
+
+		if remember {
+			rememberVariant(w, r, link, variant)
+		}
 
 		// redirect to found url
+		o.observer.ObserveRedirect("hit")
 		http.Redirect(w, r, resURL, http.StatusFound)
 	}
 }
+
+type nopObserver struct{}
+
+func (nopObserver) ObserveRedirect(string) {}
+
+func responseNotFound(w http.ResponseWriter, r *http.Request) {
+	render.Status(r, http.StatusNotFound)
+	render.JSON(w, r, resp.Error("not found"))
+}
+
+func responseGone(w http.ResponseWriter, r *http.Request) {
+	render.Status(r, http.StatusGone)
+	render.JSON(w, r, resp.Error("link expired"))
+}
+
+// targetURL builds the redirect destination for the link from dest, applying
+// query string and path suffix passthrough and UTM tags when they are enabled.
+func targetURL(dest string, link storage.URL, r *http.Request) (string, error) {
+	if !link.ForwardQuery && !link.ForwardPath && link.UTM.IsZero() {
+		return dest, nil
+	}
+
+	target, err := url.Parse(dest)
+	if err != nil {
+		return "", err
+	}
+
+	if link.ForwardPath {
+		if err := appendPath(target, pathSuffix(r)); err != nil {
+			return "", err
+		}
+	}
+
+	if link.ForwardQuery {
+		mergeQuery(target, r.URL.RawQuery)
+	}
+
+	// Метки добавляем последними, чтобы не дублировать пришедшие в query
+	utm.Apply(target, link.UTM)
+
+	return target.String(), nil
+}
+
+// pathSuffix returns the escaped path after the alias segment, e.g. "/extra/path"
+// for "/abc/extra/path". Берем путь из r.URL, а не из chi.URLParam(r, "*"):
+// middleware.URLFormat отрезает расширение (".pdf") у последнего сегмента.
+func pathSuffix(r *http.Request) string {
+	p := strings.TrimPrefix(r.URL.EscapedPath(), "/")
+
+	if i := strings.IndexByte(p, '/'); i >= 0 {
+		return p[i:]
+	}
+
+	return ""
+}
+
+// appendPath joins the escaped suffix to the target path without doubling slashes
+// and without re-encoding what the client sent (например "%2F" остается "%2F").
+func appendPath(target *url.URL, suffix string) error {
+	if suffix == "" {
+		return nil
+	}
+
+	joined := strings.TrimSuffix(target.EscapedPath(), "/") + suffix
+
+	p, err := url.PathUnescape(joined)
+	if err != nil {
+		return err
+	}
+
+	target.Path = p
+	target.RawPath = joined
+
+	return nil
+}
+
+// mergeQuery appends the incoming raw query to the target query.
+// Параметры, которые уже есть в целевом URL, не перезаписываются.
+func mergeQuery(target *url.URL, incoming string) {
+	if incoming == "" {
+		return
+	}
+
+	existing := target.Query()
+
+	var pairs []string
+	for _, pair := range strings.Split(incoming, "&") {
+		if pair == "" {
+			continue
+		}
+
+		key, _, _ := strings.Cut(pair, "=")
+		if k, err := url.QueryUnescape(key); err == nil {
+			key = k
+		}
+		// preview и его подпись - наши служебные параметры, дальше их не передаем
+		if existing.Has(key) || key == previewParam || key == previewTokenParam {
+			continue
+		}
+
+		pairs = append(pairs, pair)
+	}
+
+	if len(pairs) == 0 {
+		return
+	}
+
+	if target.RawQuery != "" {
+		target.RawQuery += "&"
+	}
+	target.RawQuery += strings.Join(pairs, "&")
+}
@@ -1,16 +1,30 @@
 package redirect_test
 
 import (
+	"html"
 	"main.go/internal/http-server/handlers/redirect"
 	"main.go/internal/http-server/handlers/redirect/mocks"
+	"main.go/internal/lib/aliassig"
 	"main.go/internal/lib/api"
 	"main.go/internal/lib/logger/handlers/slogdiscard"
+	"main.go/internal/lib/routing"
+	"main.go/internal/lib/selflink"
+	"main.go/internal/lib/split"
+	"main.go/internal/lib/utm"
+	"main.go/internal/storage"
+	"net/http"
 	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestSaveHandler(t *testing.T) {
@@ -18,6 +32,9 @@ func TestSaveHandler(t *testing.T) {
 		name      string
 		alias     string
 		url       string
+		opts      storage.URLOptions
+		path      string // путь и query после хоста, по умолчанию "/" + alias
+		want      string // ожидаемый Location, по умолчанию url
 		respError string
 		mockError error
 	}{
@@ -26,30 +43,739 @@ func TestSaveHandler(t *testing.T) {
 			alias: "test_alias",
 			url:   "https://www.google.com/",
 		},
+		{
+			name:  "Query ignored without option",
+			alias: "test_alias",
+			url:   "https://example.com/page",
+			path:  "/test_alias?utm_source=x",
+		},
+		{
+			name:  "Query forwarded",
+			alias: "test_alias",
+			url:   "https://example.com/page",
+			opts:  storage.URLOptions{ForwardQuery: true},
+			path:  "/test_alias?utm_source=x&b=2",
+			want:  "https://example.com/page?utm_source=x&b=2",
+		},
+		{
+			name:  "Query merged with target params",
+			alias: "test_alias",
+			url:   "https://example.com/page?a=1&ref=link",
+			opts:  storage.URLOptions{ForwardQuery: true},
+			path:  "/test_alias?ref=evil&utm_source=x",
+			want:  "https://example.com/page?a=1&ref=link&utm_source=x",
+		},
+		{
+			name:  "Query encoding preserved",
+			alias: "test_alias",
+			url:   "https://example.com/?q=a%20b",
+			opts:  storage.URLOptions{ForwardQuery: true},
+			path:  "/test_alias?next=%2Fhome%3Fx%3D1&name=%D0%BF%D1%80%D0%B8%D0%B2%D0%B5%D1%82&q=override",
+			want:  "https://example.com/?q=a%20b&next=%2Fhome%3Fx%3D1&name=%D0%BF%D1%80%D0%B8%D0%B2%D0%B5%D1%82",
+		},
+		{
+			name:  "Path ignored without option",
+			alias: "test_alias",
+			url:   "https://example.com/docs",
+			path:  "/test_alias/extra/path",
+		},
+		{
+			name:  "Path appended",
+			alias: "test_alias",
+			url:   "https://example.com/docs/",
+			opts:  storage.URLOptions{ForwardPath: true},
+			path:  "/test_alias/extra/path",
+			want:  "https://example.com/docs/extra/path",
+		},
+		{
+			name:  "Path with extension and escaped slash",
+			alias: "test_alias",
+			url:   "https://example.com/files",
+			opts:  storage.URLOptions{ForwardPath: true},
+			path:  "/test_alias/a%2Fb/report%20v2.pdf",
+			want:  "https://example.com/files/a%2Fb/report%20v2.pdf",
+		},
+		{
+			name:  "Path and query together",
+			alias: "test_alias",
+			url:   "https://example.com/base?lang=en",
+			opts:  storage.URLOptions{ForwardQuery: true, ForwardPath: true},
+			path:  "/test_alias/item/42?utm_medium=email",
+			want:  "https://example.com/base/item/42?lang=en&utm_medium=email",
+		},
+		{
+			name:  "UTM tags appended",
+			alias: "test_alias",
+			url:   "https://example.com/?utm_source=site",
+			opts:  storage.URLOptions{UTM: utm.Params{Source: "newsletter", Medium: "email", Campaign: "spring"}},
+			want:  "https://example.com/?utm_source=site&utm_campaign=spring&utm_medium=email",
+		},
+		{
+			name:  "UTM tags not duplicated with forwarded query",
+			alias: "test_alias",
+			url:   "https://example.com/",
+			opts:  storage.URLOptions{ForwardQuery: true, UTM: utm.Params{Source: "newsletter", Medium: "email"}},
+			path:  "/test_alias?utm_medium=social",
+			want:  "https://example.com/?utm_medium=social&utm_source=newsletter",
+		},
 	}
 
 	for _, tc := range cases { // проходимся по кейсу
 		t.Run(tc.name, func(t *testing.T) { // t.run - запускает код с названием tc.name
 			urlGetterMock := mocks.NewURLGetter(t) // Создаем объект мока
+			urlGetterMock.On("SaveClick", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
 
 			if tc.respError == "" || tc.mockError != nil {
-				urlGetterMock.On("GetURL", tc.alias). // возвращает ошибку
-					Return(tc.url, tc.mockError).Once() // Once - 1 раз
+				urlGetterMock.On("GetURL", mock.Anything, tc.alias). // возвращает ошибку
+											Return(storage.URL{Alias: tc.alias, URL: tc.url, URLOptions: tc.opts}, tc.mockError).Once() // Once - 1 раз
 			}
 
+			handler := redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock)
+
 			r := chi.NewRouter() // Создает роутер - это компонент, который распределяет HTTP-запросы на обработчики.
-			r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock))
+			r.Get("/{alias}", handler)
+			r.Get("/{alias}/*", handler)
 			//Создаёт роут (/{alias}) для GET-запросов.
 			//Назначает обработчик redirect.New(...), который выполняется, когда приходит запрос
 
 			ts := httptest.NewServer(r) // Локальный http-сервис для тестирования
 			defer ts.Close()
 
-			redirectedToURL, err := api.GetRedirect(ts.URL + "/" + tc.alias) // отправляет get запрос
-			require.NoError(t, err)                                          // проверка на ошибку
+			path := tc.path
+			if path == "" {
+				path = "/" + tc.alias
+			}
+			want := tc.want
+			if want == "" {
+				want = tc.url
+			}
+
+			redirectedToURL, err := api.GetRedirect(ts.URL + path) // отправляет get запрос
+			require.NoError(t, err)                                // проверка на ошибку
 
 			// Check the final URL after redirection.
-			assert.Equal(t, tc.url, redirectedToURL) // сравнение tc.url и redirectedToURL
+			assert.Equal(t, want, redirectedToURL) // сравнение want и redirectedToURL
+		})
+	}
+}
+
+func TestPasswordProtected(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	link := storage.URL{
+		Alias:      "test_alias",
+		URL:        "https://example.com/",
+		URLOptions: storage.URLOptions{PasswordHash: string(hash)},
+	}
+
+	urlGetterMock := mocks.NewURLGetter(t)
+	urlGetterMock.On("SaveClick", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	urlGetterMock.On("GetURL", mock.Anything, "test_alias").Return(link, nil)
+
+	handler := redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, redirect.WithPassword(redirect.PasswordConfig{
+		Secret:      []byte("test-secret"),
+		MaxAttempts: 2,
+	}))
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", handler)
+	r.Post("/{alias}", handler)
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Без пароля показываем форму
+	rr := serve(httptest.NewRequest(http.MethodGet, "/test_alias", nil))
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Contains(t, rr.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, rr.Body.String(), `name="password"`)
+
+	// Правильный пароль в заголовке - редирект
+	req := httptest.NewRequest(http.MethodGet, "/test_alias", nil)
+	req.Header.Set(redirect.PasswordHeader, "secret")
+	rr = serve(req)
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, link.URL, rr.Header().Get("Location"))
+
+	// Правильный пароль из формы - редирект и cookie
+	req = httptest.NewRequest(http.MethodPost, "/test_alias", strings.NewReader("password=secret"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = serve(req)
+	require.Equal(t, http.StatusFound, rr.Code)
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, "/test_alias", cookies[0].Path)
+	assert.True(t, cookies[0].HttpOnly)
+
+	// С cookie пароль больше не спрашиваем
+	req = httptest.NewRequest(http.MethodGet, "/test_alias", nil)
+	req.AddCookie(cookies[0])
+	rr = serve(req)
+	assert.Equal(t, http.StatusFound, rr.Code)
+
+	// Поддельная cookie не подходит
+	req = httptest.NewRequest(http.MethodGet, "/test_alias", nil)
+	req.AddCookie(&http.Cookie{Name: cookies[0].Name, Value: "9999999999.AAAA"})
+	rr = serve(req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	// Неверный пароль, после MaxAttempts попыток - 429
+	for i := 0; i < 2; i++ {
+		req = httptest.NewRequest(http.MethodGet, "/test_alias", nil)
+		req.Header.Set(redirect.PasswordHeader, "wrong")
+		rr = serve(req)
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/test_alias", nil)
+	req.Header.Set(redirect.PasswordHeader, "secret")
+	rr = serve(req)
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.NotEmpty(t, rr.Header().Get("Retry-After"))
+}
+
+func TestPasswordAttemptsConcurrent(t *testing.T) {
+	// Медленный bcrypt расширяет окно между проверкой лимита и ответом
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.DefaultCost)
+	require.NoError(t, err)
+
+	link := storage.URL{
+		Alias:      "test_alias",
+		URL:        "https://example.com/",
+		URLOptions: storage.URLOptions{PasswordHash: string(hash)},
+	}
+
+	urlGetterMock := mocks.NewURLGetter(t)
+	urlGetterMock.On("GetURL", mock.Anything, "test_alias").Return(link, nil)
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, redirect.WithPassword(redirect.PasswordConfig{
+		MaxAttempts: 3,
+	})))
+
+	// Параллельные подборы пароля вместе получают не больше MaxAttempts попыток
+	const requests = 20
+	codes := make(chan int, requests)
+	start := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+
+			req := httptest.NewRequest(http.MethodGet, "/test_alias", nil)
+			req.Header.Set(redirect.PasswordHeader, "wrong")
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+			codes <- rr.Code
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(codes)
+
+	counts := map[int]int{}
+	for code := range codes {
+		counts[code]++
+	}
+	assert.Equal(t, map[int]int{http.StatusUnauthorized: 3, http.StatusTooManyRequests: requests - 3}, counts)
+}
+
+func TestMaxClicks(t *testing.T) {
+	cases := []struct {
+		name         string
+		link         storage.URL
+		consumeError error
+		wantConsume  bool
+		wantCode     int
+	}{
+		{
+			name:        "Click consumed",
+			link:        storage.URL{Alias: "test_alias", URL: "https://example.com/", URLOptions: storage.URLOptions{MaxClicks: 1}},
+			wantConsume: true,
+			wantCode:    http.StatusFound,
+		},
+		{
+			name:     "Already exhausted",
+			link:     storage.URL{Alias: "test_alias", URL: "https://example.com/", URLOptions: storage.URLOptions{MaxClicks: 3}, Clicks: 3},
+			wantCode: http.StatusGone,
+		},
+		{
+			name:         "Last click taken concurrently",
+			link:         storage.URL{Alias: "test_alias", URL: "https://example.com/", URLOptions: storage.URLOptions{MaxClicks: 1}},
+			consumeError: storage.ErrURLGone,
+			wantConsume:  true,
+			wantCode:     http.StatusGone,
+		},
+		{
+			name:     "Unlimited link is not counted",
+			link:     storage.URL{Alias: "test_alias", URL: "https://example.com/"},
+			wantCode: http.StatusFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlGetterMock := mocks.NewURLGetter(t)
+			urlGetterMock.On("SaveClick", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+			urlGetterMock.On("GetURL", mock.Anything, "test_alias").Return(tc.link, nil).Once()
+			if tc.wantConsume {
+				urlGetterMock.On("ConsumeClick", mock.Anything, "test_alias").Return(tc.consumeError).Once()
+			}
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/test_alias", nil))
+
+			assert.Equal(t, tc.wantCode, rr.Code)
+		})
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	cases := []struct {
+		name         string
+		window       storage.URLOptions
+		schedule     redirect.ScheduleConfig
+		wantCode     int
+		wantLocation string
+	}{
+		{
+			name:         "No window",
+			wantCode:     http.StatusFound,
+			wantLocation: "https://example.com/",
+		},
+		{
+			name:         "Inside window",
+			window:       storage.URLOptions{NotBefore: now.Add(-time.Hour), NotAfter: now.Add(time.Hour)},
+			wantCode:     http.StatusFound,
+			wantLocation: "https://example.com/",
+		},
+		{
+			name:     "Not started",
+			window:   storage.URLOptions{NotBefore: now.Add(time.Minute)},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:         "Not started with fallback",
+			window:       storage.URLOptions{NotBefore: now.Add(time.Minute)},
+			schedule:     redirect.ScheduleConfig{NotStartedURL: "https://example.com/soon"},
+			wantCode:     http.StatusFound,
+			wantLocation: "https://example.com/soon",
+		},
+		{
+			name:     "Ended exactly now",
+			window:   storage.URLOptions{NotAfter: now},
+			wantCode: http.StatusGone,
+		},
+		{
+			name:         "Ended with fallback",
+			window:       storage.URLOptions{NotAfter: now.Add(-time.Hour)},
+			schedule:     redirect.ScheduleConfig{EndedURL: "https://example.com/ended"},
+			wantCode:     http.StatusFound,
+			wantLocation: "https://example.com/ended",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlGetterMock := mocks.NewURLGetter(t)
+			urlGetterMock.On("SaveClick", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+			urlGetterMock.On("GetURL", mock.Anything, "test_alias").
+				Return(storage.URL{Alias: "test_alias", URL: "https://example.com/", URLOptions: tc.window}, nil).Once()
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock,
+				redirect.WithClock(clock), redirect.WithSchedule(tc.schedule)))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/test_alias", nil))
+
+			assert.Equal(t, tc.wantCode, rr.Code)
+			assert.Equal(t, tc.wantLocation, rr.Header().Get("Location"))
+		})
+	}
+}
+
+func TestRules(t *testing.T) {
+	link := storage.URL{
+		Alias:      "test_alias",
+		URL:        "https://example.com/",
+		URLOptions: storage.URLOptions{UTM: utm.Params{Source: "qr"}},
+		Rules: []routing.Rule{
+			{ID: 1, Type: routing.TypePlatform, Value: "ios", Target: "https://apps.apple.com/app/id1"},
+			{ID: 2, Type: routing.TypePlatform, Value: "android", Target: "https://play.google.com/store/apps/details?id=app"},
+			{ID: 3, Type: routing.TypeLanguage, Value: "de", Target: "https://example.com/de/"},
+		},
+	}
+
+	cases := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{
+			name:    "iOS",
+			headers: map[string]string{"User-Agent": "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)", "Accept-Language": "de"},
+			want:    "https://apps.apple.com/app/id1?utm_source=qr",
+		},
+		{
+			name:    "Android",
+			headers: map[string]string{"User-Agent": "Mozilla/5.0 (Linux; Android 14; Pixel 8)"},
+			want:    "https://play.google.com/store/apps/details?id=app&utm_source=qr",
+		},
+		{
+			name:    "German desktop",
+			headers: map[string]string{"User-Agent": "Mozilla/5.0 (X11; Linux x86_64)", "Accept-Language": "de-DE,en;q=0.5"},
+			want:    "https://example.com/de/?utm_source=qr",
+		},
+		{
+			name:    "Default",
+			headers: map[string]string{"User-Agent": "Mozilla/5.0 (X11; Linux x86_64)", "Accept-Language": "en-US"},
+			want:    "https://example.com/?utm_source=qr",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlGetterMock := mocks.NewURLGetter(t)
+			urlGetterMock.On("SaveClick", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+			urlGetterMock.On("GetURL", mock.Anything, "test_alias").Return(link, nil).Once()
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock))
+
+			req := httptest.NewRequest(http.MethodGet, "/test_alias", nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusFound, rr.Code)
+			assert.Equal(t, tc.want, rr.Header().Get("Location"))
 		})
 	}
 }
+
+func TestVariants(t *testing.T) {
+	link := storage.URL{
+		Alias: "test_alias",
+		URL:   "https://example.com/",
+		URLOptions: storage.URLOptions{
+			Variants: []split.Variant{
+				{Name: "a", URL: "https://example.com/a", Weight: 1},
+				{Name: "b", URL: "https://example.com/b", Weight: 1},
+			},
+			StickyVariant: true,
+		},
+	}
+
+	urlGetterMock := mocks.NewURLGetter(t)
+	urlGetterMock.On("GetURL", mock.Anything, "test_alias").Return(link, nil)
+
+	var served []string
+	urlGetterMock.On("SaveClick", mock.Anything, "test_alias", mock.AnythingOfType("string")).
+		Run(func(args mock.Arguments) { served = append(served, args.String(2)) }).
+		Return(nil)
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock))
+
+	// Первый визит: вариант выбирается случайно и запоминается в cookie
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/test_alias", nil))
+	require.Equal(t, http.StatusFound, rr.Code)
+
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+	require.Len(t, served, 1)
+
+	first, ok := split.Find(link.Variants, served[0])
+	require.True(t, ok)
+	assert.Equal(t, first.URL, rr.Header().Get("Location"))
+	assert.Equal(t, first.Name, cookies[0].Value)
+
+	// Повторные визиты с cookie получают тот же вариант
+	for i := 0; i < 10; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/test_alias", nil)
+		req.AddCookie(cookies[0])
+
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		assert.Equal(t, first.URL, rr.Header().Get("Location"))
+	}
+
+	for _, name := range served {
+		assert.Equal(t, first.Name, name)
+	}
+}
+
+func TestPreview(t *testing.T) {
+	created := time.Date(2025, 3, 1, 10, 30, 0, 0, time.UTC)
+
+	cases := []struct {
+		name         string
+		path         string
+		interstitial bool
+		always       bool
+		wantPreview  bool
+	}{
+		{name: "Plain redirect", path: "/test_alias"},
+		{name: "Plus suffix", path: "/test_alias+", wantPreview: true},
+		{name: "Query param", path: "/test_alias?preview=1", wantPreview: true},
+		{name: "Per-link interstitial", path: "/test_alias", interstitial: true, wantPreview: true},
+		{name: "Global interstitial", path: "/test_alias", always: true, wantPreview: true},
+		{name: "Skip optional preview", path: "/test_alias+?preview=0"},
+		{name: "Bare preview=0 keeps global interstitial", path: "/test_alias?preview=0", always: true, wantPreview: true},
+		{name: "Bare preview=0 keeps per-link interstitial", path: "/test_alias?preview=0", interstitial: true, wantPreview: true},
+		{name: "Forged token keeps interstitial", path: "/test_alias?preview=0&preview_token=9999999999.AAAA", interstitial: true, wantPreview: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			link := storage.URL{
+				Alias:      "test_alias",
+				URL:        "https://example.com/page?a=1&b=<2>",
+				URLOptions: storage.URLOptions{Interstitial: tc.interstitial, MaxClicks: 1},
+				CreatedAt:  created,
+			}
+
+			urlGetterMock := mocks.NewURLGetter(t)
+			urlGetterMock.On("GetURL", mock.Anything, "test_alias").Return(link, nil).Once()
+			if !tc.wantPreview {
+				urlGetterMock.On("ConsumeClick", mock.Anything, "test_alias").Return(nil).Once()
+				urlGetterMock.On("SaveClick", mock.Anything, "test_alias", "").Return(nil).Once()
+			}
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, redirect.WithInterstitial(tc.always)))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			if !tc.wantPreview {
+				assert.Equal(t, http.StatusFound, rr.Code)
+				assert.Equal(t, link.URL, rr.Header().Get("Location"))
+				return
+			}
+
+			// Предпросмотр не расходует клик и показывает адрес экранированным
+			assert.Equal(t, http.StatusOK, rr.Code)
+			body := rr.Body.String()
+			assert.Contains(t, body, "https://example.com/page?a=1&amp;b=&lt;2&gt;")
+			assert.Contains(t, body, "2025-03-01 10:30 UTC")
+			assert.Contains(t, body, `href="/test_alias?preview=0&amp;preview_token=`)
+		})
+	}
+}
+
+func TestPreviewContinue(t *testing.T) {
+	now := time.Date(2025, 3, 1, 10, 30, 0, 0, time.UTC)
+	link := storage.URL{
+		Alias:      "test_alias",
+		URL:        "https://example.com/page",
+		URLOptions: storage.URLOptions{Interstitial: true},
+	}
+
+	urlGetterMock := mocks.NewURLGetter(t)
+	urlGetterMock.On("GetURL", mock.Anything, "test_alias").Return(link, nil)
+	urlGetterMock.On("GetURL", mock.Anything, "other").Return(storage.URL{Alias: "other", URL: link.URL, URLOptions: link.URLOptions}, nil).Once()
+	urlGetterMock.On("SaveClick", mock.Anything, "test_alias", "").Return(nil).Once()
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, redirect.WithClock(func() time.Time { return now })))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr
+	}
+
+	rr := get("/test_alias")
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	m := regexp.MustCompile(`href="([^"]+)"`).FindStringSubmatch(rr.Body.String())
+	require.Len(t, m, 2)
+	cont := html.UnescapeString(m[1])
+
+	// Подписанная кнопка Continue ведет на адрес назначения
+	rr = get(cont)
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, link.URL, rr.Header().Get("Location"))
+
+	// Подпись другой ссылки не подходит
+	assert.Equal(t, http.StatusOK, get(strings.Replace(cont, "/test_alias", "/other", 1)).Code)
+
+	// Просроченная подпись снова показывает предпросмотр
+	now = now.Add(11 * time.Minute)
+	assert.Equal(t, http.StatusOK, get(cont).Code)
+}
+
+func TestSelfLinks(t *testing.T) {
+	cases := []struct {
+		name     string
+		links    map[string]storage.URL
+		status   int
+		location string
+	}{
+		{
+			name: "Chain is followed internally",
+			links: map[string]storage.URL{
+				"a": {Alias: "a", URL: "https://sho.rt/b"},
+				"b": {Alias: "b", URL: "https://example.com/final"},
+			},
+			status:   http.StatusFound,
+			location: "https://example.com/final",
+		},
+		{
+			name: "Protected link is not unwrapped",
+			links: map[string]storage.URL{
+				"a": {Alias: "a", URL: "https://sho.rt/b"},
+				"b": {Alias: "b", URL: "https://example.com/secret", URLOptions: storage.URLOptions{PasswordHash: "hash"}},
+			},
+			status:   http.StatusFound,
+			location: "https://sho.rt/b",
+		},
+		{
+			name: "Loop",
+			links: map[string]storage.URL{
+				"a": {Alias: "a", URL: "https://sho.rt/b"},
+				"b": {Alias: "b", URL: "https://sho.rt/a"},
+			},
+			status: http.StatusLoopDetected,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlGetterMock := mocks.NewURLGetter(t)
+			for alias, link := range tc.links {
+				urlGetterMock.On("GetURL", mock.Anything, alias).Return(link, nil)
+			}
+			urlGetterMock.On("SaveClick", mock.Anything, "a", "").Return(nil).Maybe()
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock,
+				redirect.WithSelfLinks(selflink.New([]string{"sho.rt"}, 0))))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/a", nil))
+
+			assert.Equal(t, tc.status, rr.Code)
+			assert.Equal(t, tc.location, rr.Header().Get("Location"))
+		})
+	}
+}
+
+func TestPrivateLinks(t *testing.T) {
+	signer := aliassig.New([]byte("test-secret"), 0)
+
+	urlGetterMock := mocks.NewURLGetter(t)
+	urlGetterMock.On("SaveClick", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	urlGetterMock.On("GetURL", mock.Anything, "private").Return(storage.URL{
+		Alias:      "private",
+		URL:        "https://example.com/private",
+		URLOptions: storage.URLOptions{Private: true},
+	}, nil)
+	urlGetterMock.On("GetURL", mock.Anything, "public").Return(storage.URL{Alias: "public", URL: "https://example.com/public"}, nil)
+	urlGetterMock.On("GetURL", mock.Anything, "missing").Return(storage.URL{}, storage.ErrURLNotFound)
+
+	withSigner := chi.NewRouter()
+	withSigner.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, redirect.WithSigner(signer)))
+
+	withoutSigner := chi.NewRouter()
+	withoutSigner.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock))
+
+	cases := []struct {
+		name   string
+		router http.Handler
+		path   string
+		status int
+	}{
+		{name: "Signed", router: withSigner, path: "/" + signer.Sign("private"), status: http.StatusFound},
+		{name: "Unsigned", router: withSigner, path: "/private", status: http.StatusNotFound},
+		{name: "Wrong signature", router: withSigner, path: "/private~AAAAAAAA", status: http.StatusNotFound},
+		{name: "Public", router: withSigner, path: "/public", status: http.StatusFound},
+		{name: "Public with signature", router: withSigner, path: "/" + signer.Sign("public"), status: http.StatusNotFound},
+		{name: "Missing", router: withSigner, path: "/missing", status: http.StatusNotFound},
+		{name: "No secret configured", router: withoutSigner, path: "/" + signer.Sign("private"), status: http.StatusNotFound},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			tc.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			assert.Equal(t, tc.status, rr.Code)
+		})
+	}
+}
+
+type resultRecorder []string
+
+func (r *resultRecorder) ObserveRedirect(result string) { *r = append(*r, result) }
+
+func TestObserver(t *testing.T) {
+	urlGetterMock := mocks.NewURLGetter(t)
+	urlGetterMock.On("SaveClick", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	urlGetterMock.On("GetURL", mock.Anything, "found").Return(storage.URL{Alias: "found", URL: "https://example.com"}, nil)
+	urlGetterMock.On("GetURL", mock.Anything, "missing").Return(storage.URL{}, storage.ErrURLNotFound)
+	urlGetterMock.On("GetURL", mock.Anything, "used").Return(storage.URL{Alias: "used", URL: "https://example.com",
+		URLOptions: storage.URLOptions{MaxClicks: 1}, Clicks: 1}, nil)
+
+	var results resultRecorder
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, redirect.WithObserver(&results)))
+
+	for _, path := range []string{"/found", "/missing", "/used", "/found+"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, resultRecorder{"hit", "miss", "gone", "preview"}, results)
+}
+
+func TestVariantsPreview(t *testing.T) {
+	link := storage.URL{
+		Alias: "test_alias",
+		URL:   "https://example.com/",
+		URLOptions: storage.URLOptions{
+			Variants: []split.Variant{
+				{Name: "a", URL: "https://example.com/a", Weight: 1},
+				{Name: "b", URL: "https://example.com/b", Weight: 1},
+			},
+			StickyVariant: true,
+		},
+	}
+
+	urlGetterMock := mocks.NewURLGetter(t)
+	urlGetterMock.On("GetURL", mock.Anything, "test_alias").Return(link, nil)
+	urlGetterMock.On("SaveClick", mock.Anything, "test_alias", mock.AnythingOfType("string")).Return(nil).Once()
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock))
+
+	// Предпросмотр не закрепляет вариант
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/test_alias?preview=1", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Result().Cookies())
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/test_alias", nil))
+	require.Equal(t, http.StatusFound, rr.Code)
+	assert.Len(t, rr.Result().Cookies(), 1)
+}
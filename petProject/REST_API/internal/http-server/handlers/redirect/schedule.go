@@ -0,0 +1,83 @@
+// Окно активности ссылки (not_before / not_after)
+
+package redirect
+
+import (
+	"html/template"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"main.go/internal/storage"
+)
+
+// ScheduleConfig configures responses for links outside their activation window.
+type ScheduleConfig struct {
+	NotStartedURL string // Куда отправлять до not_before, пустой - страница "coming soon"
+	EndedURL      string // Куда отправлять после not_after, пустой - страница "campaign ended"
+}
+
+// WithSchedule configures fallback URLs for links that are not active yet or already ended.
+func WithSchedule(cfg ScheduleConfig) Option {
+	return func(o *options) {
+		o.schedule = cfg
+	}
+}
+
+// WithClock sets the clock used for activation windows and password cookies (для тестов).
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.now = now
+	}
+}
+
+var schedulePage = template.Must(template.New("schedule").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Title}}</title></head>
+<body>
+<h1>{{.Title}}</h1>
+{{if not .Starts.IsZero}}<p>This link will be available from {{.Starts.UTC.Format "2006-01-02 15:04 MST"}}.</p>{{end}}
+</body>
+</html>
+`))
+
+// checkSchedule reports whether the link is active at now.
+// Если нет, ответ (страница или редирект на fallback URL) уже записан в w.
+func checkSchedule(log *slog.Logger, cfg ScheduleConfig, w http.ResponseWriter, r *http.Request, link storage.URL, now time.Time) bool {
+	switch {
+	case !link.NotBefore.IsZero() && now.Before(link.NotBefore):
+		log.Info("url is not active yet", slog.String("alias", link.Alias))
+
+		if cfg.NotStartedURL != "" {
+			http.Redirect(w, r, cfg.NotStartedURL, http.StatusFound)
+			return false
+		}
+
+		w.Header().Set("Retry-After", link.NotBefore.UTC().Format(http.TimeFormat))
+		renderSchedulePage(w, http.StatusForbidden, "Coming soon", link.NotBefore)
+		return false
+
+	case !link.NotAfter.IsZero() && !now.Before(link.NotAfter):
+		log.Info("url is no longer active", slog.String("alias", link.Alias))
+
+		if cfg.EndedURL != "" {
+			http.Redirect(w, r, cfg.EndedURL, http.StatusFound)
+			return false
+		}
+
+		renderSchedulePage(w, http.StatusGone, "Campaign ended", time.Time{})
+		return false
+	}
+
+	return true
+}
+
+func renderSchedulePage(w http.ResponseWriter, status int, title string, starts time.Time) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = schedulePage.Execute(w, struct {
+		Title  string
+		Starts time.Time
+	}{title, starts})
+}
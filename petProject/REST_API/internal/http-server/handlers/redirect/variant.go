@@ -0,0 +1,51 @@
+// A/B-тест: выбор варианта и "липкая" cookie
+
+package redirect
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"main.go/internal/lib/split"
+	"main.go/internal/storage"
+)
+
+const (
+	variantCookie    = "link_variant"
+	variantCookieTTL = 30 * 24 * time.Hour
+)
+
+// pickVariant chooses the A/B variant for the request. Для ссылок со StickyVariant
+// посетитель с cookie получает тот же вариант, что и в прошлый раз; fresh - вариант выбран заново.
+func pickVariant(r *http.Request, link storage.URL) (v split.Variant, fresh bool) {
+	if link.StickyVariant {
+		if c, err := r.Cookie(variantCookie); err == nil {
+			if name, err := url.QueryUnescape(c.Value); err == nil {
+				if v, ok := split.Find(link.Variants, name); ok {
+					return v, false
+				}
+			}
+		}
+	}
+
+	return split.Pick(link.Variants, nil), true
+}
+
+// rememberVariant sets the sticky cookie for a freshly picked variant.
+// Ставится только вместе с редиректом: предпросмотр и ошибки вариант не закрепляют.
+func rememberVariant(w http.ResponseWriter, r *http.Request, link storage.URL, name string) {
+	if !link.StickyVariant {
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     variantCookie,
+		Value:    url.QueryEscape(name),
+		Path:     "/" + url.PathEscape(link.Alias),
+		MaxAge:   int(variantCookieTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
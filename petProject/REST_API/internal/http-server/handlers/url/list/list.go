@@ -0,0 +1,136 @@
+// Список ссылок: GET /url, по умолчанию только свои
+
+package list
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/render"
+	resp "main.go/internal/lib/api/response"
+	"main.go/internal/lib/auth"
+	"main.go/internal/lib/logger/sl"
+	"main.go/internal/storage"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 500
+)
+
+// Link is a listed short link. Хеш пароля и прочие внутренние поля не отдаем.
+type Link struct {
+	Alias     string    `json:"alias"`
+	URL       string    `json:"url"`
+	OwnerID   int64     `json:"owner_id,omitempty"`
+	Protected bool      `json:"protected,omitempty"`
+	Private   bool      `json:"private,omitempty"`
+	MaxClicks int       `json:"max_clicks,omitempty"`
+	Clicks    int       `json:"clicks,omitempty"`
+	NotBefore time.Time `json:"not_before,omitzero"`
+	NotAfter  time.Time `json:"not_after,omitzero"`
+	CreatedAt time.Time `json:"created_at,omitzero"`
+}
+
+type Response struct {
+	resp.Response
+	Links []Link `json:"links"`
+}
+
+// URLLister is an interface for listing links.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLLister
+type URLLister interface {
+	ListURLs(ctx context.Context, filter storage.ListFilter) ([]storage.URL, error)
+}
+
+// New lists links of the current user. Администратор может запросить все ссылки: ?owner=all.
+// Постранично: ?limit=50&offset=0.
+func New(log *slog.Logger, urlLister URLLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.list.New"
+
+		log := sl.FromContext(r.Context(), log).With(
+			slog.String("op", op),
+		)
+
+		p, _ := auth.FromContext(r.Context())
+
+		filter := storage.ListFilter{OwnerID: p.UserID, Limit: defaultLimit}
+		if p.UserID == 0 {
+			// Ключ без пользователя видит только ссылки, созданные им самим
+			if p.Subject == "" {
+				log.Info("listing links without a subject is not allowed")
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, resp.Error("forbidden"))
+				return
+			}
+			filter.CreatedBy = p.Subject
+		}
+
+		q := r.URL.Query()
+		if q.Get("owner") == "all" {
+			if !p.Can(auth.PermLinksReadAll) {
+				log.Info("listing all links is not allowed", slog.String("subject", p.Subject))
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, resp.Error("forbidden"))
+				return
+			}
+			filter.AllOwners = true
+		}
+
+		if v := q.Get("limit"); v != "" {
+			limit, err := strconv.Atoi(v)
+			if err != nil || limit < 1 || limit > maxLimit {
+				render.JSON(w, r, resp.Error("limit must be between 1 and "+strconv.Itoa(maxLimit)))
+				return
+			}
+			filter.Limit = limit
+		}
+
+		if v := q.Get("offset"); v != "" {
+			offset, err := strconv.Atoi(v)
+			if err != nil || offset < 0 {
+				render.JSON(w, r, resp.Error("invalid offset"))
+				return
+			}
+			filter.Offset = offset
+		}
+
+		urls, err := urlLister.ListURLs(r.Context(), filter)
+		if err != nil {
+			log.Error("failed to list urls", sl.Err(err))
+			render.JSON(w, r, resp.Error("internal error"))
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Links:    Links(urls),
+		})
+	}
+}
+
+// Links converts stored links to their public representation.
+func Links(urls []storage.URL) []Link {
+	links := make([]Link, 0, len(urls)) // в JSON пустой список, а не null
+	for _, u := range urls {
+		links = append(links, Link{
+			Alias:     u.Alias,
+			URL:       u.URL,
+			OwnerID:   u.OwnerID,
+			Protected: u.PasswordHash != "",
+			Private:   u.Private,
+			MaxClicks: u.MaxClicks,
+			Clicks:    u.Clicks,
+			NotBefore: u.NotBefore,
+			NotAfter:  u.NotAfter,
+			CreatedAt: u.CreatedAt,
+		})
+	}
+
+	return links
+}
@@ -0,0 +1,98 @@
+package list_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"main.go/internal/http-server/handlers/url/list"
+	"main.go/internal/http-server/handlers/url/list/mocks"
+	"main.go/internal/lib/auth"
+	"main.go/internal/lib/logger/handlers/slogdiscard"
+	"main.go/internal/storage"
+)
+
+func TestListHandler(t *testing.T) {
+	user := auth.Principal{Subject: "key:1", UserID: 5, Scopes: []string{auth.ScopeRead}}
+	admin := auth.Principal{Subject: "key:2", UserID: 1, Role: auth.RoleAdmin, Scopes: []string{auth.ScopeAdmin}}
+
+	link := storage.URL{
+		Alias:      "abc",
+		URL:        "https://example.com",
+		URLOptions: storage.URLOptions{PasswordHash: "hash", OwnerID: 5},
+		CreatedAt:  time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	cases := []struct {
+		name      string
+		query     string
+		principal auth.Principal
+		filter    *storage.ListFilter
+		status    int
+		want      string
+	}{
+		{
+			name:      "My links",
+			principal: user,
+			filter:    &storage.ListFilter{OwnerID: 5, Limit: 50},
+			status:    http.StatusOK,
+			want:      `{"status": "Ok", "links": [{"alias": "abc", "url": "https://example.com", "owner_id": 5, "protected": true, "created_at": "2025-03-01T00:00:00Z"}]}`,
+		},
+		{
+			name:      "All links by admin",
+			query:     "?owner=all&limit=10&offset=20",
+			principal: admin,
+			filter:    &storage.ListFilter{OwnerID: 1, AllOwners: true, Limit: 10, Offset: 20},
+			status:    http.StatusOK,
+		},
+		{
+			name:      "Ownerless key",
+			principal: auth.Principal{Subject: "key:3", Scopes: []string{auth.ScopeRead}},
+			filter:    &storage.ListFilter{CreatedBy: "key:3", Limit: 50},
+			status:    http.StatusOK,
+		},
+		{
+			name:      "Ownerless principal without subject",
+			principal: auth.Principal{Scopes: []string{auth.ScopeRead}},
+			status:    http.StatusForbidden,
+			want:      `{"status": "Error", "error": "forbidden"}`,
+		},
+		{
+			name:      "All links by user",
+			query:     "?owner=all",
+			principal: user,
+			status:    http.StatusForbidden,
+			want:      `{"status": "Error", "error": "forbidden"}`,
+		},
+		{
+			name:      "Bad limit",
+			query:     "?limit=1000",
+			principal: user,
+			status:    http.StatusOK,
+			want:      `{"status": "Error", "error": "limit must be between 1 and 500"}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlListerMock := mocks.NewURLLister(t)
+			if tc.filter != nil {
+				urlListerMock.On("ListURLs", mock.Anything, *tc.filter).Return([]storage.URL{link}, nil).Once()
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/url"+tc.query, nil)
+			req = req.WithContext(auth.WithPrincipal(req.Context(), tc.principal))
+
+			rr := httptest.NewRecorder()
+			list.New(slogdiscard.NewDiscardLogger(), urlListerMock).ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.status, rr.Code)
+			if tc.want != "" {
+				assert.JSONEq(t, tc.want, rr.Body.String())
+			}
+		})
+	}
+}
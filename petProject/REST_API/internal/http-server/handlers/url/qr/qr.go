@@ -0,0 +1,228 @@
+// QR-код короткой ссылки: /url/{alias}/qr
+
+package qr
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"main.go/internal/lib/aliassig"
+	resp "main.go/internal/lib/api/response"
+	"main.go/internal/lib/logger/sl"
+	"main.go/internal/lib/qrcode"
+	"main.go/internal/storage"
+)
+
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+)
+
+// URLGetter is an interface for checking that the alias exists.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLGetter
+type URLGetter interface {
+	GetURL(ctx context.Context, alias string) (storage.URL, error)
+}
+
+// Option configures the QR handler.
+type Option func(*options)
+
+type options struct {
+	signer *aliassig.Signer
+}
+
+// WithSigner encodes private links with their signed alias, иначе по QR-коду они не откроются.
+func WithSigner(signer *aliassig.Signer) Option {
+	return func(o *options) {
+		o.signer = signer
+	}
+}
+
+// New returns the QR code of the public short URL for the alias.
+// baseURL - публичный адрес сервиса (например "https://sho.rt"), пустой - берем из запроса.
+//
+// Query: format=png|svg, size (px), level=L|M|Q|H, margin (модули), fg и bg в hex.
+func New(log *slog.Logger, urlGetter URLGetter, baseURL string, opts ...Option) http.HandlerFunc {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.qr.New"
+
+		log := sl.FromContext(r.Context(), log).With(
+			slog.String("op", op),
+		)
+
+		alias := chi.URLParam(r, "alias")
+
+		format, opts, err := parseQuery(r.URL.Query())
+		if err != nil {
+			log.Info("invalid qr params", sl.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		link, err := urlGetter.GetURL(r.Context(), alias)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", "alias", alias)
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error("not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get url", sl.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("internal error"))
+			return
+		}
+
+		if link.Private && o.signer != nil {
+			alias = o.signer.Sign(alias)
+		}
+
+		shortURL := publicBase(baseURL, r) + "/" + url.PathEscape(alias)
+
+		// Картинка зависит только от короткого URL и параметров, поэтому ETag считаем до кодирования
+		etag := etagFor(shortURL, format, opts)
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "private, max-age=86400")
+
+		if match := r.Header.Get("If-None-Match"); match != "" && etagMatches(match, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		var (
+			body        []byte
+			contentType string
+		)
+		switch format {
+		case FormatSVG:
+			body, err = qrcode.SVG(shortURL, opts)
+			contentType = "image/svg+xml"
+		default:
+			body, err = qrcode.PNG(shortURL, opts)
+			contentType = "image/png"
+		}
+		if err != nil {
+			log.Error("failed to encode qr code", sl.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("failed to encode qr code"))
+			return
+		}
+
+		log.Info("qr code generated", slog.String("alias", alias), slog.String("format", format))
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		_, _ = w.Write(body)
+	}
+}
+
+func parseQuery(q url.Values) (string, qrcode.Options, error) {
+	opts := qrcode.DefaultOptions()
+
+	format := strings.ToLower(q.Get("format"))
+	switch format {
+	case "":
+		format = FormatPNG
+	case FormatPNG, FormatSVG:
+	default:
+		return "", opts, fmt.Errorf("format must be %s or %s", FormatPNG, FormatSVG)
+	}
+
+	if v := q.Get("size"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil {
+			return "", opts, errors.New("invalid size")
+		}
+		opts.Size = size
+	}
+
+	if v := q.Get("margin"); v != "" {
+		margin, err := strconv.Atoi(v)
+		if err != nil {
+			return "", opts, errors.New("invalid margin")
+		}
+		opts.Margin = margin
+	}
+
+	if v := q.Get("level"); v != "" {
+		level, err := qrcode.ParseLevel(v)
+		if err != nil {
+			return "", opts, err
+		}
+		opts.Level = level
+	}
+
+	if v := q.Get("fg"); v != "" {
+		c, err := qrcode.ParseColor(v)
+		if err != nil {
+			return "", opts, fmt.Errorf("fg: %w", err)
+		}
+		opts.Foreground = c
+	}
+
+	if v := q.Get("bg"); v != "" {
+		c, err := qrcode.ParseColor(v)
+		if err != nil {
+			return "", opts, fmt.Errorf("bg: %w", err)
+		}
+		opts.Background = c
+	}
+
+	if err := opts.Validate(); err != nil {
+		return "", opts, err
+	}
+
+	return format, opts, nil
+}
+
+// publicBase returns the configured base URL or one built from the request.
+func publicBase(baseURL string, r *http.Request) string {
+	if baseURL != "" {
+		return baseURL
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	return scheme + "://" + r.Host
+}
+
+func etagFor(shortURL, format string, o qrcode.Options) string {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%d|%d|%d|%v|%v",
+		shortURL, format, o.Size, o.Level, o.Margin, o.Foreground, o.Background)))
+
+	return `"` + hex.EncodeToString(h[:16]) + `"`
+}
+
+// etagMatches checks If-None-Match, which may list several tags or "*".
+func etagMatches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			return true
+		}
+	}
+
+	return false
+}
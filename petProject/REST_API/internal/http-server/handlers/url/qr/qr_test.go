@@ -0,0 +1,138 @@
+package qr_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"main.go/internal/http-server/handlers/url/qr"
+	"main.go/internal/http-server/handlers/url/qr/mocks"
+	"main.go/internal/lib/aliassig"
+	resp "main.go/internal/lib/api/response"
+	"main.go/internal/lib/logger/handlers/slogdiscard"
+	"main.go/internal/storage"
+)
+
+func newRouter(urlGetter qr.URLGetter) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/url/{alias}/qr", qr.New(slogdiscard.NewDiscardLogger(), urlGetter, "https://sho.rt/"))
+
+	return r
+}
+
+func TestQRHandler(t *testing.T) {
+	urlGetterMock := mocks.NewURLGetter(t)
+	urlGetterMock.On("GetURL", mock.Anything, "test_alias").Return(storage.URL{Alias: "test_alias", URL: "https://example.com"}, nil)
+
+	r := newRouter(urlGetterMock)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/url/test_alias/qr?size=128", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "image/png", rr.Header().Get("Content-Type"))
+
+	img, err := png.Decode(bytes.NewReader(rr.Body.Bytes()))
+	require.NoError(t, err)
+	assert.LessOrEqual(t, img.Bounds().Dx(), 128)
+
+	etag := rr.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	// Повторный запрос с тем же ETag - 304 без тела
+	req := httptest.NewRequest(http.MethodGet, "/url/test_alias/qr?size=128", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Empty(t, rr.Body.Bytes())
+
+	// Другие параметры - другой ETag
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/url/test_alias/qr?format=svg&fg=%23ff0000&level=H", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "image/svg+xml", rr.Header().Get("Content-Type"))
+	assert.NotEqual(t, etag, rr.Header().Get("ETag"))
+	assert.Contains(t, rr.Body.String(), `fill="#ff0000"`)
+}
+
+func TestQRHandler_Errors(t *testing.T) {
+	cases := []struct {
+		name      string
+		query     string
+		mockError error
+		status    int
+		respError string
+	}{
+		{
+			name:      "Unknown format",
+			query:     "format=gif",
+			status:    http.StatusBadRequest,
+			respError: "format must be png or svg",
+		},
+		{
+			name:      "Size too big",
+			query:     "size=100000",
+			status:    http.StatusBadRequest,
+			respError: "size must be between 64 and 2048",
+		},
+		{
+			name:      "Invalid color",
+			query:     "bg=white",
+			status:    http.StatusBadRequest,
+			respError: "bg: color must be a hex value like ff0000",
+		},
+		{
+			name:      "Unknown alias",
+			mockError: storage.ErrURLNotFound,
+			status:    http.StatusNotFound,
+			respError: "not found",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlGetterMock := mocks.NewURLGetter(t)
+			if tc.mockError != nil {
+				urlGetterMock.On("GetURL", mock.Anything, "test_alias").Return(storage.URL{}, tc.mockError).Once()
+			}
+
+			rr := httptest.NewRecorder()
+			newRouter(urlGetterMock).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/url/test_alias/qr?"+tc.query, nil))
+
+			require.Equal(t, tc.status, rr.Code)
+
+			var body resp.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			assert.Equal(t, tc.respError, body.Error)
+		})
+	}
+}
+
+func TestQRHandler_Private(t *testing.T) {
+	urlGetterMock := mocks.NewURLGetter(t)
+	urlGetterMock.On("GetURL", mock.Anything, "secret").Return(storage.URL{Alias: "secret", URL: "https://example.com", URLOptions: storage.URLOptions{Private: true}}, nil)
+
+	etag := func(opts ...qr.Option) string {
+		r := chi.NewRouter()
+		r.Get("/url/{alias}/qr", qr.New(slogdiscard.NewDiscardLogger(), urlGetterMock, "https://sho.rt", opts...))
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/url/secret/qr", nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		return rr.Header().Get("ETag")
+	}
+
+	// С подписью кодируется другой короткий URL
+	assert.NotEqual(t, etag(), etag(qr.WithSigner(aliassig.New([]byte("k"), 0))))
+}
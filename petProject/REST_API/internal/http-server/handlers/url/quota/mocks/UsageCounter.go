@@ -0,0 +1,60 @@
+// Code generated by mockery v2.53.7. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	storage "main.go/internal/storage"
+
+	time "time"
+)
+
+// UsageCounter is an autogenerated mock type for the UsageCounter type
+type UsageCounter struct {
+	mock.Mock
+}
+
+// GetUsage provides a mock function with given fields: ctx, userID, createdBy, since, now
+func (_m *UsageCounter) GetUsage(ctx context.Context, userID int64, createdBy string, since time.Time, now time.Time) (storage.Usage, error) {
+	ret := _m.Called(ctx, userID, createdBy, since, now)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUsage")
+	}
+
+	var r0 storage.Usage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, time.Time, time.Time) (storage.Usage, error)); ok {
+		return rf(ctx, userID, createdBy, since, now)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, time.Time, time.Time) storage.Usage); ok {
+		r0 = rf(ctx, userID, createdBy, since, now)
+	} else {
+		r0 = ret.Get(0).(storage.Usage)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string, time.Time, time.Time) error); ok {
+		r1 = rf(ctx, userID, createdBy, since, now)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewUsageCounter creates a new instance of UsageCounter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewUsageCounter(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *UsageCounter {
+	mock := &UsageCounter{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
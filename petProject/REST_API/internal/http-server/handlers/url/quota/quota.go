@@ -0,0 +1,61 @@
+// Квота текущего пользователя или ключа: GET /url/quota
+
+package quota
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+	resp "main.go/internal/lib/api/response"
+	"main.go/internal/lib/auth"
+	"main.go/internal/lib/logger/sl"
+	"main.go/internal/lib/quota"
+	"main.go/internal/storage"
+)
+
+type Response struct {
+	resp.Response
+	Limits   quota.Limits  `json:"limits"` // 0 - без ограничения
+	Usage    storage.Usage `json:"usage"`
+	ResetsAt time.Time     `json:"resets_at"` // когда обнулится счетчик за месяц
+}
+
+// UsageCounter is an interface for counting links of a user or API key.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=UsageCounter
+type UsageCounter interface {
+	GetUsage(ctx context.Context, userID int64, createdBy string, since, now time.Time) (storage.Usage, error)
+}
+
+// New returns the quota limits and current usage of the caller.
+func New(log *slog.Logger, quotas *quota.Quotas, counter UsageCounter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.quota.New"
+
+		log := sl.FromContext(r.Context(), log).With(
+			slog.String("op", op),
+		)
+
+		p, _ := auth.FromContext(r.Context())
+
+		now := time.Now()
+		since := quota.MonthStart(now)
+
+		usage, err := counter.GetUsage(r.Context(), p.UserID, p.Subject, since, now)
+		if err != nil {
+			log.Error("failed to get usage", sl.Err(err))
+			render.JSON(w, r, resp.Error("internal error"))
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Limits:   quotas.For(p),
+			Usage:    usage,
+			ResetsAt: since.AddDate(0, 1, 0),
+		})
+	}
+}
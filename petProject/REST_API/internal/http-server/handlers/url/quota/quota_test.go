@@ -0,0 +1,43 @@
+package quota_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"main.go/internal/http-server/handlers/url/quota"
+	"main.go/internal/http-server/handlers/url/quota/mocks"
+	"main.go/internal/lib/auth"
+	"main.go/internal/lib/logger/handlers/slogdiscard"
+	libquota "main.go/internal/lib/quota"
+	"main.go/internal/storage"
+)
+
+func TestQuotaHandler(t *testing.T) {
+	quotas := libquota.New(libquota.Config{
+		Default: libquota.Limits{MaxActive: 100, MaxPerMonth: 1000},
+		Users:   map[int64]libquota.Limits{5: {MaxActive: 10}},
+	})
+
+	counterMock := mocks.NewUsageCounter(t)
+	counterMock.On("GetUsage", mock.Anything, int64(5), "key:1", mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time")).
+		Return(storage.Usage{Active: 3, ThisMonth: 7}, nil).Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/url/quota", nil)
+	req = req.WithContext(auth.WithPrincipal(req.Context(), auth.Principal{Subject: "key:1", UserID: 5}))
+
+	rr := httptest.NewRecorder()
+	quota.New(slogdiscard.NewDiscardLogger(), quotas, counterMock).ServeHTTP(rr, req)
+
+	var resp quota.Response
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, libquota.Limits{MaxActive: 10}, resp.Limits)
+	assert.Equal(t, storage.Usage{Active: 3, ThisMonth: 7}, resp.Usage)
+	assert.Equal(t, 1, resp.ResetsAt.Day())
+	assert.True(t, resp.ResetsAt.After(time.Now()))
+}
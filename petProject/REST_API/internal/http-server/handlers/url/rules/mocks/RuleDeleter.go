@@ -0,0 +1,46 @@
+// Code generated by mockery v2.53.7. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// RuleDeleter is an autogenerated mock type for the RuleDeleter type
+type RuleDeleter struct {
+	mock.Mock
+}
+
+// DeleteRule provides a mock function with given fields: ctx, alias, id
+func (_m *RuleDeleter) DeleteRule(ctx context.Context, alias string, id int64) error {
+	ret := _m.Called(ctx, alias, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteRule")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) error); ok {
+		r0 = rf(ctx, alias, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewRuleDeleter creates a new instance of RuleDeleter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewRuleDeleter(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *RuleDeleter {
+	mock := &RuleDeleter{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
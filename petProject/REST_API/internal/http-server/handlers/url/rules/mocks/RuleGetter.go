@@ -0,0 +1,59 @@
+// Code generated by mockery v2.53.7. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+	routing "main.go/internal/lib/routing"
+)
+
+// RuleGetter is an autogenerated mock type for the RuleGetter type
+type RuleGetter struct {
+	mock.Mock
+}
+
+// GetRules provides a mock function with given fields: ctx, alias
+func (_m *RuleGetter) GetRules(ctx context.Context, alias string) ([]routing.Rule, error) {
+	ret := _m.Called(ctx, alias)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRules")
+	}
+
+	var r0 []routing.Rule
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]routing.Rule, error)); ok {
+		return rf(ctx, alias)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []routing.Rule); ok {
+		r0 = rf(ctx, alias)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]routing.Rule)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, alias)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewRuleGetter creates a new instance of RuleGetter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewRuleGetter(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *RuleGetter {
+	mock := &RuleGetter{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
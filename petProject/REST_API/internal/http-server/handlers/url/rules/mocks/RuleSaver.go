@@ -0,0 +1,57 @@
+// Code generated by mockery v2.53.7. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+	routing "main.go/internal/lib/routing"
+)
+
+// RuleSaver is an autogenerated mock type for the RuleSaver type
+type RuleSaver struct {
+	mock.Mock
+}
+
+// AddRule provides a mock function with given fields: ctx, alias, rule
+func (_m *RuleSaver) AddRule(ctx context.Context, alias string, rule routing.Rule) (int64, error) {
+	ret := _m.Called(ctx, alias, rule)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddRule")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, routing.Rule) (int64, error)); ok {
+		return rf(ctx, alias, rule)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, routing.Rule) int64); ok {
+		r0 = rf(ctx, alias, rule)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, routing.Rule) error); ok {
+		r1 = rf(ctx, alias, rule)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewRuleSaver creates a new instance of RuleSaver. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewRuleSaver(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *RuleSaver {
+	mock := &RuleSaver{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
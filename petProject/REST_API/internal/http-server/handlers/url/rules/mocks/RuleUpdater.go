@@ -0,0 +1,47 @@
+// Code generated by mockery v2.53.7. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+	routing "main.go/internal/lib/routing"
+)
+
+// RuleUpdater is an autogenerated mock type for the RuleUpdater type
+type RuleUpdater struct {
+	mock.Mock
+}
+
+// UpdateRule provides a mock function with given fields: ctx, alias, rule, position
+func (_m *RuleUpdater) UpdateRule(ctx context.Context, alias string, rule routing.Rule, position int) error {
+	ret := _m.Called(ctx, alias, rule, position)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateRule")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, routing.Rule, int) error); ok {
+		r0 = rf(ctx, alias, rule, position)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewRuleUpdater creates a new instance of RuleUpdater. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewRuleUpdater(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *RuleUpdater {
+	mock := &RuleUpdater{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
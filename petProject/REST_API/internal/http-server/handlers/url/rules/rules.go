@@ -0,0 +1,327 @@
+// CRUD правил условного редиректа: /url/{alias}/rules
+
+package rules
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	resp "main.go/internal/lib/api/response"
+	"main.go/internal/lib/logger/sl"
+	"main.go/internal/lib/routing"
+	"main.go/internal/lib/selflink"
+	"main.go/internal/storage"
+)
+
+type ListResponse struct {
+	resp.Response
+	Rules []routing.Rule `json:"rules"`
+}
+
+// UpdateRequest is a rule with its new place in the list (с 1, 0 - оставить на месте).
+type UpdateRequest struct {
+	routing.Rule
+	Position int `json:"position,omitempty" validate:"gte=0"`
+}
+
+type CreateResponse struct {
+	resp.Response
+	ID int64 `json:"id,omitempty"`
+}
+
+// RuleGetter is an interface for listing link rules.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=RuleGetter
+type RuleGetter interface {
+	GetRules(ctx context.Context, alias string) ([]routing.Rule, error)
+}
+
+// RuleSaver is an interface for appending a rule to a link.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=RuleSaver
+type RuleSaver interface {
+	AddRule(ctx context.Context, alias string, rule routing.Rule) (int64, error)
+}
+
+// RuleUpdater is an interface for changing a rule and its place in the list.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=RuleUpdater
+type RuleUpdater interface {
+	UpdateRule(ctx context.Context, alias string, rule routing.Rule, position int) error
+}
+
+// RuleDeleter is an interface for removing a link rule.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=RuleDeleter
+type RuleDeleter interface {
+	DeleteRule(ctx context.Context, alias string, id int64) error
+}
+
+// URLGetter is an interface for following self links in rule targets.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLGetter
+type URLGetter interface {
+	GetURL(ctx context.Context, alias string) (storage.URL, error)
+}
+
+// DestinationChecker decides whether a URL may be used as a rule target.
+type DestinationChecker interface {
+	Check(rawURL string) error
+}
+
+// SelfLinks configures rule targets that point back to our own short links.
+type SelfLinks struct {
+	Resolver *selflink.Resolver
+	Getter   URLGetter
+	Reject   bool // true - не принимать свои ссылки вовсе, false - сохранять конечный адрес цепочки
+}
+
+// Option configures the create and update handlers.
+type Option func(*options)
+
+type options struct {
+	checker   DestinationChecker
+	selfLinks SelfLinks
+}
+
+// WithDestinationChecker rejects rule targets that the checker does not allow.
+func WithDestinationChecker(checker DestinationChecker) Option {
+	return func(o *options) {
+		o.checker = checker
+	}
+}
+
+// WithSelfLinks replaces rule targets on our own hosts with their final destination (or rejects them).
+func WithSelfLinks(cfg SelfLinks) Option {
+	return func(o *options) {
+		o.selfLinks = cfg
+	}
+}
+
+// NewList returns the rules of a link in evaluation order.
+func NewList(log *slog.Logger, ruleGetter RuleGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.rules.NewList"
+
+		log := sl.FromContext(r.Context(), log).With(
+			slog.String("op", op),
+		)
+
+		alias := chi.URLParam(r, "alias")
+
+		rules, err := ruleGetter.GetRules(r.Context(), alias)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", "alias", alias)
+			render.JSON(w, r, resp.Error("not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get rules", sl.Err(err))
+			render.JSON(w, r, resp.Error("internal error"))
+			return
+		}
+
+		if rules == nil {
+			rules = []routing.Rule{} // в JSON пустой список, а не null
+		}
+
+		render.JSON(w, r, ListResponse{
+			Response: resp.OK(),
+			Rules:    rules,
+		})
+	}
+}
+
+// NewCreate appends a rule to the end of the link's rule list.
+// Цель правила проходит те же проверки, что и адрес ссылки при сохранении.
+func NewCreate(log *slog.Logger, ruleSaver RuleSaver, opts ...Option) http.HandlerFunc {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.rules.NewCreate"
+
+		log := sl.FromContext(r.Context(), log).With(
+			slog.String("op", op),
+		)
+
+		alias := chi.URLParam(r, "alias")
+
+		var rule routing.Rule
+		if !o.readRule(log, w, r, alias, &rule, &rule, "failed to add rule") {
+			return
+		}
+
+		id, err := ruleSaver.AddRule(r.Context(), alias, rule)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", "alias", alias)
+			render.JSON(w, r, resp.Error("not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to add rule", sl.Err(err))
+			render.JSON(w, r, resp.Error("failed to add rule"))
+			return
+		}
+
+		log.Info("rule added", slog.String("alias", alias), slog.Int64("id", id))
+
+		render.JSON(w, r, CreateResponse{
+			Response: resp.OK(),
+			ID:       id,
+		})
+	}
+}
+
+// NewUpdate replaces a rule by its id. Необязательный position (с 1) переносит правило
+// на это место в порядке проверки, без него порядок не меняется.
+func NewUpdate(log *slog.Logger, ruleUpdater RuleUpdater, opts ...Option) http.HandlerFunc {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.rules.NewUpdate"
+
+		log := sl.FromContext(r.Context(), log).With(
+			slog.String("op", op),
+		)
+
+		alias := chi.URLParam(r, "alias")
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			log.Info("invalid rule id", sl.Err(err))
+			render.JSON(w, r, resp.Error("invalid rule id"))
+			return
+		}
+
+		var req UpdateRequest
+		if !o.readRule(log, w, r, alias, &req, &req.Rule, "failed to update rule") {
+			return
+		}
+		req.Rule.ID = id
+
+		err = ruleUpdater.UpdateRule(r.Context(), alias, req.Rule, req.Position)
+		if errors.Is(err, storage.ErrRuleNotFound) {
+			log.Info("rule not found", "alias", alias, "id", id)
+			render.JSON(w, r, resp.Error("not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to update rule", sl.Err(err))
+			render.JSON(w, r, resp.Error("failed to update rule"))
+			return
+		}
+
+		log.Info("rule updated", slog.String("alias", alias), slog.Int64("id", id), slog.Int("position", req.Position))
+
+		render.JSON(w, r, resp.OK())
+	}
+}
+
+// NewDelete removes a rule by its id.
+func NewDelete(log *slog.Logger, ruleDeleter RuleDeleter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.rules.NewDelete"
+
+		log := sl.FromContext(r.Context(), log).With(
+			slog.String("op", op),
+		)
+
+		alias := chi.URLParam(r, "alias")
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			log.Info("invalid rule id", sl.Err(err))
+			render.JSON(w, r, resp.Error("invalid rule id"))
+			return
+		}
+
+		err = ruleDeleter.DeleteRule(r.Context(), alias, id)
+		if errors.Is(err, storage.ErrRuleNotFound) {
+			log.Info("rule not found", "alias", alias, "id", id)
+			render.JSON(w, r, resp.Error("not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to delete rule", sl.Err(err))
+			render.JSON(w, r, resp.Error("internal error"))
+			return
+		}
+
+		log.Info("rule deleted", slog.String("alias", alias), slog.Int64("id", id))
+
+		render.JSON(w, r, resp.OK())
+	}
+}
+
+// readRule decodes and validates the request body into req, rule - правило внутри req.
+// Цель правила проходит те же проверки, что и адрес ссылки при сохранении.
+// Если false, ответ уже записан.
+func (o options) readRule(log *slog.Logger, w http.ResponseWriter, r *http.Request, alias string, req any, rule *routing.Rule, failMsg string) bool {
+	err := render.DecodeJSON(r.Body, req)
+	if errors.Is(err, io.EOF) {
+		log.Error("request body is empty")
+		render.JSON(w, r, resp.Error("empty request"))
+		return false
+	}
+	if err != nil {
+		log.Error("failed to decode request body", sl.Err(err))
+		render.JSON(w, r, resp.Error("failed to decode request"))
+		return false
+	}
+
+	if err := validator.New().Struct(req); err != nil {
+		var validateErrs validator.ValidationErrors
+		if errors.As(err, &validateErrs) {
+			log.Info("invalid request", sl.Err(err))
+			render.JSON(w, r, resp.ValidationError(validateErrs))
+			return false
+		}
+
+		log.Error("failed to validate request", sl.Err(err))
+		render.JSON(w, r, resp.Error("invalid request"))
+		return false
+	}
+	if err := rule.Validate(); err != nil {
+		log.Info("invalid rule", sl.Err(err))
+		render.JSON(w, r, resp.Error(err.Error()))
+		return false
+	}
+
+	// Цель на наш же сервис заменяем конечным адресом, иначе получаются цепочки и циклы
+	if o.selfLinks.Resolver != nil {
+		rule.Target, err = o.selfLinks.Resolver.ResolveStatic(r.Context(), o.selfLinks.Getter, alias, rule.Target, o.selfLinks.Reject)
+		if err != nil {
+			if !selflink.IsRejection(err) {
+				log.Error("failed to resolve self link", sl.Err(err))
+				render.JSON(w, r, resp.Error(failMsg))
+				return false
+			}
+			log.Info("self link rejected", sl.Err(err))
+			render.JSON(w, r, resp.Error(err.Error()))
+			return false
+		}
+	}
+
+	if o.checker != nil {
+		if err := o.checker.Check(rule.Target); err != nil {
+			log.Info("target rejected", slog.String("url", rule.Target), sl.Err(err))
+			render.JSON(w, r, resp.Error(err.Error()))
+			return false
+		}
+	}
+
+	return true
+}
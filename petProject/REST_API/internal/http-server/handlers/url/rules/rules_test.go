@@ -0,0 +1,272 @@
+package rules_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"main.go/internal/http-server/handlers/url/rules"
+	"main.go/internal/http-server/handlers/url/rules/mocks"
+	"main.go/internal/lib/logger/handlers/slogdiscard"
+	"main.go/internal/lib/routing"
+	"main.go/internal/lib/selflink"
+	"main.go/internal/lib/urlpolicy"
+	"main.go/internal/storage"
+)
+
+func TestCreateHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		input     string
+		rule      routing.Rule
+		respError string
+		mockError error
+	}{
+		{
+			name:  "Success",
+			input: `{"type": "platform", "value": "ios", "target": "https://apps.apple.com/app/id1"}`,
+			rule:  routing.Rule{Type: routing.TypePlatform, Value: "ios", Target: "https://apps.apple.com/app/id1"},
+		},
+		{
+			name:      "Invalid target",
+			input:     `{"type": "platform", "value": "ios", "target": "not a url"}`,
+			respError: "failed Target is not a valid URL",
+		},
+		{
+			name:      "Unknown platform",
+			input:     `{"type": "platform", "value": "symbian", "target": "https://example.com"}`,
+			respError: `unknown platform: "symbian"`,
+		},
+		{
+			name:      "Unknown alias",
+			input:     `{"type": "language", "value": "de", "target": "https://example.com/de"}`,
+			rule:      routing.Rule{Type: routing.TypeLanguage, Value: "de", Target: "https://example.com/de"},
+			respError: "not found",
+			mockError: storage.ErrURLNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ruleSaverMock := mocks.NewRuleSaver(t)
+
+			if tc.respError == "" || tc.mockError != nil {
+				ruleSaverMock.On("AddRule", mock.Anything, "test_alias", tc.rule).
+					Return(int64(1), tc.mockError).
+					Once()
+			}
+
+			r := chi.NewRouter()
+			r.Post("/url/{alias}/rules", rules.NewCreate(slogdiscard.NewDiscardLogger(), ruleSaverMock))
+
+			req := httptest.NewRequest(http.MethodPost, "/url/test_alias/rules", bytes.NewReader([]byte(tc.input)))
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp rules.CreateResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
+
+func TestCreateHandlerTarget(t *testing.T) {
+	policy, err := urlpolicy.New(urlpolicy.Config{BlockPrivate: true})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name       string
+		target     string
+		reject     bool
+		links      map[string]storage.URL
+		wantTarget string // цель, с которой правило сохраняется
+		respError  string
+	}{
+		{
+			name:       "Allowed",
+			target:     "https://example.com/de",
+			wantTarget: "https://example.com/de",
+		},
+		{
+			name:      "Denied scheme",
+			target:    "ftp://example.com/file",
+			respError: "url scheme is not allowed",
+		},
+		{
+			name:      "Private IP",
+			target:    "http://10.0.0.1/admin",
+			respError: "private and loopback addresses are not allowed",
+		},
+		{
+			name:       "Self link is resolved",
+			target:     "http://sho.rt/next",
+			links:      map[string]storage.URL{"next": {Alias: "next", URL: "https://example.com/final"}},
+			wantTarget: "https://example.com/final",
+		},
+		{
+			name:      "Self link to a private destination",
+			target:    "http://sho.rt/next",
+			links:     map[string]storage.URL{"next": {Alias: "next", URL: "http://127.0.0.1/"}},
+			respError: "private and loopback addresses are not allowed",
+		},
+		{
+			name:      "Self link to the same alias",
+			target:    "http://sho.rt/test_alias",
+			respError: "redirect loop detected",
+		},
+		{
+			name:      "Self link rejected",
+			target:    "http://sho.rt/next",
+			reject:    true,
+			respError: "destination points to this url shortener",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ruleSaverMock := mocks.NewRuleSaver(t)
+			if tc.wantTarget != "" {
+				ruleSaverMock.On("AddRule", mock.Anything, "test_alias", routing.Rule{Type: routing.TypeLanguage, Value: "de", Target: tc.wantTarget}).
+					Return(int64(1), nil).
+					Once()
+			}
+
+			urlGetterMock := mocks.NewURLGetter(t)
+			for alias, link := range tc.links {
+				urlGetterMock.On("GetURL", mock.Anything, alias).Return(link, nil).Once()
+			}
+
+			r := chi.NewRouter()
+			r.Post("/url/{alias}/rules", rules.NewCreate(slogdiscard.NewDiscardLogger(), ruleSaverMock,
+				rules.WithDestinationChecker(policy),
+				rules.WithSelfLinks(rules.SelfLinks{
+					Resolver: selflink.New([]string{"sho.rt"}, 0),
+					Getter:   urlGetterMock,
+					Reject:   tc.reject,
+				}),
+			))
+
+			input := `{"type": "language", "value": "de", "target": "` + tc.target + `"}`
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/url/test_alias/rules", bytes.NewReader([]byte(input))))
+
+			var resp rules.CreateResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
+
+func TestUpdateHandler(t *testing.T) {
+	policy, err := urlpolicy.New(urlpolicy.Config{BlockPrivate: true})
+	require.NoError(t, err)
+
+	de := routing.Rule{ID: 7, Type: routing.TypeLanguage, Value: "de", Target: "https://example.com/de"}
+
+	cases := []struct {
+		name      string
+		path      string
+		input     string
+		position  int
+		mock      bool
+		mockError error
+		want      string
+	}{
+		{
+			name:  "Update in place",
+			path:  "/url/test_alias/rules/7",
+			input: `{"type": "language", "value": "de", "target": "https://example.com/de"}`,
+			mock:  true,
+			want:  `{"status": "Ok"}`,
+		},
+		{
+			name:     "Move to the top",
+			path:     "/url/test_alias/rules/7",
+			input:    `{"id": 99, "type": "language", "value": "de", "target": "https://example.com/de", "position": 1}`,
+			position: 1,
+			mock:     true,
+			want:     `{"status": "Ok"}`,
+		},
+		{
+			name:      "Unknown rule",
+			path:      "/url/test_alias/rules/7",
+			input:     `{"type": "language", "value": "de", "target": "https://example.com/de"}`,
+			mock:      true,
+			mockError: storage.ErrRuleNotFound,
+			want:      `{"status": "Error", "error": "not found"}`,
+		},
+		{
+			name:  "Negative position",
+			path:  "/url/test_alias/rules/7",
+			input: `{"type": "language", "value": "de", "target": "https://example.com/de", "position": -1}`,
+			want:  `{"status": "Error", "error": "field Position is not valid"}`,
+		},
+		{
+			name:  "Private target",
+			path:  "/url/test_alias/rules/7",
+			input: `{"type": "language", "value": "de", "target": "http://10.0.0.1/"}`,
+			want:  `{"status": "Error", "error": "private and loopback addresses are not allowed"}`,
+		},
+		{
+			name:  "Bad id",
+			path:  "/url/test_alias/rules/abc",
+			input: `{"type": "language", "value": "de", "target": "https://example.com/de"}`,
+			want:  `{"status": "Error", "error": "invalid rule id"}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ruleUpdaterMock := mocks.NewRuleUpdater(t)
+			if tc.mock {
+				ruleUpdaterMock.On("UpdateRule", mock.Anything, "test_alias", de, tc.position).Return(tc.mockError).Once()
+			}
+
+			r := chi.NewRouter()
+			r.Put("/url/{alias}/rules/{id}", rules.NewUpdate(slogdiscard.NewDiscardLogger(), ruleUpdaterMock, rules.WithDestinationChecker(policy)))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, tc.path, bytes.NewReader([]byte(tc.input))))
+
+			require.JSONEq(t, tc.want, rr.Body.String())
+		})
+	}
+}
+
+func TestListHandler(t *testing.T) {
+	ruleGetterMock := mocks.NewRuleGetter(t)
+	ruleGetterMock.On("GetRules", mock.Anything, "test_alias").Return(nil, nil).Once()
+
+	r := chi.NewRouter()
+	r.Get("/url/{alias}/rules", rules.NewList(slogdiscard.NewDiscardLogger(), ruleGetterMock))
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/url/test_alias/rules", nil))
+
+	require.JSONEq(t, `{"status": "Ok", "rules": []}`, rr.Body.String())
+}
+
+func TestDeleteHandler(t *testing.T) {
+	ruleDeleterMock := mocks.NewRuleDeleter(t)
+	ruleDeleterMock.On("DeleteRule", mock.Anything, "test_alias", int64(7)).Return(storage.ErrRuleNotFound).Once()
+
+	r := chi.NewRouter()
+	r.Delete("/url/{alias}/rules/{id}", rules.NewDelete(slogdiscard.NewDiscardLogger(), ruleDeleterMock))
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/url/test_alias/rules/7", nil))
+	require.JSONEq(t, `{"status": "Error", "error": "not found"}`, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/url/test_alias/rules/abc", nil))
+	require.JSONEq(t, `{"status": "Error", "error": "invalid rule id"}`, rr.Body.String())
+}
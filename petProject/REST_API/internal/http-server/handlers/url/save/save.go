@@ -1,34 +1,65 @@
 package save
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	_ "github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
+	"golang.org/x/crypto/bcrypt"
 	"io"
 	"log/slog"
+	"main.go/internal/lib/aliassig"
 	resp "main.go/internal/lib/api/response"
+	"main.go/internal/lib/auth"
 	"main.go/internal/lib/logger/sl"
+	"main.go/internal/lib/quota"
 	"main.go/internal/lib/random"
+	"main.go/internal/lib/selflink"
+	"main.go/internal/lib/split"
+	"main.go/internal/lib/utm"
 	"main.go/internal/storage"
 	"net/http"
+	"time"
 )
 
 // Эта структура для парсинга выходящих данных json
 
 type Request struct { // Структура запроса для парсинга
-	URL   string `json:"url" validate:"url"` // обязательное поле для валидного URL
-	Alias string `json:"alias,omitempty"`    // omitempty - если nil. То есть мы указываем, что должно отобразится
+	URL   string `json:"url" validate:"url"`                                     // обязательное поле для валидного URL
+	Alias string `json:"alias,omitempty" validate:"omitempty,excludesall=/+?#~"` // omitempty - если nil. То есть мы указываем, что должно отобразится
 	//необязательное поле для псевдонима. Если оно не указано, оставляется пустым.
+	ForwardQuery  bool            `json:"forward_query,omitempty"`                        // передавать query string при редиректе
+	ForwardPath   bool            `json:"forward_path,omitempty"`                         // дописывать хвост пути после alias к URL
+	UTM           utm.Params      `json:"utm,omitempty"`                                  // UTM-метки, добавляются к URL при редиректе
+	Campaign      string          `json:"campaign,omitempty"`                             // имя пресета UTM из конфига, поля UTM его перекрывают
+	Password      string          `json:"password,omitempty" validate:"omitempty,max=72"` // пароль на ссылку, хранится только bcrypt-хеш
+	MaxClicks     int             `json:"max_clicks,omitempty" validate:"min=0"`          // ссылка работает N редиректов, 1 - одноразовая
+	NotBefore     time.Time       `json:"not_before,omitempty"`                           // RFC 3339, до этого момента ссылка не активна
+	NotAfter      time.Time       `json:"not_after,omitempty"`                            // RFC 3339, после этого момента ссылка не активна
+	Variants      []split.Variant `json:"variants,omitempty" validate:"omitempty,dive"`   // A/B-тест: адреса с весами вместо URL
+	StickyVariant bool            `json:"sticky_variant,omitempty"`                       // запоминать вариант посетителя в cookie
+	Interstitial  bool            `json:"interstitial,omitempty"`                         // показывать страницу предпросмотра перед редиректом
+	Private       bool            `json:"private,omitempty"`                              // редирект только по подписанному alias из ответа
+}
+
+// LogValue hides the password when the request is logged.
+func (r Request) LogValue() slog.Value {
+	if r.Password != "" {
+		r.Password = "***"
+	}
+
+	type plain Request // без метода LogValue, иначе бесконечная рекурсия
+	return slog.AnyValue(plain(r))
 }
 
 // Это структура для формирования ответа с дополнительным полем Alias.
 
 type Response struct {
 	resp.Response
-	Alias string `json:"alias,omitempty"` // Alias, который был использован
+	Alias       string `json:"alias,omitempty"`        // Alias, который был использован
+	SignedAlias string `json:"signed_alias,omitempty"` // для приватной ссылки: только он работает в коротком URL
 }
 
 // TODO: move to config (перенести в конфиг)
@@ -36,20 +67,116 @@ const aliasLength = 6
 
 //var w http.ResponseWriter
 
+// Option configures the save handler.
+type Option func(*options)
+
+type options struct {
+	campaigns map[string]utm.Params
+	checker   DestinationChecker
+	selfLinks SelfLinks
+	quota     Quota
+	signer    *aliassig.Signer
+	reserved  map[string]struct{}
+}
+
+// Quota limits how many links a user or API key may have.
+type Quota struct {
+	Quotas  *quota.Quotas
+	Counter UsageCounter
+}
+
+// UsageCounter is an interface for counting links of a user or API key.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=UsageCounter
+type UsageCounter interface {
+	GetUsage(ctx context.Context, userID int64, createdBy string, since, now time.Time) (storage.Usage, error)
+}
+
+// SelfLinks configures destinations that point back to our own short links.
+type SelfLinks struct {
+	Resolver *selflink.Resolver
+	Getter   URLGetter
+	Reject   bool // true - не сокращать свои ссылки вовсе, false - сохранять конечный адрес цепочки
+}
+
+// URLGetter is an interface for resolving our own short links.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLGetter
+type URLGetter interface {
+	GetURL(ctx context.Context, alias string) (storage.URL, error)
+}
+
+// DestinationChecker decides whether a URL may be used as a link destination.
+type DestinationChecker interface {
+	Check(rawURL string) error
+}
+
+// WithCampaigns sets named UTM presets that can be referenced by Request.Campaign.
+func WithCampaigns(campaigns map[string]utm.Params) Option {
+	return func(o *options) {
+		o.campaigns = campaigns
+	}
+}
+
+// WithDestinationChecker rejects destinations (URL and A/B variants) that the checker does not allow.
+func WithDestinationChecker(checker DestinationChecker) Option {
+	return func(o *options) {
+		o.checker = checker
+	}
+}
+
+// WithSelfLinks replaces destinations on our own hosts with their final target (or rejects them).
+func WithSelfLinks(cfg SelfLinks) Option {
+	return func(o *options) {
+		o.selfLinks = cfg
+	}
+}
+
+// WithQuota rejects new links once the principal has used up its quota.
+func WithQuota(cfg Quota) Option {
+	return func(o *options) {
+		o.quota = cfg
+	}
+}
+
+// WithSigner enables private links: их alias в коротком URL подписан HMAC.
+func WithSigner(signer *aliassig.Signer) Option {
+	return func(o *options) {
+		o.signer = signer
+	}
+}
+
+// WithReservedAliases rejects aliases that collide with top-level routes (/url, /admin, /metrics и т.п.):
+// такая ссылка сохранилась бы, но редирект по ней был бы недоступен.
+func WithReservedAliases(aliases ...string) Option {
+	return func(o *options) {
+		if o.reserved == nil {
+			o.reserved = make(map[string]struct{}, len(aliases))
+		}
+		for _, a := range aliases {
+			o.reserved[a] = struct{}{}
+		}
+	}
+}
+
 type URLSaver interface {
-	SaveURL(urlToSave string, alias string) (int64, error)
+	SaveURL(ctx context.Context, urlToSave string, alias string, opts storage.URLOptions) (int64, error)
 	// который будет сохранять URL с псевдонимом в базе данных.
 }
 
 //go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLSaver
 // New возвращает функцию-обработчик HTTP-запроса, которая будет вызываться при обработке запроса POST
 
-func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
+func New(log *slog.Logger, urlSaver URLSaver, opts ...Option) http.HandlerFunc {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.save.New"
-		log = log.With(
+		log := sl.FromContext(r.Context(), log).With(
 			slog.String("op", op),
-			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
 		var req Request
@@ -74,7 +201,7 @@ func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
 				render.JSON(w, r, resp.Error("failed to decode request: "+err.Error()))
 				return
 			}
-			log.Info("Decoded JSON: ", req)
+			log.Info("decoded request", slog.Any("request", req))
 		}
 
 		// Если URL пустой, генерируем его
@@ -96,14 +223,127 @@ func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
 			return
 		}
 
+		if !req.NotBefore.IsZero() && !req.NotAfter.IsZero() && !req.NotAfter.After(req.NotBefore) {
+			log.Info("invalid activation window")
+			render.JSON(w, r, resp.Error("not_after must be after not_before"))
+			return
+		}
+
+		if req.Private && o.signer == nil {
+			log.Info("private links are not enabled")
+			render.JSON(w, r, resp.Error("private links are not enabled"))
+			return
+		}
+
+		variants, err := split.Normalize(req.Variants)
+		if err != nil {
+			log.Info("invalid variants", sl.Err(err))
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
 		alias := req.Alias
 		if alias == "" {
 			alias = random.NewRandomString(6)
 			log.Info("Generated alias: ", slog.String("alias", alias))
 		}
 
+		if _, ok := o.reserved[alias]; ok {
+			log.Info("alias is reserved", slog.String("alias", alias))
+			render.JSON(w, r, resp.Error("alias is reserved"))
+			return
+		}
+
+		// Ссылки на наш же сервис заменяем конечным адресом, иначе получаются цепочки и циклы
+		if o.selfLinks.Resolver != nil {
+			if req.URL, err = o.resolveSelf(r.Context(), alias, req.URL); err == nil {
+				for i := range variants {
+					if variants[i].URL, err = o.resolveSelf(r.Context(), alias, variants[i].URL); err != nil {
+						break
+					}
+				}
+			}
+			if err != nil {
+				if !selflink.IsRejection(err) {
+					log.Error("failed to resolve self link", sl.Err(err))
+					render.JSON(w, r, resp.Error("failed to add URL"))
+					return
+				}
+				log.Info("self link rejected", sl.Err(err))
+				render.JSON(w, r, resp.Error(err.Error()))
+				return
+			}
+		}
+
+		if o.checker != nil {
+			destinations := []string{req.URL}
+			for _, v := range variants {
+				destinations = append(destinations, v.URL)
+			}
+
+			for _, dest := range destinations {
+				if err := o.checker.Check(dest); err != nil {
+					log.Info("destination rejected", slog.String("url", dest), sl.Err(err))
+					render.JSON(w, r, resp.Error(err.Error()))
+					return
+				}
+			}
+		}
+
+		tags := req.UTM
+		if req.Campaign != "" {
+			preset, ok := o.campaigns[req.Campaign]
+			if !ok {
+				log.Info("unknown campaign", slog.String("campaign", req.Campaign))
+				render.JSON(w, r, resp.Error("unknown campaign"))
+				return
+			}
+			tags = tags.Merge(preset)
+		}
+
+		// Владелец ссылки - пользователь, от имени которого пришел запрос
+		owner, _ := auth.FromContext(r.Context())
+
+		if o.quota.Quotas != nil {
+			if err := o.checkQuota(r.Context(), owner); err != nil {
+				if errors.Is(err, quota.ErrActiveLinks) || errors.Is(err, quota.ErrMonthlyLinks) {
+					log.Info("quota exceeded", slog.String("subject", owner.Subject), sl.Err(err))
+					render.JSON(w, r, resp.Error(err.Error()))
+					return
+				}
+				log.Error("failed to check quota", sl.Err(err))
+				render.JSON(w, r, resp.Error("failed to add URL"))
+				return
+			}
+		}
+
+		var passwordHash string
+		if req.Password != "" {
+			hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+			if err != nil {
+				log.Error("failed to hash password", sl.Err(err))
+				render.JSON(w, r, resp.Error("failed to add URL"))
+				return
+			}
+			passwordHash = string(hash)
+		}
+
 		// Обработка сохранения URL
-		id, err := urlSaver.SaveURL(req.URL, alias)
+		id, err := urlSaver.SaveURL(r.Context(), req.URL, alias, storage.URLOptions{
+			ForwardQuery:  req.ForwardQuery,
+			ForwardPath:   req.ForwardPath,
+			UTM:           tags,
+			PasswordHash:  passwordHash,
+			MaxClicks:     req.MaxClicks,
+			NotBefore:     req.NotBefore,
+			NotAfter:      req.NotAfter,
+			Variants:      variants,
+			StickyVariant: req.StickyVariant,
+			Interstitial:  req.Interstitial,
+			OwnerID:       owner.UserID,
+			CreatedBy:     owner.Subject,
+			Private:       req.Private,
+		})
 		// SaveURL - сохр url и alias в бд и возвр ID, ошибку
 		// 1 - url наш | 2 - имя (сокращенное имя url)
 		if errors.Is(err, storage.ErrURLExists) {
@@ -118,13 +358,41 @@ func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
 		}
 
 		log.Info("URL added", slog.Int64("id", id))
-		responseOK(w, r, alias)
+
+		var signed string
+		if req.Private {
+			signed = o.signer.Sign(alias)
+		}
+		responseOK(w, r, alias, signed)
+	}
+}
+
+// checkQuota returns quota.ErrActiveLinks or quota.ErrMonthlyLinks if one more link does not fit.
+// Параллельные запросы могут превысить квоту на несколько ссылок, это допустимо.
+func (o options) checkQuota(ctx context.Context, p auth.Principal) error {
+	limits := o.quota.Quotas.For(p)
+	if limits.Unlimited() {
+		return nil
 	}
+
+	now := time.Now()
+	usage, err := o.quota.Counter.GetUsage(ctx, p.UserID, p.Subject, quota.MonthStart(now), now)
+	if err != nil {
+		return err
+	}
+
+	return limits.Allow(usage)
+}
+
+// resolveSelf returns the final destination for dest if it points to our own short link.
+func (o options) resolveSelf(ctx context.Context, alias, dest string) (string, error) {
+	return o.selfLinks.Resolver.ResolveStatic(ctx, o.selfLinks.Getter, alias, dest, o.selfLinks.Reject)
 }
 
-func responseOK(w http.ResponseWriter, r *http.Request, alias string) {
+func responseOK(w http.ResponseWriter, r *http.Request, alias, signedAlias string) {
 	render.JSON(w, r, Response{
-		Response: resp.OK(),
-		Alias:    alias,
+		Response:    resp.OK(),
+		Alias:       alias,
+		SignedAlias: signedAlias,
 	})
 }
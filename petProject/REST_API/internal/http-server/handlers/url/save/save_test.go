@@ -7,13 +7,24 @@ import (
 	"fmt"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 	"log"
 	"main.go/internal/http-server/handlers/url/save"
 	"main.go/internal/http-server/handlers/url/save/mocks"
+	"main.go/internal/lib/aliassig"
+	"main.go/internal/lib/auth"
 	"main.go/internal/lib/logger/handlers/slogdiscard"
+	"main.go/internal/lib/quota"
+	"main.go/internal/lib/selflink"
+	"main.go/internal/lib/split"
+	"main.go/internal/lib/urlpolicy"
+	"main.go/internal/lib/utm"
+	"main.go/internal/storage"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestSaveHandler(t *testing.T) {
@@ -35,10 +46,9 @@ func TestSaveHandler(t *testing.T) {
 			url:   "https://google.com",
 		},
 		{
-			name:      "Empty URL",
-			url:       "",
-			alias:     "some_alias",
-			respError: "failed URL is a reuired field",
+			name:  "Empty URL",
+			url:   "",
+			alias: "some_alias",
 		},
 		{
 			name:      "Invalid URL",
@@ -50,7 +60,7 @@ func TestSaveHandler(t *testing.T) {
 			name:      "SaveURL Error",
 			alias:     "test_alias",
 			url:       "https://google.com",
-			respError: "failed to add url",
+			respError: "failed to add URL",
 			mockError: errors.New("unexpected error"),
 		},
 	}
@@ -64,8 +74,14 @@ func TestSaveHandler(t *testing.T) {
 
 			urlSaverMock := mocks.NewURLSaver(t)
 
+			// Пустой URL заменяется сгенерированным
+			var savedURL any = tc.url
+			if tc.url == "" {
+				savedURL = mock.MatchedBy(func(u string) bool { return strings.HasPrefix(u, "https://generated-url.com/") })
+			}
+
 			if tc.respError == "" || tc.mockError != nil {
-				urlSaverMock.On("SaveURL", tc.url, mock.AnythingOfType("string")).
+				urlSaverMock.On("SaveURL", mock.Anything, savedURL, mock.AnythingOfType("string"), mock.AnythingOfType("storage.URLOptions")).
 					Return(int64(1), tc.mockError).
 					Once()
 			}
@@ -106,3 +122,468 @@ func TestSaveHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestSaveHandler_Campaign(t *testing.T) {
+	campaigns := map[string]utm.Params{
+		"newsletter": {Source: "newsletter", Medium: "email", Campaign: "weekly"},
+	}
+
+	cases := []struct {
+		name      string
+		input     string
+		utm       utm.Params
+		respError string
+	}{
+		{
+			name:  "Explicit tags",
+			input: `{"url": "https://google.com", "utm": {"source": "twitter", "content": "banner"}}`,
+			utm:   utm.Params{Source: "twitter", Content: "banner"},
+		},
+		{
+			name:  "Preset",
+			input: `{"url": "https://google.com", "campaign": "newsletter"}`,
+			utm:   utm.Params{Source: "newsletter", Medium: "email", Campaign: "weekly"},
+		},
+		{
+			name:  "Preset overridden by explicit tags",
+			input: `{"url": "https://google.com", "campaign": "newsletter", "utm": {"campaign": "spring"}}`,
+			utm:   utm.Params{Source: "newsletter", Medium: "email", Campaign: "spring"},
+		},
+		{
+			name:      "Unknown preset",
+			input:     `{"url": "https://google.com", "campaign": "missing"}`,
+			respError: "unknown campaign",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlSaverMock := mocks.NewURLSaver(t)
+
+			if tc.respError == "" {
+				urlSaverMock.On("SaveURL", mock.Anything, "https://google.com", mock.AnythingOfType("string"), storage.URLOptions{UTM: tc.utm}).
+					Return(int64(1), nil).
+					Once()
+			}
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.WithCampaigns(campaigns))
+
+			req, err := http.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(tc.input)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
+
+func TestSaveHandler_Password(t *testing.T) {
+	urlSaverMock := mocks.NewURLSaver(t)
+
+	urlSaverMock.On("SaveURL", mock.Anything, "https://google.com", mock.AnythingOfType("string"),
+		mock.MatchedBy(func(opts storage.URLOptions) bool {
+			// Сохраняется только хеш, а не сам пароль
+			return opts.PasswordHash != "secret" &&
+				bcrypt.CompareHashAndPassword([]byte(opts.PasswordHash), []byte("secret")) == nil
+		})).
+		Return(int64(1), nil).
+		Once()
+
+	handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock)
+
+	input := `{"url": "https://google.com", "password": "secret"}`
+	req, err := http.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(input)))
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	var resp save.Response
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Empty(t, resp.Error)
+}
+
+func TestSaveHandler_ActivationWindow(t *testing.T) {
+	cases := []struct {
+		name      string
+		input     string
+		opts      storage.URLOptions
+		respError string
+	}{
+		{
+			name:  "Window",
+			input: `{"url": "https://google.com", "not_before": "2025-03-01T00:00:00Z", "not_after": "2025-04-01T00:00:00Z"}`,
+			opts: storage.URLOptions{
+				NotBefore: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+				NotAfter:  time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:      "Inverted window",
+			input:     `{"url": "https://google.com", "not_before": "2025-04-01T00:00:00Z", "not_after": "2025-03-01T00:00:00Z"}`,
+			respError: "not_after must be after not_before",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlSaverMock := mocks.NewURLSaver(t)
+
+			if tc.respError == "" {
+				urlSaverMock.On("SaveURL", mock.Anything, "https://google.com", mock.AnythingOfType("string"), tc.opts).
+					Return(int64(1), nil).
+					Once()
+			}
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock)
+
+			req, err := http.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(tc.input)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
+
+func TestSaveHandler_Variants(t *testing.T) {
+	cases := []struct {
+		name      string
+		input     string
+		opts      storage.URLOptions
+		respError string
+	}{
+		{
+			name:  "Default names",
+			input: `{"url": "https://google.com", "variants": [{"url": "https://google.com/a", "weight": 1}, {"url": "https://google.com/b", "weight": 2}], "sticky_variant": true}`,
+			opts: storage.URLOptions{
+				Variants: []split.Variant{
+					{Name: "v1", URL: "https://google.com/a", Weight: 1},
+					{Name: "v2", URL: "https://google.com/b", Weight: 2},
+				},
+				StickyVariant: true,
+			},
+		},
+		{
+			name:      "Zero weight",
+			input:     `{"url": "https://google.com", "variants": [{"url": "https://google.com/a", "weight": 0}]}`,
+			respError: "field Weight is not valid",
+		},
+		{
+			name:      "Duplicate names",
+			input:     `{"url": "https://google.com", "variants": [{"name": "a", "url": "https://google.com/a", "weight": 1}, {"name": "a", "url": "https://google.com/b", "weight": 1}]}`,
+			respError: `duplicate variant name: "a"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlSaverMock := mocks.NewURLSaver(t)
+
+			if tc.respError == "" {
+				urlSaverMock.On("SaveURL", mock.Anything, "https://google.com", mock.AnythingOfType("string"), tc.opts).
+					Return(int64(1), nil).
+					Once()
+			}
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock)
+
+			req, err := http.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(tc.input)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
+
+func TestSaveHandler_DestinationPolicy(t *testing.T) {
+	policy, err := urlpolicy.New(urlpolicy.Config{
+		DenyDomains:  []string{"*.evil.com"},
+		BlockPrivate: true,
+	})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name      string
+		input     string
+		respError string
+	}{
+		{
+			name:  "Allowed",
+			input: `{"url": "https://google.com"}`,
+		},
+		{
+			name:      "Javascript scheme",
+			input:     `{"url": "javascript:alert(1)"}`,
+			respError: "url scheme is not allowed",
+		},
+		{
+			name:      "Loopback",
+			input:     `{"url": "http://127.0.0.1:8080/admin"}`,
+			respError: "private and loopback addresses are not allowed",
+		},
+		{
+			name:      "Denied variant",
+			input:     `{"url": "https://google.com", "variants": [{"url": "https://www.evil.com", "weight": 1}]}`,
+			respError: "domain is denied",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlSaverMock := mocks.NewURLSaver(t)
+
+			if tc.respError == "" {
+				urlSaverMock.On("SaveURL", mock.Anything, "https://google.com", mock.AnythingOfType("string"), mock.AnythingOfType("storage.URLOptions")).
+					Return(int64(1), nil).
+					Once()
+			}
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.WithDestinationChecker(policy))
+
+			req, err := http.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(tc.input)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
+
+func TestSaveHandler_SelfLinks(t *testing.T) {
+	cases := []struct {
+		name      string
+		input     string
+		reject    bool
+		links     map[string]storage.URL
+		saved     string
+		respError string
+	}{
+		{
+			name:  "External url",
+			input: `{"url": "https://google.com", "alias": "new"}`,
+			saved: "https://google.com",
+		},
+		{
+			name:  "Chain is flattened",
+			input: `{"url": "http://localhost:8080/a", "alias": "new"}`,
+			links: map[string]storage.URL{
+				"a": {Alias: "a", URL: "https://sho.rt/b"},
+				"b": {Alias: "b", URL: "https://google.com"},
+			},
+			saved: "https://google.com",
+		},
+		{
+			name:      "Reject mode",
+			input:     `{"url": "https://sho.rt/a", "alias": "new"}`,
+			reject:    true,
+			respError: "destination points to this url shortener",
+		},
+		{
+			name:  "Loop",
+			input: `{"url": "https://sho.rt/a", "alias": "new"}`,
+			links: map[string]storage.URL{
+				"a": {Alias: "a", URL: "https://sho.rt/new"},
+			},
+			respError: "redirect loop detected",
+		},
+		{
+			name:      "Unknown alias",
+			input:     `{"url": "https://sho.rt/missing", "alias": "new"}`,
+			respError: "destination points to an unknown short link",
+		},
+		{
+			name:  "Protected link",
+			input: `{"url": "https://sho.rt/a", "alias": "new"}`,
+			links: map[string]storage.URL{
+				"a": {Alias: "a", URL: "https://google.com", URLOptions: storage.URLOptions{PasswordHash: "hash"}},
+			},
+			respError: "destination points to a short link with its own rules",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlSaverMock := mocks.NewURLSaver(t)
+			urlGetterMock := mocks.NewURLGetter(t)
+
+			for alias, link := range tc.links {
+				urlGetterMock.On("GetURL", mock.Anything, alias).Return(link, nil).Maybe()
+			}
+			urlGetterMock.On("GetURL", mock.Anything, mock.AnythingOfType("string")).Return(storage.URL{}, storage.ErrURLNotFound).Maybe()
+
+			if tc.respError == "" {
+				urlSaverMock.On("SaveURL", mock.Anything, tc.saved, "new", mock.AnythingOfType("storage.URLOptions")).
+					Return(int64(1), nil).
+					Once()
+			}
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.WithSelfLinks(save.SelfLinks{
+				Resolver: selflink.New([]string{"localhost:8080", "sho.rt"}, 0),
+				Getter:   urlGetterMock,
+				Reject:   tc.reject,
+			}))
+
+			req, err := http.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(tc.input)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
+
+func TestSaveHandler_Owner(t *testing.T) {
+	urlSaverMock := mocks.NewURLSaver(t)
+	urlSaverMock.On("SaveURL", mock.Anything, "https://google.com", "mine", mock.MatchedBy(func(opts storage.URLOptions) bool {
+		return opts.OwnerID == 5 && opts.CreatedBy == "key:1"
+	})).Return(int64(1), nil).Once()
+
+	handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock)
+
+	req, err := http.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(`{"url": "https://google.com", "alias": "mine"}`)))
+	require.NoError(t, err)
+	req = req.WithContext(auth.WithPrincipal(req.Context(), auth.Principal{Subject: "key:1", UserID: 5}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	var resp save.Response
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Empty(t, resp.Error)
+}
+
+func TestSaveHandler_Quota(t *testing.T) {
+	quotas := quota.New(quota.Config{
+		Default:  quota.Limits{MaxActive: 2, MaxPerMonth: 10},
+		Subjects: map[string]quota.Limits{"key:admin": {}},
+	})
+
+	cases := []struct {
+		name      string
+		subject   string
+		usage     storage.Usage
+		respError string
+	}{
+		{name: "Within quota", subject: "key:1", usage: storage.Usage{Active: 1, ThisMonth: 9}},
+		{name: "Active limit", subject: "key:1", usage: storage.Usage{Active: 2, ThisMonth: 2}, respError: quota.ErrActiveLinks.Error()},
+		{name: "Monthly limit", subject: "key:1", usage: storage.Usage{Active: 0, ThisMonth: 10}, respError: quota.ErrMonthlyLinks.Error()},
+		{name: "Unlimited override", subject: "key:admin"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlSaverMock := mocks.NewURLSaver(t)
+			counterMock := mocks.NewUsageCounter(t)
+
+			if tc.subject != "key:admin" {
+				counterMock.On("GetUsage", mock.Anything, int64(0), tc.subject, mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time")).
+					Return(tc.usage, nil).Once()
+			}
+			if tc.respError == "" {
+				urlSaverMock.On("SaveURL", mock.Anything, "https://google.com", "q", mock.Anything).Return(int64(1), nil).Once()
+			}
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.WithQuota(save.Quota{Quotas: quotas, Counter: counterMock}))
+
+			req, err := http.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(`{"url": "https://google.com", "alias": "q"}`)))
+			require.NoError(t, err)
+			req = req.WithContext(auth.WithPrincipal(req.Context(), auth.Principal{Subject: tc.subject}))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
+
+func TestSaveHandler_Private(t *testing.T) {
+	signer := aliassig.New([]byte("secret"), 0)
+
+	urlSaverMock := mocks.NewURLSaver(t)
+	urlSaverMock.On("SaveURL", mock.Anything, "https://google.com", "secret_link", mock.MatchedBy(func(opts storage.URLOptions) bool {
+		return opts.Private
+	})).Return(int64(1), nil).Once()
+
+	do := func(handler http.HandlerFunc, body string) save.Response {
+		req, err := http.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(body)))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		var resp save.Response
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		return resp
+	}
+
+	resp := do(save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.WithSigner(signer)),
+		`{"url": "https://google.com", "alias": "secret_link", "private": true}`)
+	require.Empty(t, resp.Error)
+	require.Equal(t, "secret_link", resp.Alias)
+	require.Equal(t, signer.Sign("secret_link"), resp.SignedAlias)
+
+	// Без секрета приватные ссылки не создаются
+	resp = do(save.New(slogdiscard.NewDiscardLogger(), urlSaverMock),
+		`{"url": "https://google.com", "alias": "secret_link", "private": true}`)
+	require.Equal(t, "private links are not enabled", resp.Error)
+
+	// Разделитель подписи нельзя использовать в alias
+	resp = do(save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.WithSigner(signer)),
+		`{"url": "https://google.com", "alias": "a~b"}`)
+	require.NotEmpty(t, resp.Error)
+}
+
+func TestSaveHandler_ReservedAliases(t *testing.T) {
+	urlSaverMock := mocks.NewURLSaver(t)
+	urlSaverMock.On("SaveURL", mock.Anything, "https://google.com", "metrics2", mock.AnythingOfType("storage.URLOptions")).
+		Return(int64(1), nil).Once()
+
+	handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.WithReservedAliases("url", "admin", "metrics"))
+
+	for alias, want := range map[string]string{
+		"admin":    "alias is reserved",
+		"metrics":  "alias is reserved",
+		"metrics2": "",
+	} {
+		t.Run(alias, func(t *testing.T) {
+			input := fmt.Sprintf(`{"url": "https://google.com", "alias": "%s"}`, alias)
+			req, err := http.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(input)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, want, resp.Error)
+		})
+	}
+}
@@ -0,0 +1,57 @@
+// Статистика переходов по ссылке: /url/{alias}/stats
+
+package stats
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	resp "main.go/internal/lib/api/response"
+	"main.go/internal/lib/logger/sl"
+	"main.go/internal/storage"
+)
+
+type Response struct {
+	resp.Response
+	Clicks storage.ClickStats `json:"clicks"`
+}
+
+// StatsGetter is an interface for getting click stats by alias.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=StatsGetter
+type StatsGetter interface {
+	GetClickStats(ctx context.Context, alias string) (storage.ClickStats, error)
+}
+
+func New(log *slog.Logger, statsGetter StatsGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.stats.New"
+
+		log := sl.FromContext(r.Context(), log).With(
+			slog.String("op", op),
+		)
+
+		alias := chi.URLParam(r, "alias")
+
+		clicks, err := statsGetter.GetClickStats(r.Context(), alias)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", "alias", alias)
+			render.JSON(w, r, resp.Error("not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get stats", sl.Err(err))
+			render.JSON(w, r, resp.Error("internal error"))
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Clicks:   clicks,
+		})
+	}
+}
@@ -0,0 +1,46 @@
+// Code generated by mockery v2.53.7. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// RoleSetter is an autogenerated mock type for the RoleSetter type
+type RoleSetter struct {
+	mock.Mock
+}
+
+// SetUserRole provides a mock function with given fields: ctx, id, role
+func (_m *RoleSetter) SetUserRole(ctx context.Context, id int64, role string) error {
+	ret := _m.Called(ctx, id, role)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetUserRole")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) error); ok {
+		r0 = rf(ctx, id, role)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewRoleSetter creates a new instance of RoleSetter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewRoleSetter(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *RoleSetter {
+	mock := &RoleSetter{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,59 @@
+// Code generated by mockery v2.53.7. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+	storage "main.go/internal/storage"
+)
+
+// UserLister is an autogenerated mock type for the UserLister type
+type UserLister struct {
+	mock.Mock
+}
+
+// ListUsers provides a mock function with given fields: ctx
+func (_m *UserLister) ListUsers(ctx context.Context) ([]storage.User, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListUsers")
+	}
+
+	var r0 []storage.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]storage.User, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []storage.User); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]storage.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewUserLister creates a new instance of UserLister. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewUserLister(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *UserLister {
+	mock := &UserLister{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
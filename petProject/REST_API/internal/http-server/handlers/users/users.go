@@ -0,0 +1,203 @@
+// Пользователи: /users (только admin)
+
+package users
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	resp "main.go/internal/lib/api/response"
+	"main.go/internal/lib/auth"
+	"main.go/internal/lib/logger/sl"
+	"main.go/internal/storage"
+)
+
+type Request struct {
+	Name string `json:"name" validate:"required,max=64"`
+	Role string `json:"role,omitempty" validate:"omitempty,oneof=viewer editor admin"` // по умолчанию editor
+}
+
+type RoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=viewer editor admin"`
+}
+
+type CreateResponse struct {
+	resp.Response
+	ID int64 `json:"id,omitempty"`
+}
+
+type ListResponse struct {
+	resp.Response
+	Users []storage.User `json:"users"`
+}
+
+// UserSaver is an interface for creating users.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=UserSaver
+type UserSaver interface {
+	SaveUser(ctx context.Context, name, role string) (int64, error)
+}
+
+// UserLister is an interface for listing users.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=UserLister
+type UserLister interface {
+	ListUsers(ctx context.Context) ([]storage.User, error)
+}
+
+// RoleSetter is an interface for changing the role of a user.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=RoleSetter
+type RoleSetter interface {
+	SetUserRole(ctx context.Context, id int64, role string) error
+}
+
+// NewCreate creates a user. Ключи для него выпускаются через POST /keys с user_id.
+func NewCreate(log *slog.Logger, userSaver UserSaver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.users.NewCreate"
+
+		log := sl.FromContext(r.Context(), log).With(
+			slog.String("op", op),
+		)
+
+		var req Request
+		err := render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+			render.JSON(w, r, resp.Error("empty request"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			var validateErrs validator.ValidationErrors
+			if errors.As(err, &validateErrs) {
+				log.Info("invalid request", sl.Err(err))
+				render.JSON(w, r, resp.ValidationError(validateErrs))
+				return
+			}
+
+			log.Error("failed to validate request", sl.Err(err))
+			render.JSON(w, r, resp.Error("invalid request"))
+			return
+		}
+
+		role := req.Role
+		if role == "" {
+			role = auth.RoleEditor
+		}
+
+		id, err := userSaver.SaveUser(r.Context(), req.Name, role)
+		if errors.Is(err, storage.ErrUserExists) {
+			log.Info("user already exists", slog.String("name", req.Name))
+			render.JSON(w, r, resp.Error("user already exists"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to save user", sl.Err(err))
+			render.JSON(w, r, resp.Error("failed to create user"))
+			return
+		}
+
+		log.Info("user created", slog.Int64("id", id), slog.String("name", req.Name), slog.String("role", role))
+
+		render.JSON(w, r, CreateResponse{
+			Response: resp.OK(),
+			ID:       id,
+		})
+	}
+}
+
+// NewList returns all users.
+func NewList(log *slog.Logger, userLister UserLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.users.NewList"
+
+		log := sl.FromContext(r.Context(), log).With(
+			slog.String("op", op),
+		)
+
+		users, err := userLister.ListUsers(r.Context())
+		if err != nil {
+			log.Error("failed to list users", sl.Err(err))
+			render.JSON(w, r, resp.Error("internal error"))
+			return
+		}
+
+		if users == nil {
+			users = []storage.User{} // в JSON пустой список, а не null
+		}
+
+		render.JSON(w, r, ListResponse{
+			Response: resp.OK(),
+			Users:    users,
+		})
+	}
+}
+
+// NewSetRole changes the role of a user. Новая роль действует сразу на все его ключи.
+func NewSetRole(log *slog.Logger, roleSetter RoleSetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.users.NewSetRole"
+
+		log := sl.FromContext(r.Context(), log).With(
+			slog.String("op", op),
+		)
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			log.Info("invalid user id", sl.Err(err))
+			render.JSON(w, r, resp.Error("invalid user id"))
+			return
+		}
+
+		var req RoleRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			var validateErrs validator.ValidationErrors
+			if errors.As(err, &validateErrs) {
+				log.Info("invalid request", sl.Err(err))
+				render.JSON(w, r, resp.ValidationError(validateErrs))
+				return
+			}
+
+			log.Error("failed to validate request", sl.Err(err))
+			render.JSON(w, r, resp.Error("invalid request"))
+			return
+		}
+
+		err = roleSetter.SetUserRole(r.Context(), id, req.Role)
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("user not found", slog.Int64("id", id))
+			render.JSON(w, r, resp.Error("not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to set role", sl.Err(err))
+			render.JSON(w, r, resp.Error("internal error"))
+			return
+		}
+
+		p, _ := auth.FromContext(r.Context())
+		log.Info("user role changed", slog.Int64("id", id), slog.String("role", req.Role), slog.String("by", p.Subject))
+
+		render.JSON(w, r, resp.OK())
+	}
+}
@@ -0,0 +1,99 @@
+package users_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"main.go/internal/http-server/handlers/users"
+	"main.go/internal/http-server/handlers/users/mocks"
+	"main.go/internal/lib/logger/handlers/slogdiscard"
+	"main.go/internal/storage"
+)
+
+func TestCreateHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		input     string
+		mockError error
+		want      string
+	}{
+		{
+			name:  "Success",
+			input: `{"name": "alice"}`,
+			want:  `{"status": "Ok", "id": 3}`,
+		},
+		{
+			name:      "Duplicate",
+			input:     `{"name": "alice"}`,
+			mockError: storage.ErrUserExists,
+			want:      `{"status": "Error", "error": "user already exists"}`,
+		},
+		{
+			name:  "Unknown role",
+			input: `{"name": "alice", "role": "root"}`,
+			want:  `{"status": "Error", "error": "field Role is not valid"}`,
+		},
+		{
+			name:  "Empty name",
+			input: `{"name": ""}`,
+			want:  `{"status": "Error", "error": "failed Name is a reuired field"}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			userSaverMock := mocks.NewUserSaver(t)
+			if tc.name != "Empty name" && tc.name != "Unknown role" {
+				userSaverMock.On("SaveUser", mock.Anything, "alice", "editor").Return(int64(3), tc.mockError).Once()
+			}
+
+			rr := httptest.NewRecorder()
+			users.NewCreate(slogdiscard.NewDiscardLogger(), userSaverMock).
+				ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/users", bytes.NewReader([]byte(tc.input))))
+
+			assert.JSONEq(t, tc.want, rr.Body.String())
+		})
+	}
+}
+
+func TestListHandler(t *testing.T) {
+	userListerMock := mocks.NewUserLister(t)
+	userListerMock.On("ListUsers", mock.Anything, mock.Anything).Return(nil, nil).Once()
+
+	rr := httptest.NewRecorder()
+	users.NewList(slogdiscard.NewDiscardLogger(), userListerMock).
+		ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/users", nil))
+
+	assert.JSONEq(t, `{"status": "Ok", "users": []}`, rr.Body.String())
+}
+
+func TestSetRoleHandler(t *testing.T) {
+	roleSetterMock := mocks.NewRoleSetter(t)
+	roleSetterMock.On("SetUserRole", mock.Anything, int64(3), "viewer").Return(nil).Once()
+	roleSetterMock.On("SetUserRole", mock.Anything, int64(4), "viewer").Return(storage.ErrUserNotFound).Once()
+
+	r := chi.NewRouter()
+	r.Put("/users/{id}/role", users.NewSetRole(slogdiscard.NewDiscardLogger(), roleSetterMock))
+
+	cases := []struct {
+		path  string
+		input string
+		want  string
+	}{
+		{path: "/users/3/role", input: `{"role": "viewer"}`, want: `{"status": "Ok"}`},
+		{path: "/users/4/role", input: `{"role": "viewer"}`, want: `{"status": "Error", "error": "not found"}`},
+		{path: "/users/3/role", input: `{"role": "owner"}`, want: `{"status": "Error", "error": "field Role is not valid"}`},
+		{path: "/users/x/role", input: `{"role": "viewer"}`, want: `{"status": "Error", "error": "invalid user id"}`},
+	}
+
+	for _, tc := range cases {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, tc.path, bytes.NewReader([]byte(tc.input))))
+		assert.JSONEq(t, tc.want, rr.Body.String(), tc.path+" "+tc.input)
+	}
+}
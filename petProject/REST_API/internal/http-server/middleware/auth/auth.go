@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	resp "main.go/internal/lib/api/response"
+	libauth "main.go/internal/lib/auth"
+	"main.go/internal/lib/logger/sl"
+	"main.go/internal/storage"
+)
+
+// New authenticates requests with the first authenticator that recognizes
+// the credentials and puts the principal into the request context.
+func New(log *slog.Logger, authenticators ...libauth.Authenticator) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			log := sl.FromContext(r.Context(), log).With(
+				slog.String("component", "middleware/auth"),
+			)
+
+			for _, a := range authenticators {
+				p, err := a.Authenticate(r)
+				if errors.Is(err, libauth.ErrNoCredentials) {
+					continue
+				}
+				if errors.Is(err, libauth.ErrExpired) {
+					log.Info("authentication failed", sl.Err(err))
+					unauthorized(w, r, libauth.ErrExpired.Error())
+					return
+				}
+				if errors.Is(err, libauth.ErrInvalidCredentials) {
+					// Подробности (какой claim не прошел) только в лог
+					log.Info("authentication failed", sl.Err(err))
+					unauthorized(w, r, libauth.ErrInvalidCredentials.Error())
+					return
+				}
+				if err != nil {
+					log.Error("failed to authenticate", sl.Err(err))
+					render.Status(r, http.StatusInternalServerError)
+					render.JSON(w, r, resp.Error("internal error"))
+					return
+				}
+
+				next.ServeHTTP(w, r.WithContext(libauth.WithPrincipal(r.Context(), p)))
+				return
+			}
+
+			log.Info("request without credentials", slog.String("method", r.Method), slog.String("path", r.URL.Path))
+			unauthorized(w, r, "unauthorized")
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// Authorize lets the request through only if the principal's role and scopes allow the action.
+// Отказы пишутся в лог запроса (с request_id), чтобы их можно было найти по ответу клиенту.
+func Authorize(log *slog.Logger, perm libauth.Permission) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			p, ok := libauth.FromContext(r.Context())
+			if !ok {
+				unauthorized(w, r, "unauthorized")
+				return
+			}
+
+			if !p.Can(perm) {
+				sl.FromContext(r.Context(), log).Warn("permission denied",
+					slog.String("component", "middleware/auth"),
+					slog.String("subject", p.Subject),
+					slog.String("role", p.Role),
+					slog.String("permission", string(perm)),
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+				)
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, resp.Error("forbidden"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// OwnerGetter is an interface for getting the user and the key that own a link.
+type OwnerGetter interface {
+	GetURLOwner(ctx context.Context, alias string) (storage.Owner, error)
+}
+
+// RequireOwner lets the request through only if the principal owns the link
+// from the {alias} URL parameter or is an admin.
+func RequireOwner(log *slog.Logger, ownerGetter OwnerGetter) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			log := sl.FromContext(r.Context(), log).With(
+				slog.String("component", "middleware/auth"),
+			)
+
+			p, ok := libauth.FromContext(r.Context())
+			if !ok {
+				unauthorized(w, r, "unauthorized")
+				return
+			}
+
+			alias := chi.URLParam(r, "alias")
+
+			owner, err := ownerGetter.GetURLOwner(r.Context(), alias)
+			if errors.Is(err, storage.ErrURLNotFound) {
+				log.Info("url not found", slog.String("alias", alias))
+				render.Status(r, http.StatusNotFound)
+				render.JSON(w, r, resp.Error("not found"))
+				return
+			}
+			if err != nil {
+				log.Error("failed to get url owner", sl.Err(err))
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error("internal error"))
+				return
+			}
+
+			if !p.CanManage(owner.UserID, owner.CreatedBy) {
+				log.Warn("not an owner of the link",
+					slog.String("alias", alias),
+					slog.String("subject", p.Subject),
+				)
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, resp.Error("forbidden"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, msg string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="url-shortener"`)
+	render.Status(r, http.StatusUnauthorized)
+	render.JSON(w, r, resp.Error(msg))
+}
@@ -0,0 +1,129 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	mwAuth "main.go/internal/http-server/middleware/auth"
+	"main.go/internal/lib/auth"
+	"main.go/internal/lib/logger/handlers/slogdiscard"
+	"main.go/internal/storage"
+)
+
+type authenticatorFunc func(r *http.Request) (auth.Principal, error)
+
+func (f authenticatorFunc) Authenticate(r *http.Request) (auth.Principal, error) { return f(r) }
+
+func TestAuth(t *testing.T) {
+	log := slogdiscard.NewDiscardLogger()
+
+	byHeader := authenticatorFunc(func(r *http.Request) (auth.Principal, error) {
+		switch r.Header.Get("X-Test") {
+		case "":
+			return auth.Principal{}, auth.ErrNoCredentials
+		case "reader":
+			return auth.Principal{Subject: "key:1", Role: auth.RoleEditor, Scopes: []string{auth.ScopeRead}}, nil
+		case "broken":
+			return auth.Principal{}, errors.New("db is down")
+		}
+		return auth.Principal{}, auth.ErrInvalidCredentials
+	})
+
+	h := mwAuth.New(log, byHeader)(mwAuth.Authorize(log, auth.PermLinksRead)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, _ := auth.FromContext(r.Context())
+		_, _ = w.Write([]byte(p.Subject))
+	})))
+	create := mwAuth.New(log, byHeader)(mwAuth.Authorize(log, auth.PermLinksCreate)(http.NotFoundHandler()))
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		header  string
+		status  int
+	}{
+		{name: "No credentials", handler: h, status: http.StatusUnauthorized},
+		{name: "Invalid", handler: h, header: "nope", status: http.StatusUnauthorized},
+		{name: "Authenticator error", handler: h, header: "broken", status: http.StatusInternalServerError},
+		{name: "Allowed", handler: h, header: "reader", status: http.StatusOK},
+		{name: "Missing scope", handler: create, header: "reader", status: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/url", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Test", tt.header)
+			}
+
+			rr := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.status, rr.Code)
+			if tt.status == http.StatusOK {
+				assert.Equal(t, "key:1", rr.Body.String())
+			}
+			if tt.status == http.StatusUnauthorized {
+				assert.NotEmpty(t, rr.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+type ownerGetterFunc func(alias string) (storage.Owner, error)
+
+func (f ownerGetterFunc) GetURLOwner(_ context.Context, alias string) (storage.Owner, error) {
+	return f(alias)
+}
+
+func TestRequireOwner(t *testing.T) {
+	log := slogdiscard.NewDiscardLogger()
+
+	owners := ownerGetterFunc(func(alias string) (storage.Owner, error) {
+		switch alias {
+		case "mine":
+			return storage.Owner{UserID: 1}, nil
+		case "theirs":
+			return storage.Owner{UserID: 2}, nil
+		case "anon":
+			return storage.Owner{CreatedBy: "key:7"}, nil
+		}
+		return storage.Owner{}, storage.ErrURLNotFound
+	})
+
+	tests := []struct {
+		name      string
+		alias     string
+		principal auth.Principal
+		status    int
+	}{
+		{name: "Owner", alias: "mine", principal: auth.Principal{UserID: 1}, status: http.StatusOK},
+		{name: "Other user", alias: "theirs", principal: auth.Principal{UserID: 1}, status: http.StatusForbidden},
+		{name: "Admin", alias: "theirs", principal: auth.Principal{Role: auth.RoleAdmin, Scopes: []string{auth.ScopeAdmin}}, status: http.StatusOK},
+		{name: "Ownerless key that created the link", alias: "anon", principal: auth.Principal{Subject: "key:7"}, status: http.StatusOK},
+		{name: "Another ownerless key", alias: "anon", principal: auth.Principal{Subject: "key:8"}, status: http.StatusForbidden},
+		{name: "Not found", alias: "missing", principal: auth.Principal{UserID: 1}, status: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := chi.NewRouter()
+			r.Route("/url/{alias}", func(r chi.Router) {
+				r.Use(mwAuth.RequireOwner(log, owners))
+				r.Get("/stats", func(w http.ResponseWriter, r *http.Request) {})
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/url/"+tt.alias+"/stats", nil)
+			req = req.WithContext(auth.WithPrincipal(req.Context(), tt.principal))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.status, rr.Code)
+		})
+	}
+}
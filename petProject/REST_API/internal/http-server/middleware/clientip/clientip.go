@@ -0,0 +1,20 @@
+package clientip
+
+import (
+	"net/http"
+
+	"main.go/internal/lib/clientip"
+)
+
+// New resolves the client IP once per request, учитывая доверенные прокси.
+// Лимиты, логи и трассировка дальше берут его через clientip.FromRequest, поэтому ставится первым после RequestID.
+func New(resolver *clientip.Resolver) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ctx := clientip.NewContext(r.Context(), resolver.Resolve(r))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
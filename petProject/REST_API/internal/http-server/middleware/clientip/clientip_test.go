@@ -0,0 +1,29 @@
+package clientip_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	mwClientIP "main.go/internal/http-server/middleware/clientip"
+	"main.go/internal/lib/clientip"
+)
+
+func TestClientIP(t *testing.T) {
+	resolver, err := clientip.New([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	var got string
+	h := mwClientIP.New(resolver)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = clientip.FromRequest(r)
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.0.0.2:5000"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	assert.Equal(t, "198.51.100.1", got)
+}
@@ -0,0 +1,56 @@
+package enumguard
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	resp "main.go/internal/lib/api/response"
+	"main.go/internal/lib/clientip"
+	"main.go/internal/lib/enumguard"
+	"main.go/internal/lib/logger/sl"
+)
+
+// New counts 404 responses per client IP and slows down or blocks clients that miss too often.
+// Ставится перед redirect, промахом считается любой ответ 404.
+func New(log *slog.Logger, guard *enumguard.Guard) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ip := clientip.FromRequest(r)
+
+			delay, blocked := guard.Check(ip, time.Now())
+			if blocked > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(blocked.Seconds()))))
+				render.Status(r, http.StatusTooManyRequests)
+				render.JSON(w, r, resp.Error("too many requests"))
+				return
+			}
+
+			if delay > 0 {
+				t := time.NewTimer(delay)
+				select {
+				case <-t.C:
+				case <-r.Context().Done(): // клиент ушел, отвечать некому
+					t.Stop()
+					return
+				}
+			}
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			if ww.Status() == http.StatusNotFound && guard.Miss(ip, time.Now()) {
+				sl.FromContext(r.Context(), log).Warn("client blocked for alias enumeration",
+					slog.String("component", "middleware/enumguard"),
+					slog.String("client_ip", ip),
+				)
+			}
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
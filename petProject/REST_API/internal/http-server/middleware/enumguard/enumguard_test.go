@@ -0,0 +1,62 @@
+package enumguard_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	mwEnumGuard "main.go/internal/http-server/middleware/enumguard"
+	"main.go/internal/lib/enumguard"
+	"main.go/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestEnumGuard(t *testing.T) {
+	guard := enumguard.New(enumguard.Config{
+		Window:     time.Minute,
+		SlowAfter:  1,
+		Delay:      20 * time.Millisecond,
+		BlockAfter: 3,
+		BlockFor:   time.Minute,
+	})
+
+	h := mwEnumGuard.New(slogdiscard.NewDiscardLogger(), guard)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/known" {
+			w.WriteHeader(http.StatusFound)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+
+	do := func(path string) (int, time.Duration) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		rr := httptest.NewRecorder()
+
+		start := time.Now()
+		h.ServeHTTP(rr, req)
+		return rr.Code, time.Since(start)
+	}
+
+	code, _ := do("/known")
+	assert.Equal(t, http.StatusFound, code)
+
+	code, _ = do("/aaa")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// После первого промаха ответы замедляются, в том числе на существующие ссылки
+	code, took := do("/known")
+	assert.Equal(t, http.StatusFound, code)
+	assert.GreaterOrEqual(t, took, 20*time.Millisecond)
+
+	do("/bbb")
+	do("/ccc") // третий промах - блокировка
+
+	req := httptest.NewRequest(http.MethodGet, "/known", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "60", rr.Header().Get("Retry-After"))
+}
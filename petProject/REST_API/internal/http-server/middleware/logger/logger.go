@@ -1,41 +1,79 @@
 package logger
 
 import (
+	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"log/slog"
+	"main.go/internal/lib/aliassig"
+	"main.go/internal/lib/clientip"
+	"main.go/internal/lib/logger/handlers/slogredact"
+	"main.go/internal/lib/logger/sl"
 	"net/http"
+	"strings"
 	"time"
 )
 
+// New writes an access log line per request and puts a request-scoped logger into the context.
+// Обработчики берут его через sl.FromContext, так request_id и trace_id попадают во все их логи.
+// Ставится после RequestID, clientip и tracing.
 func New(log *slog.Logger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
-		log = log.With( // Создаем копию логера
+		log.With( // Создаем копию логера
 			slog.String("component", "middleware/logger"), // component - подсказка
-		)
-
-		log.Info("logger middleware enabled")
+		).Info("logger middleware enabled")
 
 		fn := func(w http.ResponseWriter, r *http.Request) {
-			entry := log.With(
-				slog.String("method", r.Method),
-				slog.String("path", r.URL.Path),
-				slog.String("remote_addr", r.RemoteAddr),
-				slog.String("remote_addr", r.UserAgent()),
+			reqLog := log.With(
 				slog.String("request_id", middleware.GetReqID(r.Context())),
+				sl.TraceID(r.Context()),
 			)
 			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
 
 			t1 := time.Now()
 			defer func() { // Вывод лога
-				entry.Info("request completed",
-					slog.Int("status", ww.Status()),
+				// Шаблон известен только после роутинга
+				route := ""
+				if rctx := chi.RouteContext(r.Context()); rctx != nil {
+					route = rctx.RoutePattern()
+				}
+
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK // обработчик ничего не записал
+				}
+
+				reqLog.Info("request completed",
+					slog.String("component", "middleware/logger"),
+					slog.String("method", r.Method),
+					slog.String("path", maskSignature(r.URL.Path)),
+					slog.String("route", route),
+					slog.String("client_ip", clientip.FromRequest(r)),
+					slog.String("user_agent", r.UserAgent()),
+					slog.Int("status", status),
 					slog.Int("bytes", ww.BytesWritten()),
-					slog.String("duration", time.Since(t1).String()),
+					slog.Float64("duration_ms", float64(time.Since(t1).Microseconds())/1000),
 				)
 			}()
 
-			next.ServeHTTP(ww, r)
+			next.ServeHTTP(ww, r.WithContext(sl.WithLogger(r.Context(), reqLog)))
 		}
 		return http.HandlerFunc(fn)
 	}
 }
+
+// maskSignature hides the signature of signed aliases (abc~sig -> abc~***):
+// с подписью приватную ссылку может открыть любой, кто читает логи.
+func maskSignature(path string) string {
+	if !strings.Contains(path, aliassig.Separator) {
+		return path
+	}
+
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if alias, _, signed := aliassig.Split(seg); signed {
+			segments[i] = alias + aliassig.Separator + slogredact.Mask
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,77 @@
+package logger_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	mwLogger "main.go/internal/http-server/middleware/logger"
+	"main.go/internal/lib/logger/sl"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(mwLogger.New(log))
+	r.Get("/{alias}", func(w http.ResponseWriter, r *http.Request) {
+		sl.FromContext(r.Context(), nil).Info("handler log")
+		w.WriteHeader(http.StatusFound)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+	req.RemoteAddr = "203.0.113.7:5000"
+	req.Header.Set("User-Agent", "test-agent")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3) // включение middleware, лог обработчика, лог запроса
+
+	var handler, access map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &handler))
+	require.NoError(t, json.Unmarshal([]byte(lines[2]), &access))
+
+	assert.Equal(t, "handler log", handler["msg"])
+	assert.NotEmpty(t, handler["request_id"])
+	assert.Equal(t, handler["request_id"], access["request_id"])
+
+	assert.Equal(t, "request completed", access["msg"])
+	assert.Equal(t, "/{alias}", access["route"])
+	assert.Equal(t, "/abc", access["path"])
+	assert.Equal(t, "203.0.113.7", access["client_ip"])
+	assert.Equal(t, "test-agent", access["user_agent"])
+	assert.Equal(t, float64(http.StatusFound), access["status"])
+	assert.IsType(t, float64(0), access["duration_ms"])
+	assert.NotContains(t, access, "remote_addr")
+}
+
+func TestLogger_SignedAlias(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	r := chi.NewRouter()
+	r.Use(mwLogger.New(log))
+	r.Get("/{alias}/*", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusFound)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/abc~Xy9kQ2mP/docs", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var access map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &access))
+
+	assert.Equal(t, "/abc~***/docs", access["path"])
+	assert.NotContains(t, buf.String(), "Xy9kQ2mP")
+}
@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Observer records finished HTTP requests.
+type Observer interface {
+	ObserveHTTP(route, method string, status int, d time.Duration)
+}
+
+// New counts requests and their latency per route pattern and status.
+func New(observer Observer) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			start := time.Now()
+			next.ServeHTTP(ww, r)
+
+			// Шаблон известен только после роутинга. Неизвестные пути - одной серией
+			route := "unmatched"
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK // обработчик ничего не записал
+			}
+
+			observer.ObserveHTTP(route, r.Method, status, time.Since(start))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
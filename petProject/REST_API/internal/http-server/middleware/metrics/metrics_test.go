@@ -0,0 +1,49 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	mwMetrics "main.go/internal/http-server/middleware/metrics"
+)
+
+type observed struct {
+	route, method string
+	status        int
+}
+
+type recorder []observed
+
+func (r *recorder) ObserveHTTP(route, method string, status int, _ time.Duration) {
+	*r = append(*r, observed{route, method, status})
+}
+
+func TestMetrics(t *testing.T) {
+	var rec recorder
+
+	r := chi.NewRouter()
+	r.Use(mwMetrics.New(&rec))
+	r.Get("/{alias}", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://example.com", http.StatusFound)
+	})
+	r.Route("/url", func(r chi.Router) {
+		r.Get("/{alias}/stats", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("{}"))
+		})
+	})
+
+	for _, path := range []string{"/abc", "/def", "/url/abc/stats", "/a/b/c"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, recorder{
+		{"/{alias}", http.MethodGet, http.StatusFound},
+		{"/{alias}", http.MethodGet, http.StatusFound},
+		{"/url/{alias}/stats", http.MethodGet, http.StatusOK},
+		{"unmatched", http.MethodGet, http.StatusNotFound},
+	}, rec)
+}
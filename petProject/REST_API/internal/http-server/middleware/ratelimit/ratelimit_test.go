@@ -0,0 +1,83 @@
+package ratelimit_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	mwRateLimit "main.go/internal/http-server/middleware/ratelimit"
+	"main.go/internal/lib/auth"
+	"main.go/internal/lib/logger/handlers/slogdiscard"
+	"main.go/internal/lib/ratelimit"
+)
+
+type failingStore struct{}
+
+func (failingStore) Take(string, ratelimit.Limit, time.Time) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("store is down")
+}
+
+func TestRateLimit(t *testing.T) {
+	limit := ratelimit.Limit{Requests: 2, Per: time.Minute}
+	h := mwRateLimit.New(slogdiscard.NewDiscardLogger(), "redirect", ratelimit.NewMemoryStore(), limit, mwRateLimit.ByClientIP)(http.NotFoundHandler())
+
+	do := func(addr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+		req.RemoteAddr = addr
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do("10.0.0.1:1000")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "2", rr.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", rr.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "2;w=60", rr.Header().Get("RateLimit-Policy"))
+
+	do("10.0.0.1:1001") // другой порт - тот же клиент
+	rr = do("10.0.0.1:1002")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "30", rr.Header().Get("Retry-After"))
+	assert.Equal(t, "0", rr.Header().Get("RateLimit-Remaining"))
+	assert.JSONEq(t, `{"status": "Error", "error": "too many requests"}`, rr.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, do("10.0.0.2:1000").Code)
+}
+
+func TestRateLimit_ByPrincipal(t *testing.T) {
+	limit := ratelimit.Limit{Requests: 1, Per: time.Minute}
+	h := mwRateLimit.New(slogdiscard.NewDiscardLogger(), "create", ratelimit.NewMemoryStore(), limit, mwRateLimit.ByPrincipal)(http.NotFoundHandler())
+
+	do := func(subject string) int {
+		req := httptest.NewRequest(http.MethodPost, "/url", nil)
+		req = req.WithContext(auth.WithPrincipal(req.Context(), auth.Principal{Subject: subject}))
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// С одного IP, но разные ключи
+	assert.Equal(t, http.StatusNotFound, do("key:1"))
+	assert.Equal(t, http.StatusNotFound, do("key:2"))
+	assert.Equal(t, http.StatusTooManyRequests, do("key:1"))
+}
+
+func TestRateLimit_Passthrough(t *testing.T) {
+	log := slogdiscard.NewDiscardLogger()
+
+	for name, h := range map[string]http.Handler{
+		"disabled":     mwRateLimit.New(log, "g", ratelimit.NewMemoryStore(), ratelimit.Limit{}, mwRateLimit.ByClientIP)(http.NotFoundHandler()),
+		"store errors": mwRateLimit.New(log, "g", failingStore{}, ratelimit.Limit{Requests: 1, Per: time.Second}, mwRateLimit.ByClientIP)(http.NotFoundHandler()),
+	} {
+		for i := 0; i < 3; i++ {
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+			assert.Equal(t, http.StatusNotFound, rr.Code, name)
+			assert.Empty(t, rr.Header().Get("RateLimit-Limit"), name)
+		}
+	}
+}
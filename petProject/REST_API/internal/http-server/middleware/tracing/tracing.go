@@ -0,0 +1,62 @@
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"main.go/internal/lib/clientip"
+)
+
+const tracerName = "main.go/internal/http-server/middleware/tracing"
+
+// New starts a server span per request. Входящий traceparent (W3C) делает span дочерним
+// для вызывающего сервиса, span'ы хранилища и trace_id в логах берутся из контекста запроса.
+// Должен стоять после middleware.RequestID.
+func New(tp trace.TracerProvider) func(next http.Handler) http.Handler {
+	tracer := tp.Tracer(tracerName)
+
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+			ctx, span := tracer.Start(ctx, r.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.request.method", r.Method),
+					attribute.String("url.path", r.URL.Path),
+					attribute.String("client.address", clientip.FromRequest(r)),
+					attribute.String("request_id", middleware.GetReqID(r.Context())),
+				),
+			)
+			defer span.End()
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			// Шаблон известен только после роутинга: по нему называем span, а не по пути с alias
+			if rctx := chi.RouteContext(ctx); rctx != nil && rctx.RoutePattern() != "" {
+				span.SetName(r.Method + " " + rctx.RoutePattern())
+				span.SetAttributes(attribute.String("http.route", rctx.RoutePattern()))
+			}
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK // обработчик ничего не записал
+			}
+			span.SetAttributes(attribute.Int("http.response.status_code", status))
+
+			// 4xx - ошибка клиента, а не сервера
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
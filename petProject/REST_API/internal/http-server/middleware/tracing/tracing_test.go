@@ -0,0 +1,63 @@
+package tracing_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	mwTracing "main.go/internal/http-server/middleware/tracing"
+)
+
+func TestTracing(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+
+	var handlerSpan trace.SpanContext
+
+	r := chi.NewRouter()
+	r.Use(mwTracing.New(tp))
+	r.Get("/{alias}", func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = trace.SpanContextFromContext(r.Context())
+		http.Redirect(w, r, "https://example.com", http.StatusFound)
+	})
+	r.Get("/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	spans := rec.Ended()
+	require.Len(t, spans, 2)
+
+	redirect := spans[0]
+	assert.Equal(t, "GET /{alias}", redirect.Name())
+	assert.Equal(t, trace.SpanKindServer, redirect.SpanKind())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", redirect.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", redirect.Parent().SpanID().String())
+	assert.True(t, redirect.Parent().IsRemote())
+	assert.Equal(t, redirect.SpanContext(), handlerSpan, "handler must see the request span")
+	assert.Contains(t, redirect.Attributes(), attribute.String("http.route", "/{alias}"))
+	assert.Contains(t, redirect.Attributes(), attribute.Int("http.response.status_code", http.StatusFound))
+	assert.Equal(t, codes.Unset, redirect.Status().Code)
+
+	fail := spans[1]
+	assert.Equal(t, "GET /fail", fail.Name())
+	assert.False(t, fail.Parent().IsValid(), "without traceparent the span is a root")
+	assert.Equal(t, codes.Error, fail.Status().Code)
+}
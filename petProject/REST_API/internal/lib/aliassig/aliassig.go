@@ -0,0 +1,61 @@
+// Подписанные alias для приватных ссылок: "abc123~Xy9kQ2mP", подпись - HMAC-SHA256 от alias
+
+package aliassig
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+)
+
+// Separator отделяет подпись от alias. Его нельзя использовать в своих alias.
+const Separator = "~"
+
+// DefaultLength is the signature length in base64url characters (48 бит).
+const DefaultLength = 8
+
+// Signer signs and verifies aliases with a secret. Safe for concurrent use.
+type Signer struct {
+	secret []byte
+	length int
+}
+
+// New returns a signer. length <= 0 - DefaultLength, больше 43 не бывает.
+func New(secret []byte, length int) *Signer {
+	if length <= 0 {
+		length = DefaultLength
+	}
+	if length > 43 {
+		length = 43
+	}
+
+	return &Signer{secret: secret, length: length}
+}
+
+// Sign returns the alias with its signature appended.
+func (s *Signer) Sign(alias string) string {
+	return alias + Separator + s.sig(alias)
+}
+
+// Verify reports whether sig is the signature of alias.
+func (s *Signer) Verify(alias, sig string) bool {
+	return hmac.Equal([]byte(sig), []byte(s.sig(alias)))
+}
+
+func (s *Signer) sig(alias string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(alias))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))[:s.length]
+}
+
+// Split separates the alias and the signature. signed - был ли разделитель.
+func Split(s string) (alias, sig string, signed bool) {
+	i := strings.LastIndex(s, Separator)
+	if i < 0 {
+		return s, "", false
+	}
+
+	return s[:i], s[i+len(Separator):], true
+}
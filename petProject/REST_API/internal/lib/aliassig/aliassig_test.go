@@ -0,0 +1,33 @@
+package aliassig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSigner(t *testing.T) {
+	s := New([]byte("secret"), 0)
+
+	signed := s.Sign("abc123")
+	alias, sig, ok := Split(signed)
+	assert.True(t, ok)
+	assert.Equal(t, "abc123", alias)
+	assert.Len(t, sig, DefaultLength)
+	assert.True(t, s.Verify(alias, sig))
+
+	assert.False(t, s.Verify("abc124", sig))
+	assert.False(t, s.Verify(alias, ""))
+	assert.False(t, New([]byte("other"), 0).Verify(alias, sig))
+
+	// Подпись стабильна
+	assert.Equal(t, signed, s.Sign("abc123"))
+	assert.Len(t, New([]byte("secret"), 100).Sign("a"), 2+43)
+}
+
+func TestSplit(t *testing.T) {
+	alias, sig, ok := Split("plain")
+	assert.Equal(t, "plain", alias)
+	assert.Empty(t, sig)
+	assert.False(t, ok)
+}
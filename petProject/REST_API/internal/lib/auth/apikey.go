@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"main.go/internal/storage"
+)
+
+const (
+	// APIKeyHeader - альтернатива "Authorization: Bearer <key>"
+	APIKeyHeader = "X-API-Key"
+
+	keyPrefix  = "sk_" // по префиксу отличаем наши ключи от других bearer-токенов
+	prefixLen  = 8     // столько символов ключа храним открыто, чтобы ключ можно было узнать в списке
+	touchEvery = time.Minute
+)
+
+// GenerateKey returns a new random API key, its displayable prefix and hash.
+// Сам ключ показывается один раз, в базе остается только хеш.
+func GenerateKey() (key, prefix, hash string, err error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+
+	key = keyPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	return key, key[:len(keyPrefix)+prefixLen], HashKey(key), nil
+}
+
+// HashKey returns the stored form of the key. Ключ случайный и длинный,
+// поэтому достаточно SHA-256, медленный bcrypt на каждый запрос не нужен.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// KeyFromRequest extracts an API key from "Authorization: Bearer" or X-API-Key.
+func KeyFromRequest(r *http.Request) string {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return key
+	}
+
+	if token, ok := BearerToken(r); ok && strings.HasPrefix(token, keyPrefix) {
+		return token
+	}
+
+	return ""
+}
+
+// BearerToken returns the token from the Authorization header.
+func BearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+
+	return token, token != ""
+}
+
+// APIKeyStore is an interface for looking up issued API keys.
+type APIKeyStore interface {
+	GetAPIKeyByHash(ctx context.Context, hash string) (storage.APIKey, error)
+	TouchAPIKey(ctx context.Context, id int64, at time.Time) error
+	GetUser(ctx context.Context, id int64) (storage.User, error)
+}
+
+// APIKeys authenticates requests by API key.
+type APIKeys struct {
+	store     APIKeyStore
+	bootstrap string // хеш ключа из конфига с правами admin, чтобы выпустить первые ключи
+	now       func() time.Time
+}
+
+// NewAPIKeys creates the API key authenticator. bootstrapKey может быть пустым.
+func NewAPIKeys(store APIKeyStore, bootstrapKey string) *APIKeys {
+	a := &APIKeys{store: store, now: time.Now}
+	if bootstrapKey != "" {
+		a.bootstrap = HashKey(bootstrapKey)
+	}
+
+	return a
+}
+
+func (a *APIKeys) Authenticate(r *http.Request) (Principal, error) {
+	key := KeyFromRequest(r)
+	if key == "" {
+		return Principal{}, ErrNoCredentials
+	}
+
+	hash := HashKey(key)
+
+	if a.bootstrap != "" && subtle.ConstantTimeCompare([]byte(hash), []byte(a.bootstrap)) == 1 {
+		return Principal{Subject: "key:bootstrap", Name: "bootstrap", Role: RoleAdmin, Scopes: []string{ScopeAdmin}}, nil
+	}
+
+	k, err := a.store.GetAPIKeyByHash(r.Context(), hash)
+	if errors.Is(err, storage.ErrAPIKeyNotFound) {
+		return Principal{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return Principal{}, err
+	}
+
+	now := a.now()
+	if !k.RevokedAt.IsZero() {
+		return Principal{}, ErrInvalidCredentials
+	}
+	if !k.ExpiresAt.IsZero() && !now.Before(k.ExpiresAt) {
+		return Principal{}, ErrExpired
+	}
+
+	// Время последнего использования пишем не чаще раза в минуту, чтобы не писать в базу на каждый запрос
+	if now.Sub(k.LastUsedAt) >= touchEvery {
+		if err := a.store.TouchAPIKey(r.Context(), k.ID, now); err != nil {
+			return Principal{}, err
+		}
+	}
+
+	// Роль берем у владельца ключа, поэтому смена роли действует сразу на все его ключи
+	role := RoleForScopes(k.Scopes)
+	if k.UserID != 0 {
+		user, err := a.store.GetUser(r.Context(), k.UserID)
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return Principal{}, ErrInvalidCredentials
+		}
+		if err != nil {
+			return Principal{}, err
+		}
+		role = user.Role
+	}
+
+	return Principal{
+		Subject: "key:" + strconv.FormatInt(k.ID, 10),
+		Name:    k.Name,
+		UserID:  k.UserID,
+		Role:    role,
+		Scopes:  k.Scopes,
+	}, nil
+}
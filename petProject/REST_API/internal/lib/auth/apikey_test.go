@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"main.go/internal/storage"
+)
+
+type fakeKeyStore struct {
+	keys    map[string]storage.APIKey
+	touched []int64
+}
+
+func (s *fakeKeyStore) GetAPIKeyByHash(_ context.Context, hash string) (storage.APIKey, error) {
+	k, ok := s.keys[hash]
+	if !ok {
+		return storage.APIKey{}, storage.ErrAPIKeyNotFound
+	}
+	return k, nil
+}
+
+func (s *fakeKeyStore) GetUser(_ context.Context, id int64) (storage.User, error) {
+	if id == 9 {
+		return storage.User{ID: 9, Name: "alice", Role: RoleViewer}, nil
+	}
+	return storage.User{}, storage.ErrUserNotFound
+}
+
+func (s *fakeKeyStore) TouchAPIKey(_ context.Context, id int64, _ time.Time) error {
+	s.touched = append(s.touched, id)
+	return nil
+}
+
+func TestGenerateKey(t *testing.T) {
+	key, prefix, hash, err := GenerateKey()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(key, "sk_"))
+	assert.True(t, strings.HasPrefix(key, prefix))
+	assert.Len(t, prefix, 11)
+	assert.Equal(t, HashKey(key), hash)
+
+	other, _, _, err := GenerateKey()
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+}
+
+func TestAPIKeys_Authenticate(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	store := &fakeKeyStore{keys: map[string]storage.APIKey{
+		HashKey("sk_valid"):   {ID: 1, UserID: 9, Name: "ci", Scopes: []string{ScopeRead}},
+		HashKey("sk_recent"):  {ID: 2, Scopes: []string{ScopeRead}, LastUsedAt: now.Add(-time.Second)},
+		HashKey("sk_revoked"): {ID: 3, RevokedAt: now.Add(-time.Hour)},
+		HashKey("sk_expired"): {ID: 4, ExpiresAt: now},
+		HashKey("sk_orphan"):  {ID: 5, UserID: 10},
+	}}
+
+	a := NewAPIKeys(store, "sk_bootstrap")
+	a.now = func() time.Time { return now }
+
+	tests := []struct {
+		name    string
+		header  string
+		value   string
+		subject string
+		role    string
+		err     error
+	}{
+		{name: "no credentials", err: ErrNoCredentials},
+		{name: "foreign bearer token", header: "Authorization", value: "Bearer eyJhbGciOi", err: ErrNoCredentials},
+		{name: "bearer", header: "Authorization", value: "Bearer sk_valid", subject: "key:1", role: RoleViewer},
+		{name: "header", header: APIKeyHeader, value: "sk_valid", subject: "key:1", role: RoleViewer},
+		{name: "recently used", header: APIKeyHeader, value: "sk_recent", subject: "key:2", role: RoleEditor},
+		{name: "deleted user", header: APIKeyHeader, value: "sk_orphan", err: ErrInvalidCredentials},
+		{name: "unknown", header: APIKeyHeader, value: "sk_unknown", err: ErrInvalidCredentials},
+		{name: "revoked", header: APIKeyHeader, value: "sk_revoked", err: ErrInvalidCredentials},
+		{name: "expired", header: APIKeyHeader, value: "sk_expired", err: ErrExpired},
+		{name: "bootstrap", header: "Authorization", value: "bearer sk_bootstrap", subject: "key:bootstrap", role: RoleAdmin},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/url", nil)
+			if tt.header != "" {
+				r.Header.Set(tt.header, tt.value)
+			}
+
+			p, err := a.Authenticate(r)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.subject, p.Subject)
+			assert.Equal(t, tt.role, p.Role)
+		})
+	}
+
+	// last_used_at обновляется только у давно не использованного ключа
+	assert.Equal(t, []int64{1, 1, 5}, store.touched)
+}
+
+func TestPrincipal_Has(t *testing.T) {
+	p := Principal{Scopes: []string{ScopeRead}}
+	assert.True(t, p.Has(ScopeRead))
+	assert.False(t, p.Has(ScopeCreate))
+
+	admin := Principal{Scopes: []string{ScopeAdmin}}
+	assert.True(t, admin.Has(ScopeDelete))
+}
+
+func TestPrincipal_CanManage(t *testing.T) {
+	user := Principal{UserID: 5, Role: RoleEditor, Scopes: []string{ScopeRead}}
+	assert.True(t, user.CanManage(5, ""))
+	assert.False(t, user.CanManage(6, ""))
+	assert.False(t, user.CanManage(0, ""))
+
+	// Ключ пользователя не получает ссылки без владельца, даже созданные им самим
+	assert.False(t, Principal{Subject: "key:1", UserID: 5}.CanManage(0, "key:1"))
+
+	// Ключ без пользователя управляет только ссылками без владельца, которые создал сам
+	anon := Principal{Subject: "key:1", Scopes: []string{ScopeRead}}
+	assert.True(t, anon.CanManage(0, "key:1"))
+	assert.False(t, anon.CanManage(0, "key:2"))
+	assert.False(t, anon.CanManage(0, ""))
+	assert.False(t, anon.CanManage(5, "key:1"))
+	assert.False(t, Principal{Scopes: []string{ScopeRead}}.CanManage(0, ""))
+
+	assert.True(t, Principal{Role: RoleAdmin, Scopes: []string{ScopeAdmin}}.CanManage(6, ""))
+
+	// Админский ключ у пользователя без роли admin прав администратора не дает
+	assert.False(t, Principal{UserID: 5, Role: RoleEditor, Scopes: []string{ScopeAdmin}}.CanManage(6, ""))
+}
@@ -0,0 +1,82 @@
+// Аутентифицированный клиент API и его права
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+)
+
+// Scopes of API access. ScopeAdmin включает все остальные.
+const (
+	ScopeCreate = "create"
+	ScopeRead   = "read"
+	ScopeDelete = "delete"
+	ScopeAdmin  = "admin"
+)
+
+// Scopes lists all known scopes.
+var Scopes = []string{ScopeCreate, ScopeRead, ScopeDelete, ScopeAdmin}
+
+var (
+	ErrNoCredentials      = errors.New("no credentials") // запрос без данных для этого способа входа
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrExpired            = errors.New("credentials expired")
+)
+
+// Principal is the authenticated API client.
+type Principal struct {
+	Subject string // "key:12" для API-ключа
+	Name    string // Для логов: имя ключа
+	UserID  int64  // Пользователь, от имени которого идет запрос. 0 - без пользователя (bootstrap-ключ)
+	Role    string // viewer, editor или admin
+	Scopes  []string
+}
+
+// CanManage reports whether the principal may view stats of, change or delete
+// a link owned by ownerID and created by the subject createdBy.
+// Администратор может все, остальные - только свои ссылки. Ключ без пользователя
+// управляет ссылками без владельца, которые создал сам.
+func (p Principal) CanManage(ownerID int64, createdBy string) bool {
+	if p.IsAdmin() {
+		return true
+	}
+
+	if p.UserID == 0 {
+		return ownerID == 0 && p.Subject != "" && p.Subject == createdBy
+	}
+
+	return p.UserID == ownerID
+}
+
+// Has reports whether the principal is granted the scope.
+func (p Principal) Has(scope string) bool {
+	return slices.Contains(p.Scopes, scope) || slices.Contains(p.Scopes, ScopeAdmin)
+}
+
+// Authenticator recognizes the client by request credentials.
+// Если в запросе нет подходящих данных, возвращает ErrNoCredentials,
+// и middleware пробует следующий способ.
+type Authenticator interface {
+	Authenticate(r *http.Request) (Principal, error)
+}
+
+type ctxKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the principal.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, ctxKey{}, p)
+}
+
+// FromContext returns the principal stored by the auth middleware.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(ctxKey{}).(Principal)
+	return p, ok
+}
+
+// ValidScope reports whether s is a known scope.
+func ValidScope(s string) bool {
+	return slices.Contains(Scopes, s)
+}
@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+)
+
+// jwk is one key of a JSON Web Key Set (RFC 7517). Поддерживаем oct (HS256), RSA (RS256) и EC P-256 (ES256).
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type verifyKey struct {
+	kid string
+	alg string // алгоритм, для которого годится ключ
+	key any    // []byte, *rsa.PublicKey или *ecdsa.PublicKey
+}
+
+type keySet struct {
+	keys    []verifyKey
+	modTime time.Time
+}
+
+// loadJWKS reads and parses the key set file.
+func loadJWKS(path string) (*keySet, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("parse jwks: %w", err)
+	}
+
+	ks := &keySet{modTime: info.ModTime()}
+	for i, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		vk, err := k.verifyKey()
+		if err != nil {
+			return nil, fmt.Errorf("jwks key %d (kid %q): %w", i, k.Kid, err)
+		}
+		ks.keys = append(ks.keys, vk)
+	}
+
+	if len(ks.keys) == 0 {
+		return nil, errors.New("jwks has no signing keys")
+	}
+
+	return ks, nil
+}
+
+func (k jwk) verifyKey() (verifyKey, error) {
+	vk := verifyKey{kid: k.Kid}
+
+	switch k.Kty {
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil || len(secret) == 0 {
+			return vk, errors.New("invalid k")
+		}
+		vk.alg, vk.key = "HS256", secret
+
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return vk, errors.New("invalid n")
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil || !e.IsInt64() {
+			return vk, errors.New("invalid e")
+		}
+		vk.alg, vk.key = "RS256", &rsa.PublicKey{N: n, E: int(e.Int64())}
+
+	case "EC":
+		if k.Crv != "P-256" {
+			return vk, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, errX := decodeBigInt(k.X)
+		y, errY := decodeBigInt(k.Y)
+		if errX != nil || errY != nil {
+			return vk, errors.New("invalid x or y")
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		if !pub.Curve.IsOnCurve(x, y) {
+			return vk, errors.New("point is not on curve")
+		}
+		vk.alg, vk.key = "ES256", pub
+
+	default:
+		return vk, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+
+	if k.Alg != "" && k.Alg != vk.alg {
+		return vk, fmt.Errorf("unsupported alg %q for key type %s", k.Alg, k.Kty)
+	}
+
+	return vk, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid base64url integer")
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
+
+// find returns the key for the token's kid and alg.
+// Алгоритм всегда берется из ключа, а не из токена: иначе RSA-ключ можно подсунуть как HMAC-секрет.
+func (ks *keySet) find(kid, alg string) (any, error) {
+	var found *verifyKey
+	for i := range ks.keys {
+		k := &ks.keys[i]
+		if k.alg != alg || (kid != "" && k.kid != kid) {
+			continue
+		}
+		if found != nil {
+			return nil, errors.New("several keys match, token must have kid")
+		}
+		found = k
+	}
+
+	if found == nil {
+		return nil, fmt.Errorf("no key for kid %q and alg %s", kid, alg)
+	}
+
+	return found.key, nil
+}
@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"main.go/internal/lib/logger/sl"
+)
+
+// JWTConfig configures validation of tokens issued by an external SSO.
+type JWTConfig struct {
+	JWKSPath    string              // локальный файл с ключами (JWKS), перечитывается при изменении
+	Issuer      string              // пустой - не проверяется
+	Audience    string              // пустой - не проверяется
+	ClockSkew   time.Duration       // допустимое расхождение часов для exp, nbf, iat
+	ScopeClaim  string              // claim со scopes: строка через пробел или массив, по умолчанию "scope"
+	ScopeMap    map[string][]string // значение claim -> наши scopes. Известные scopes ("read" и т.п.) можно не указывать
+	UserIDClaim string              // claim с id пользователя в нашей базе, по умолчанию "uid"
+	RoleClaim   string              // claim с ролью (viewer, editor, admin), по умолчанию "role"
+}
+
+// JWT authenticates requests by bearer JWT (HS256, RS256, ES256).
+type JWT struct {
+	cfg  JWTConfig
+	keys atomic.Pointer[keySet]
+	now  func() time.Time
+}
+
+// NewJWT creates the JWT authenticator and loads the key set.
+func NewJWT(cfg JWTConfig) (*JWT, error) {
+	const op = "lib.auth.NewJWT"
+
+	if cfg.ScopeClaim == "" {
+		cfg.ScopeClaim = "scope"
+	}
+	if cfg.UserIDClaim == "" {
+		cfg.UserIDClaim = "uid"
+	}
+	if cfg.RoleClaim == "" {
+		cfg.RoleClaim = "role"
+	}
+
+	j := &JWT{cfg: cfg, now: time.Now}
+	if err := j.Reload(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return j, nil
+}
+
+// Reload re-reads the key set file. При ошибке остаются старые ключи.
+func (j *JWT) Reload() error {
+	ks, err := loadJWKS(j.cfg.JWKSPath)
+	if err != nil {
+		return err
+	}
+
+	j.keys.Store(ks)
+
+	return nil
+}
+
+// Watch polls the key set file and reloads it when it changes, until ctx is done.
+// Так ротация ключей на стороне SSO не требует рестарта.
+func (j *JWT) Watch(ctx context.Context, log *slog.Logger, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		info, err := os.Stat(j.cfg.JWKSPath)
+		if err != nil {
+			log.Error("failed to stat jwks", sl.Err(err))
+			continue
+		}
+		if info.ModTime().Equal(j.keys.Load().modTime) {
+			continue
+		}
+
+		if err := j.Reload(); err != nil {
+			log.Error("failed to reload jwks", sl.Err(err))
+			continue
+		}
+
+		log.Info("jwks reloaded", slog.Int("keys", len(j.keys.Load().keys)))
+	}
+}
+
+func (j *JWT) Authenticate(r *http.Request) (Principal, error) {
+	token, ok := BearerToken(r)
+	// API-ключи тоже приходят как Bearer, их проверяет другой Authenticator
+	if !ok || strings.HasPrefix(token, keyPrefix) {
+		return Principal{}, ErrNoCredentials
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"HS256", "RS256", "ES256"}),
+		jwt.WithLeeway(j.cfg.ClockSkew),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithTimeFunc(j.now),
+	}
+	if j.cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(j.cfg.Issuer))
+	}
+	if j.cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(j.cfg.Audience))
+	}
+
+	ks := j.keys.Load()
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.NewParser(opts...).ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return ks.find(kid, t.Method.Alg())
+	})
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return Principal{}, ErrExpired
+	}
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	sub, _ := claims.GetSubject()
+	if sub == "" {
+		return Principal{}, fmt.Errorf("%w: token has no sub", ErrInvalidCredentials)
+	}
+
+	scopes := j.scopes(claims[j.cfg.ScopeClaim])
+
+	role, _ := claims[j.cfg.RoleClaim].(string)
+	if !ValidRole(role) {
+		role = RoleForScopes(scopes)
+	}
+
+	return Principal{
+		Subject: "jwt:" + sub,
+		Name:    sub,
+		UserID:  claimInt(claims[j.cfg.UserIDClaim]),
+		Role:    role,
+		Scopes:  scopes,
+	}, nil
+}
+
+// scopes maps the claim value to our scopes.
+func (j *JWT) scopes(claim any) []string {
+	var values []string
+	switch v := claim.(type) {
+	case string:
+		values = strings.Fields(v)
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	var scopes []string
+	add := func(s string) {
+		if !slices.Contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+
+	for _, v := range values {
+		if mapped, ok := j.cfg.ScopeMap[v]; ok {
+			for _, s := range mapped {
+				add(s)
+			}
+			continue
+		}
+		if ValidScope(v) {
+			add(v)
+		}
+	}
+
+	return scopes
+}
+
+// claimInt accepts numbers and numeric strings.
+func claimInt(v any) int64 {
+	switch n := v.(type) {
+	case float64:
+		return int64(n)
+	case string:
+		id, _ := strconv.ParseInt(n, 10, 64)
+		return id
+	}
+
+	return 0
+}
@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func writeJWKS(t *testing.T, path string, keys ...map[string]string) {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]any{"keys": keys})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+}
+
+func rsaJWK(kid string, k *rsa.PrivateKey) map[string]string {
+	return map[string]string{"kty": "RSA", "kid": kid, "n": b64(k.N.Bytes()), "e": b64(big.NewInt(int64(k.E)).Bytes())}
+}
+
+func bearer(token string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/url", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+func TestJWT_Authenticate(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	secret := []byte("0123456789abcdef0123456789abcdef")
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, path,
+		rsaJWK("rsa-1", rsaKey),
+		map[string]string{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": b64(ecKey.X.FillBytes(make([]byte, 32))), "y": b64(ecKey.Y.FillBytes(make([]byte, 32)))},
+		map[string]string{"kty": "oct", "kid": "hs-1", "k": b64(secret)},
+	)
+
+	j, err := NewJWT(JWTConfig{
+		JWKSPath:  path,
+		Issuer:    "https://sso.example.com",
+		Audience:  "url-shortener",
+		ClockSkew: 30 * time.Second,
+		ScopeMap:  map[string][]string{"links:write": {ScopeCreate, ScopeRead}},
+	})
+	require.NoError(t, err)
+	j.now = func() time.Time { return now }
+
+	claims := func(mod func(jwt.MapClaims)) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"sub":   "alice",
+			"iss":   "https://sso.example.com",
+			"aud":   "url-shortener",
+			"exp":   now.Add(time.Hour).Unix(),
+			"iat":   now.Unix(),
+			"scope": "links:write delete unknown",
+			"uid":   7,
+			"role":  "viewer",
+		}
+		if mod != nil {
+			mod(c)
+		}
+		return c
+	}
+	sign := func(method jwt.SigningMethod, kid string, key any, c jwt.MapClaims) string {
+		tok := jwt.NewWithClaims(method, c)
+		tok.Header["kid"] = kid
+		s, err := tok.SignedString(key)
+		require.NoError(t, err)
+		return s
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{name: "RS256", token: sign(jwt.SigningMethodRS256, "rsa-1", rsaKey, claims(nil))},
+		{name: "ES256", token: sign(jwt.SigningMethodES256, "ec-1", ecKey, claims(nil))},
+		{name: "HS256", token: sign(jwt.SigningMethodHS256, "hs-1", secret, claims(nil))},
+		{
+			name:  "Expired within skew",
+			token: sign(jwt.SigningMethodRS256, "rsa-1", rsaKey, claims(func(c jwt.MapClaims) { c["exp"] = now.Add(-10 * time.Second).Unix() })),
+		},
+		{
+			name:  "Expired",
+			token: sign(jwt.SigningMethodRS256, "rsa-1", rsaKey, claims(func(c jwt.MapClaims) { c["exp"] = now.Add(-time.Minute).Unix() })),
+			err:   ErrExpired,
+		},
+		{
+			name:  "Wrong issuer",
+			token: sign(jwt.SigningMethodRS256, "rsa-1", rsaKey, claims(func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" })),
+			err:   ErrInvalidCredentials,
+		},
+		{
+			name:  "Wrong audience",
+			token: sign(jwt.SigningMethodRS256, "rsa-1", rsaKey, claims(func(c jwt.MapClaims) { c["aud"] = "other" })),
+			err:   ErrInvalidCredentials,
+		},
+		{
+			name:  "No exp",
+			token: sign(jwt.SigningMethodRS256, "rsa-1", rsaKey, claims(func(c jwt.MapClaims) { delete(c, "exp") })),
+			err:   ErrInvalidCredentials,
+		},
+		{
+			// RSA-ключ нельзя использовать как HMAC-секрет
+			name:  "Algorithm confusion",
+			token: sign(jwt.SigningMethodHS256, "rsa-1", rsaKey.PublicKey.N.Bytes(), claims(nil)),
+			err:   ErrInvalidCredentials,
+		},
+		{
+			name:  "Unsigned",
+			token: sign(jwt.SigningMethodNone, "rsa-1", jwt.UnsafeAllowNoneSignatureType, claims(nil)),
+			err:   ErrInvalidCredentials,
+		},
+		{name: "Garbage", token: "not.a.jwt", err: ErrInvalidCredentials},
+		{name: "API key", token: "sk_abc", err: ErrNoCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := j.Authenticate(bearer(tt.token))
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, "jwt:alice", p.Subject)
+			assert.Equal(t, int64(7), p.UserID)
+			assert.Equal(t, RoleViewer, p.Role)
+			assert.Equal(t, []string{ScopeCreate, ScopeRead, ScopeDelete}, p.Scopes)
+		})
+	}
+}
+
+func TestJWT_Reload(t *testing.T) {
+	now := time.Now()
+
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, path, rsaJWK("old", oldKey))
+
+	j, err := NewJWT(JWTConfig{JWKSPath: path})
+	require.NoError(t, err)
+
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"sub": "alice", "exp": now.Add(time.Hour).Unix(), "scope": []string{"read"}})
+	tok.Header["kid"] = "new"
+	signed, err := tok.SignedString(newKey)
+	require.NoError(t, err)
+
+	_, err = j.Authenticate(bearer(signed))
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	// Ротация: SSO добавил новый ключ в файл
+	writeJWKS(t, path, rsaJWK("old", oldKey), rsaJWK("new", newKey))
+	require.NoError(t, j.Reload())
+
+	p, err := j.Authenticate(bearer(signed))
+	require.NoError(t, err)
+	assert.Equal(t, []string{ScopeRead}, p.Scopes)
+
+	// Битый файл не ломает уже загруженные ключи
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o644))
+	assert.Error(t, j.Reload())
+
+	_, err = j.Authenticate(bearer(signed))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,83 @@
+package auth
+
+import "slices"
+
+// Roles of users. Роль ограничивает пользователя, scopes - конкретный ключ или токен:
+// действие разрешено, только если его разрешают и роль, и scopes.
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+// Roles lists all known roles.
+var Roles = []string{RoleViewer, RoleEditor, RoleAdmin}
+
+// Permission is an action on a group of routes.
+type Permission string
+
+const (
+	PermLinksRead       Permission = "links:read"
+	PermLinksCreate     Permission = "links:create" // в том числе правила редиректа
+	PermLinksDelete     Permission = "links:delete"
+	PermLinksReadAll    Permission = "links:read_all" // чужие ссылки
+	PermLinksBulkDelete Permission = "links:bulk_delete"
+	PermLinksExport     Permission = "links:export"
+	PermKeysManage      Permission = "keys:manage"
+	PermUsersManage     Permission = "users:manage"
+	PermConfigReload    Permission = "config:reload"
+	PermLogLevel        Permission = "logs:level" // уровень логов без рестарта
+)
+
+// permissionScope - какой scope ключа нужен для действия
+var permissionScope = map[Permission]string{
+	PermLinksRead:       ScopeRead,
+	PermLinksCreate:     ScopeCreate,
+	PermLinksDelete:     ScopeDelete,
+	PermLinksReadAll:    ScopeAdmin,
+	PermLinksBulkDelete: ScopeAdmin,
+	PermLinksExport:     ScopeAdmin,
+	PermKeysManage:      ScopeAdmin,
+	PermUsersManage:     ScopeAdmin,
+	PermConfigReload:    ScopeAdmin,
+	PermLogLevel:        ScopeAdmin,
+}
+
+var rolePermissions = map[string][]Permission{
+	RoleViewer: {PermLinksRead},
+	RoleEditor: {PermLinksRead, PermLinksCreate, PermLinksDelete},
+	RoleAdmin: {
+		PermLinksRead, PermLinksCreate, PermLinksDelete, PermLinksReadAll, PermLinksBulkDelete,
+		PermLinksExport, PermKeysManage, PermUsersManage, PermConfigReload, PermLogLevel,
+	},
+}
+
+// ValidRole reports whether r is a known role.
+func ValidRole(r string) bool {
+	return slices.Contains(Roles, r)
+}
+
+// RoleForScopes picks a role for a client without a user (ключ без владельца или токен без роли):
+// роль не ограничивает больше, чем уже ограничивают scopes.
+func RoleForScopes(scopes []string) string {
+	if slices.Contains(scopes, ScopeAdmin) {
+		return RoleAdmin
+	}
+
+	return RoleEditor
+}
+
+// Can reports whether the principal's role and scopes both allow the action.
+func (p Principal) Can(perm Permission) bool {
+	scope, ok := permissionScope[perm]
+	if !ok {
+		return false
+	}
+
+	return slices.Contains(rolePermissions[p.Role], perm) && p.Has(scope)
+}
+
+// IsAdmin reports whether the principal has full access.
+func (p Principal) IsAdmin() bool {
+	return p.Role == RoleAdmin && p.Has(ScopeAdmin)
+}
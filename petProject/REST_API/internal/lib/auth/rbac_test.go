@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrincipal_Can(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal Principal
+		allowed   []Permission
+		denied    []Permission
+	}{
+		{
+			name:      "viewer",
+			principal: Principal{Role: RoleViewer, Scopes: []string{ScopeAdmin}},
+			allowed:   []Permission{PermLinksRead},
+			denied:    []Permission{PermLinksCreate, PermLinksDelete, PermKeysManage},
+		},
+		{
+			name:      "editor",
+			principal: Principal{Role: RoleEditor, Scopes: []string{ScopeRead, ScopeCreate, ScopeDelete}},
+			allowed:   []Permission{PermLinksRead, PermLinksCreate, PermLinksDelete},
+			denied:    []Permission{PermLinksReadAll, PermLinksExport, PermUsersManage, PermLogLevel},
+		},
+		{
+			name:      "editor with read-only key",
+			principal: Principal{Role: RoleEditor, Scopes: []string{ScopeRead}},
+			allowed:   []Permission{PermLinksRead},
+			denied:    []Permission{PermLinksCreate, PermLinksDelete},
+		},
+		{
+			name:      "admin",
+			principal: Principal{Role: RoleAdmin, Scopes: []string{ScopeAdmin}},
+			allowed:   []Permission{PermLinksRead, PermLinksBulkDelete, PermLinksExport, PermUsersManage, PermConfigReload, PermLogLevel},
+		},
+		{
+			name:      "admin with read-only key",
+			principal: Principal{Role: RoleAdmin, Scopes: []string{ScopeRead}},
+			allowed:   []Permission{PermLinksRead},
+			denied:    []Permission{PermLinksCreate, PermConfigReload, PermLogLevel},
+		},
+		{
+			name:      "no role",
+			principal: Principal{Scopes: []string{ScopeAdmin}},
+			denied:    []Permission{PermLinksRead},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, perm := range tt.allowed {
+				assert.True(t, tt.principal.Can(perm), perm)
+			}
+			for _, perm := range tt.denied {
+				assert.False(t, tt.principal.Can(perm), perm)
+			}
+		})
+	}
+
+	assert.False(t, Principal{Role: RoleAdmin, Scopes: []string{ScopeAdmin}}.Can("unknown"))
+}
+
+func TestRoleForScopes(t *testing.T) {
+	assert.Equal(t, RoleAdmin, RoleForScopes([]string{ScopeRead, ScopeAdmin}))
+	assert.Equal(t, RoleEditor, RoleForScopes([]string{ScopeRead}))
+}
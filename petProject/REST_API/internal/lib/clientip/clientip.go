@@ -0,0 +1,123 @@
+// Адрес клиента для лимитов и логов
+
+package clientip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type ctxKey struct{}
+
+// Resolver finds the client IP behind trusted reverse proxies.
+// Заголовкам X-Forwarded-For и X-Real-IP верим, только если соединение пришло от доверенного прокси:
+// иначе клиент мог бы подставить любой адрес и обойти лимиты.
+type Resolver struct {
+	trusted []*net.IPNet
+}
+
+// New parses trusted proxies: адреса или подсети в CIDR ("10.0.0.0/8", "127.0.0.1").
+func New(trusted []string) (*Resolver, error) {
+	const op = "lib.clientip.New"
+
+	r := &Resolver{}
+	for _, t := range trusted {
+		if !strings.Contains(t, "/") {
+			ip := net.ParseIP(t)
+			if ip == nil {
+				return nil, fmt.Errorf("%s: invalid proxy address %q", op, t)
+			}
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+			r.trusted = append(r.trusted, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, n, err := net.ParseCIDR(t)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		r.trusted = append(r.trusted, n)
+	}
+
+	return r, nil
+}
+
+// Resolve returns the IP of the client. X-Forwarded-For разбирается справа налево
+// до первого адреса, который не является доверенным прокси.
+func (res *Resolver) Resolve(r *http.Request) string {
+	remote := remoteHost(r)
+	if !res.isTrusted(remote) {
+		return remote
+	}
+
+	var hops []string
+	for _, h := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(h, ",")...)
+	}
+
+	client := ""
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break // дальше мусор, который мог подставить кто угодно
+		}
+		client = hop
+		if !res.isTrusted(hop) {
+			return hop
+		}
+	}
+	if client != "" {
+		return client // вся цепочка из доверенных прокси - берем самый дальний адрес
+	}
+
+	if real := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(real) != nil {
+		return real
+	}
+
+	return remote
+}
+
+func (res *Resolver) isTrusted(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, n := range res.trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewContext stores the resolved client IP for FromRequest.
+func NewContext(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, ip)
+}
+
+// FromRequest returns the client IP resolved by the clientip middleware,
+// without it - the IP of the peer that opened the connection.
+func FromRequest(r *http.Request) string {
+	if ip, ok := r.Context().Value(ctxKey{}).(string); ok {
+		return ip
+	}
+
+	return remoteHost(r)
+}
+
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
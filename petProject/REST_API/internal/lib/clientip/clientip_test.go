@@ -0,0 +1,98 @@
+package clientip_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"main.go/internal/lib/clientip"
+)
+
+func TestResolve(t *testing.T) {
+	res, err := clientip.New([]string{"10.0.0.0/8", "127.0.0.1"})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name   string
+		remote string
+		xff    []string
+		realIP string
+		want   string
+	}{
+		{
+			name:   "Direct client",
+			remote: "203.0.113.7:5000",
+			want:   "203.0.113.7",
+		},
+		{
+			name:   "Untrusted peer spoofs header",
+			remote: "203.0.113.7:5000",
+			xff:    []string{"1.2.3.4"},
+			want:   "203.0.113.7",
+		},
+		{
+			name:   "Behind trusted proxy",
+			remote: "10.0.0.2:5000",
+			xff:    []string{"198.51.100.1"},
+			want:   "198.51.100.1",
+		},
+		{
+			name:   "Chain of proxies",
+			remote: "127.0.0.1:5000",
+			xff:    []string{"1.2.3.4, 198.51.100.1", "10.0.0.5"},
+			want:   "198.51.100.1",
+		},
+		{
+			name:   "Only trusted hops",
+			remote: "10.0.0.2:5000",
+			xff:    []string{"10.0.0.9, 10.0.0.5"},
+			want:   "10.0.0.9",
+		},
+		{
+			name:   "Garbage in chain",
+			remote: "10.0.0.2:5000",
+			xff:    []string{"1.2.3.4, junk, 10.0.0.5"},
+			want:   "10.0.0.5",
+		},
+		{
+			name:   "X-Real-IP",
+			remote: "10.0.0.2:5000",
+			realIP: "198.51.100.2",
+			want:   "198.51.100.2",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tc.remote
+			for _, h := range tc.xff {
+				r.Header.Add("X-Forwarded-For", h)
+			}
+			if tc.realIP != "" {
+				r.Header.Set("X-Real-IP", tc.realIP)
+			}
+
+			assert.Equal(t, tc.want, res.Resolve(r))
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	_, err := clientip.New([]string{"not-an-ip"})
+	assert.Error(t, err)
+
+	_, err = clientip.New([]string{"10.0.0.0/33"})
+	assert.Error(t, err)
+}
+
+func TestFromRequest(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "203.0.113.7:5000"
+	assert.Equal(t, "203.0.113.7", clientip.FromRequest(r))
+
+	r = r.WithContext(clientip.NewContext(r.Context(), "198.51.100.1"))
+	assert.Equal(t, "198.51.100.1", clientip.FromRequest(r))
+}
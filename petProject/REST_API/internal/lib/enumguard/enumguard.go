@@ -0,0 +1,113 @@
+// Защита от перебора alias: считаем промахи (404) клиента, замедляем и блокируем
+
+package enumguard
+
+import (
+	"sync"
+	"time"
+)
+
+// Config describes how misses are punished. Промахи считаются в окне Window.
+type Config struct {
+	Window     time.Duration // за какое время считаем промахи
+	SlowAfter  int           // после стольких промахов в окне ответы замедляются, 0 - не замедлять
+	Delay      time.Duration // задержка за каждый промах сверх SlowAfter
+	MaxDelay   time.Duration // больше этого не замедляем
+	BlockAfter int           // после стольких промахов в окне клиент блокируется, 0 - не блокировать
+	BlockFor   time.Duration
+}
+
+// Guard tracks misses per client. Safe for concurrent use.
+type Guard struct {
+	cfg Config
+
+	mu        sync.Mutex
+	clients   map[string]*client
+	lastSweep time.Time
+}
+
+type client struct {
+	misses       int
+	windowStart  time.Time
+	blockedUntil time.Time
+}
+
+const sweepInterval = time.Minute
+
+func New(cfg Config) *Guard {
+	return &Guard{cfg: cfg, clients: make(map[string]*client)}
+}
+
+// Check returns how long to delay the client's request, or how long it stays blocked.
+func (g *Guard) Check(key string, now time.Time) (delay, blocked time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	c, ok := g.clients[key]
+	if !ok {
+		return 0, 0
+	}
+
+	if now.Before(c.blockedUntil) {
+		return 0, c.blockedUntil.Sub(now)
+	}
+
+	g.resetExpired(c, now)
+
+	if g.cfg.SlowAfter <= 0 || c.misses < g.cfg.SlowAfter {
+		return 0, 0
+	}
+
+	delay = g.cfg.Delay * time.Duration(c.misses-g.cfg.SlowAfter+1)
+	if g.cfg.MaxDelay > 0 && delay > g.cfg.MaxDelay {
+		delay = g.cfg.MaxDelay
+	}
+
+	return delay, 0
+}
+
+// Miss records a miss of the client and reports whether the client got blocked by it.
+func (g *Guard) Miss(key string, now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if now.Sub(g.lastSweep) >= sweepInterval {
+		g.sweep(now)
+	}
+
+	c, ok := g.clients[key]
+	if !ok {
+		c = &client{windowStart: now}
+		g.clients[key] = c
+	}
+
+	g.resetExpired(c, now)
+	c.misses++
+
+	if g.cfg.BlockAfter > 0 && c.misses >= g.cfg.BlockAfter {
+		// После блокировки счет начинается заново
+		c.blockedUntil = now.Add(g.cfg.BlockFor)
+		c.misses = 0
+		c.windowStart = c.blockedUntil
+		return true
+	}
+
+	return false
+}
+
+func (g *Guard) resetExpired(c *client, now time.Time) {
+	if now.Sub(c.windowStart) >= g.cfg.Window {
+		c.misses = 0
+		c.windowStart = now
+	}
+}
+
+// sweep drops clients that are neither blocked nor have misses in the current window.
+func (g *Guard) sweep(now time.Time) {
+	for key, c := range g.clients {
+		if !now.Before(c.blockedUntil) && now.Sub(c.windowStart) >= g.cfg.Window {
+			delete(g.clients, key)
+		}
+	}
+	g.lastSweep = now
+}
@@ -0,0 +1,60 @@
+package enumguard
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGuard(t *testing.T) {
+	g := New(Config{
+		Window:     time.Minute,
+		SlowAfter:  3,
+		Delay:      100 * time.Millisecond,
+		MaxDelay:   250 * time.Millisecond,
+		BlockAfter: 6,
+		BlockFor:   10 * time.Minute,
+	})
+	now := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	delay, blocked := g.Check("1.2.3.4", now)
+	assert.Zero(t, delay)
+	assert.Zero(t, blocked)
+
+	wantDelays := []time.Duration{0, 0, 100 * time.Millisecond, 200 * time.Millisecond, 250 * time.Millisecond}
+	for i, want := range wantDelays {
+		assert.False(t, g.Miss("1.2.3.4", now))
+		delay, _ := g.Check("1.2.3.4", now)
+		assert.Equal(t, want, delay, "after miss %d", i+1)
+	}
+
+	// Другой клиент не страдает
+	delay, _ = g.Check("5.6.7.8", now)
+	assert.Zero(t, delay)
+
+	assert.True(t, g.Miss("1.2.3.4", now))
+	_, blocked = g.Check("1.2.3.4", now.Add(time.Minute))
+	assert.Equal(t, 9*time.Minute, blocked)
+
+	// После блокировки - с чистого листа
+	delay, blocked = g.Check("1.2.3.4", now.Add(11*time.Minute))
+	assert.Zero(t, delay)
+	assert.Zero(t, blocked)
+}
+
+func TestGuard_WindowExpires(t *testing.T) {
+	g := New(Config{Window: time.Minute, SlowAfter: 1, Delay: time.Second})
+	now := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	g.Miss("c", now)
+	delay, _ := g.Check("c", now)
+	assert.Equal(t, time.Second, delay)
+
+	delay, _ = g.Check("c", now.Add(time.Minute))
+	assert.Zero(t, delay)
+
+	// Клиенты без промахов в окне выбрасываются
+	g.Miss("d", now.Add(2*time.Minute))
+	assert.NotContains(t, g.clients, "c")
+}
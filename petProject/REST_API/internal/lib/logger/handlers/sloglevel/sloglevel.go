@@ -0,0 +1,254 @@
+package sloglevel
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"log/slog"
+)
+
+// Controller changes log levels at runtime without restart.
+// У каждого приемника свой slog.LevelVar, Set меняет их все сразу.
+// Для отдельного компонента (атрибут op) уровень можно понизить на время через SetOp.
+type Controller struct {
+	mu        sync.RWMutex
+	vars      []*slog.LevelVar
+	defaults  []slog.Level // уровни из конфига, к ним возвращает Reset
+	overrides map[string]override
+	timer     *time.Timer // возврат к defaults после Set с длительностью
+	until     time.Time
+	now       func() time.Time
+}
+
+type override struct {
+	level slog.Level
+	until time.Time // нулевое - до Reset
+}
+
+// State is the current configuration of levels.
+type State struct {
+	Sinks     []slog.Level `json:"sinks"`
+	Until     *time.Time   `json:"until,omitempty"` // когда Set вернется к конфигу
+	Overrides []Override   `json:"overrides,omitempty"`
+}
+
+type Override struct {
+	Op    string     `json:"op"`
+	Level slog.Level `json:"level"`
+	Until *time.Time `json:"until,omitempty"`
+}
+
+func NewController() *Controller {
+	return &Controller{
+		overrides: make(map[string]override),
+		now:       time.Now,
+	}
+}
+
+// Register adds a sink with the configured level. LevelVar передается в slog.HandlerOptions приемника.
+func (c *Controller) Register(level slog.Level) *slog.LevelVar {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	v := new(slog.LevelVar)
+	v.Set(level)
+	c.vars = append(c.vars, v)
+	c.defaults = append(c.defaults, level)
+
+	return v
+}
+
+// Set changes the level of all sinks. При d > 0 через d уровни вернутся к конфигу.
+func (c *Controller) Set(level slog.Level, d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stopTimer()
+	for _, v := range c.vars {
+		v.Set(level)
+	}
+
+	if d > 0 {
+		c.until = c.now().Add(d)
+		var t *time.Timer
+		t = time.AfterFunc(d, func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if c.timer != t {
+				return // уже был новый Set или Reset
+			}
+			c.timer = nil
+			c.restore()
+		})
+		c.timer = t
+	}
+}
+
+// SetOp changes the level for records of one component: op совпадает целиком
+// или является префиксом по точке ("handlers.url" включает "handlers.url.save.New").
+// При d > 0 переопределение действует d.
+func (c *Controller) SetOp(op string, level slog.Level, d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	o := override{level: level}
+	if d > 0 {
+		o.until = c.now().Add(d)
+	}
+	c.overrides[op] = o
+}
+
+// SetDefaults replaces the configured levels of sinks (например после перечитывания конфига)
+// and applies them. Действующий Set с длительностью отменяется.
+func (c *Controller) SetDefaults(levels []slog.Level) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(levels) != len(c.vars) {
+		return false // приемники добавились или пропали - нужен рестарт
+	}
+
+	c.stopTimer()
+	copy(c.defaults, levels)
+	c.restore()
+
+	return true
+}
+
+// Reset returns sinks to the configured levels and drops all overrides.
+func (c *Controller) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stopTimer()
+	c.restore()
+	c.overrides = make(map[string]override)
+}
+
+// State returns the current levels. Истекшие переопределения удаляются.
+func (c *Controller) State() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var s State
+	for _, v := range c.vars {
+		s.Sinks = append(s.Sinks, v.Level())
+	}
+	if !c.until.IsZero() {
+		until := c.until
+		s.Until = &until
+	}
+
+	now := c.now()
+	for op, o := range c.overrides {
+		if o.expired(now) {
+			delete(c.overrides, op)
+			continue
+		}
+		ov := Override{Op: op, Level: o.level}
+		if !o.until.IsZero() {
+			until := o.until
+			ov.Until = &until
+		}
+		s.Overrides = append(s.Overrides, ov)
+	}
+	sort.Slice(s.Overrides, func(i, j int) bool { return s.Overrides[i].Op < s.Overrides[j].Op })
+
+	return s
+}
+
+// Wrap returns a handler that lets through records of overridden components
+// even below the level of the sink. Оборачивает приемник целиком (снаружи остальных обработчиков).
+func (c *Controller) Wrap(h slog.Handler) slog.Handler {
+	return &Handler{next: h, c: c}
+}
+
+// overrideFor returns the override for op if there is an active one.
+func (c *Controller) overrideFor(op string) (slog.Level, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if len(c.overrides) == 0 || op == "" {
+		return 0, false
+	}
+
+	now := c.now()
+	// Самое точное совпадение важнее префикса
+	best, found := "", false
+	var level slog.Level
+	for k, o := range c.overrides {
+		if o.expired(now) || len(k) < len(best) {
+			continue
+		}
+		if op == k || strings.HasPrefix(op, k+".") {
+			best, level, found = k, o.level, true
+		}
+	}
+
+	return level, found
+}
+
+func (c *Controller) restore() {
+	for i, v := range c.vars {
+		v.Set(c.defaults[i])
+	}
+	c.until = time.Time{}
+}
+
+func (c *Controller) stopTimer() {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+}
+
+func (o override) expired(now time.Time) bool {
+	return !o.until.IsZero() && !now.Before(o.until)
+}
+
+// Handler applies per-component overrides. Компонент берется из атрибута op, добавленного через With.
+type Handler struct {
+	next    slog.Handler
+	c       *Controller
+	op      string
+	grouped bool // op внутри группы - уже не атрибут верхнего уровня
+}
+
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	if minLevel, ok := h.c.overrideFor(h.op); ok {
+		return level >= minLevel
+	}
+
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle passes the record on. Стандартные обработчики проверяют уровень только в Enabled,
+// поэтому запись компонента с пониженным уровнем доходит до приемника.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	return h.next.Handle(ctx, r)
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.next = h.next.WithAttrs(attrs)
+	if !h.grouped {
+		for _, a := range attrs {
+			if a.Key == "op" {
+				h2.op = a.Value.String()
+			}
+		}
+	}
+
+	return &h2
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.next = h.next.WithGroup(name)
+	h2.grouped = h2.grouped || name != ""
+
+	return &h2
+}
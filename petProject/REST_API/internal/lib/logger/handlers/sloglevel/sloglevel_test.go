@@ -0,0 +1,110 @@
+package sloglevel
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"main.go/internal/lib/logger/handlers/slogmulti"
+)
+
+func TestSet(t *testing.T) {
+	c := NewController()
+	v := c.Register(slog.LevelInfo)
+
+	var buf bytes.Buffer
+	log := slog.New(c.Wrap(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: v})))
+
+	log.Debug("hidden")
+	c.Set(slog.LevelDebug, 0)
+	log.Debug("shown")
+	c.Reset()
+	log.Debug("hidden again")
+
+	assert.NotContains(t, buf.String(), "hidden")
+	assert.Contains(t, buf.String(), "shown")
+	assert.Equal(t, slog.LevelInfo, v.Level())
+}
+
+func TestSet_Duration(t *testing.T) {
+	c := NewController()
+	v := c.Register(slog.LevelWarn)
+
+	c.Set(slog.LevelDebug, 20*time.Millisecond)
+	assert.Equal(t, slog.LevelDebug, v.Level())
+	assert.NotNil(t, c.State().Until)
+
+	require.Eventually(t, func() bool { return v.Level() == slog.LevelWarn }, time.Second, 5*time.Millisecond)
+	assert.Nil(t, c.State().Until)
+
+	// Новый Set отменяет возврат по предыдущему
+	c.Set(slog.LevelDebug, 20*time.Millisecond)
+	c.Set(slog.LevelError, 0)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, slog.LevelError, v.Level())
+}
+
+func TestSetOp(t *testing.T) {
+	now := time.Date(2024, 5, 17, 12, 0, 0, 0, time.UTC)
+	c := NewController()
+	c.now = func() time.Time { return now }
+
+	var info, warn bytes.Buffer
+	root := slog.New(slogmulti.NewFanoutHandler(
+		c.Wrap(slog.NewTextHandler(&info, &slog.HandlerOptions{Level: c.Register(slog.LevelInfo)})),
+		c.Wrap(slog.NewTextHandler(&warn, &slog.HandlerOptions{Level: c.Register(slog.LevelWarn)})),
+	))
+
+	save := root.With(slog.String("op", "handlers.url.save.New"))
+	redirect := root.With(slog.String("op", "handlers.redirect.New"))
+
+	c.SetOp("handlers.url", slog.LevelDebug, time.Minute)
+
+	save.Debug("save debug")
+	redirect.Debug("redirect debug")
+	root.WithGroup("g").With(slog.String("op", "handlers.url.save.New")).Debug("grouped op")
+
+	// Компонент получает записи во все приемники, остальные - по уровню приемника
+	assert.Contains(t, info.String(), "save debug")
+	assert.Contains(t, warn.String(), "save debug")
+	assert.NotContains(t, info.String(), "redirect debug")
+	assert.NotContains(t, info.String(), "grouped op")
+
+	// Более точное совпадение важнее префикса
+	c.SetOp("handlers.url.save.New", slog.LevelError, 0)
+	save.Warn("save warn")
+	assert.NotContains(t, info.String(), "save warn")
+
+	state := c.State()
+	require.Len(t, state.Overrides, 2)
+	assert.Equal(t, "handlers.url", state.Overrides[0].Op)
+	assert.Equal(t, now.Add(time.Minute), *state.Overrides[0].Until)
+	assert.Nil(t, state.Overrides[1].Until)
+
+	// Истекшее переопределение больше не действует
+	now = now.Add(2 * time.Minute)
+	save.Info("after expiry")
+	root.With(slog.String("op", "handlers.url.list.New")).Debug("list debug")
+	assert.NotContains(t, info.String(), "list debug")
+	assert.Len(t, c.State().Overrides, 1)
+
+	c.Reset()
+	assert.Empty(t, c.State().Overrides)
+}
+
+func TestSetDefaults(t *testing.T) {
+	c := NewController()
+	a, b := c.Register(slog.LevelInfo), c.Register(slog.LevelWarn)
+
+	c.Set(slog.LevelDebug, time.Hour)
+	assert.True(t, c.SetDefaults([]slog.Level{slog.LevelWarn, slog.LevelError}))
+	assert.Equal(t, slog.LevelWarn, a.Level())
+	assert.Equal(t, slog.LevelError, b.Level())
+	assert.Nil(t, c.State().Until)
+
+	assert.False(t, c.SetDefaults([]slog.Level{slog.LevelDebug}))
+	assert.Equal(t, slog.LevelWarn, a.Level())
+}
@@ -0,0 +1,61 @@
+package slogmulti
+
+import (
+	"context"
+	"errors"
+
+	"log/slog"
+)
+
+// FanoutHandler sends every record to all handlers that accept its level.
+// Так один логер пишет, например, Debug в stdout и только Warn+ в файл.
+type FanoutHandler struct {
+	handlers []slog.Handler
+}
+
+func NewFanoutHandler(handlers ...slog.Handler) *FanoutHandler {
+	return &FanoutHandler{handlers: handlers}
+}
+
+func (h *FanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, hh := range h.handlers {
+		if hh.Enabled(ctx, level) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Handle passes a copy of r to each handler: ошибка одного не мешает писать в остальные.
+func (h *FanoutHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, hh := range h.handlers {
+		if !hh.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := hh.Handle(ctx, r.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (h *FanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, hh := range h.handlers {
+		handlers[i] = hh.WithAttrs(attrs)
+	}
+
+	return &FanoutHandler{handlers: handlers}
+}
+
+func (h *FanoutHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, hh := range h.handlers {
+		handlers[i] = hh.WithGroup(name)
+	}
+
+	return &FanoutHandler{handlers: handlers}
+}
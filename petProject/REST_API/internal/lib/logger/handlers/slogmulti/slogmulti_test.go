@@ -0,0 +1,49 @@
+package slogmulti_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"main.go/internal/lib/logger/handlers/slogmulti"
+)
+
+func TestFanout(t *testing.T) {
+	var debug, warn bytes.Buffer
+
+	log := slog.New(slogmulti.NewFanoutHandler(
+		slog.NewTextHandler(&debug, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		slog.NewTextHandler(&warn, &slog.HandlerOptions{Level: slog.LevelWarn}),
+	)).With(slog.String("op", "test")).WithGroup("g")
+
+	log.Debug("details", slog.Int("n", 1))
+	log.Warn("problem", slog.Int("n", 2))
+
+	assert.Contains(t, debug.String(), "msg=details op=test g.n=1")
+	assert.Contains(t, debug.String(), "msg=problem op=test g.n=2")
+	assert.NotContains(t, warn.String(), "details")
+	assert.Contains(t, warn.String(), "msg=problem op=test g.n=2")
+}
+
+type failing struct{ slog.Handler }
+
+func (failing) Handle(context.Context, slog.Record) error { return errors.New("disk full") }
+
+func TestFanout_Error(t *testing.T) {
+	var out bytes.Buffer
+
+	h := slogmulti.NewFanoutHandler(
+		failing{slog.NewTextHandler(&out, nil)},
+		slog.NewTextHandler(&out, nil),
+	)
+
+	err := h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "still written", 0))
+	assert.ErrorContains(t, err, "disk full")
+	assert.Contains(t, out.String(), "still written")
+
+	assert.False(t, h.Enabled(context.Background(), slog.LevelDebug))
+}
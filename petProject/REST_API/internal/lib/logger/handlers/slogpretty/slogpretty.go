@@ -3,60 +3,94 @@ package slogpretty
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	stdLog "log"
+	"path/filepath"
+	"runtime"
 
 	"github.com/fatih/color"
 	"log/slog"
 )
 
 type PrettyHandlerOptions struct {
-	SlogOpts *slog.HandlerOptions
+	SlogOpts *slog.HandlerOptions // Level, AddSource и ReplaceAttr работают как у стандартных обработчиков
 }
 
+// PrettyHandler prints colored records for local development: время, уровень, сообщение и атрибуты в JSON.
 type PrettyHandler struct {
 	opts PrettyHandlerOptions
-	slog.Handler
-	l     *stdLog.Logger
-	attrs []slog.Attr
+	l    *stdLog.Logger
+	// goas - накопленные WithGroup/WithAttrs по порядку: атрибуты попадают в группы, открытые до них
+	goas []groupOrAttrs
+}
+
+type groupOrAttrs struct {
+	group string      // имя группы, если attrs пустой
+	attrs []slog.Attr // атрибуты из WithAttrs
 }
 
 func (opts PrettyHandlerOptions) NewPrettyHandler(
 	out io.Writer,
 ) *PrettyHandler {
+	if opts.SlogOpts == nil {
+		opts.SlogOpts = &slog.HandlerOptions{}
+	}
+
 	h := &PrettyHandler{
-		Handler: slog.NewJSONHandler(out, opts.SlogOpts),
-		l:       stdLog.New(out, "", 0),
+		opts: opts,
+		l:    stdLog.New(out, "", 0),
 	}
 
 	return h
 }
 
+// Enabled reports whether the level is at least SlogOpts.Level (по умолчанию Info).
+func (h *PrettyHandler) Enabled(_ context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.SlogOpts.Level != nil {
+		minLevel = h.opts.SlogOpts.Level.Level()
+	}
+
+	return level >= minLevel
+}
+
 func (h *PrettyHandler) Handle(_ context.Context, r slog.Record) error {
 	level := r.Level.String() + ":"
 
-	switch r.Level {
-	case slog.LevelDebug:
-		level = color.MagentaString(level)
-	case slog.LevelInfo:
-		level = color.BlueString(level)
-	case slog.LevelWarn:
-		level = color.YellowString(level)
-	case slog.LevelError:
+	switch {
+	case r.Level >= slog.LevelError:
 		level = color.RedString(level)
+	case r.Level >= slog.LevelWarn:
+		level = color.YellowString(level)
+	case r.Level >= slog.LevelInfo:
+		level = color.BlueString(level)
+	default:
+		level = color.MagentaString(level)
 	}
 
 	fields := make(map[string]interface{}, r.NumAttrs())
 
-	r.Attrs(func(a slog.Attr) bool {
-		fields[a.Key] = a.Value.Any()
+	// Сначала накопленные атрибуты и группы, затем атрибуты записи - в последнюю открытую группу
+	current, groups := fields, []string(nil)
+	for _, goa := range h.goas {
+		if goa.group != "" {
+			next := make(map[string]interface{})
+			current[goa.group] = next
+			current, groups = next, append(groups, goa.group)
+			continue
+		}
+		for _, a := range goa.attrs {
+			h.addAttr(current, groups, a)
+		}
+	}
 
+	r.Attrs(func(a slog.Attr) bool {
+		h.addAttr(current, groups, a)
 		return true
 	})
 
-	for _, a := range h.attrs {
-		fields[a.Key] = a.Value.Any()
-	}
+	prune(fields)
 
 	var b []byte
 	var err error
@@ -68,31 +102,110 @@ func (h *PrettyHandler) Handle(_ context.Context, r slog.Record) error {
 		}
 	}
 
-	timeStr := r.Time.Format("[15:05:05.000]")
 	msg := color.CyanString(r.Message)
 
-	h.l.Println(
-		timeStr,
-		level,
-		msg,
-		color.WhiteString(string(b)),
-	)
+	// Нулевое время не выводим, как и стандартные обработчики
+	line := []interface{}{level}
+	if !r.Time.IsZero() {
+		line = []interface{}{r.Time.Format("[15:04:05.000]"), level}
+	}
+	if h.opts.SlogOpts.AddSource && r.PC != 0 {
+		line = append(line, source(r.PC))
+	}
+	line = append(line, msg)
+	if len(b) > 0 {
+		line = append(line, color.WhiteString(string(b)))
+	}
+
+	h.l.Println(line...)
 
 	return nil
 }
 
+// addAttr puts a into m. Группа без ключа встраивается в текущий уровень, пустые атрибуты пропускаются.
+func (h *PrettyHandler) addAttr(m map[string]interface{}, groups []string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+
+	if a.Value.Kind() != slog.KindGroup && h.opts.SlogOpts.ReplaceAttr != nil {
+		a = h.opts.SlogOpts.ReplaceAttr(groups, a)
+		a.Value = a.Value.Resolve()
+	}
+
+	if a.Equal(slog.Attr{}) {
+		return // пустой атрибут (например trace_id без span) не выводим, как и стандартные обработчики
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		target := m
+		if a.Key != "" {
+			target = make(map[string]interface{})
+			m[a.Key] = target
+			groups = append(groups[:len(groups):len(groups)], a.Key)
+		}
+		for _, ga := range a.Value.Group() {
+			h.addAttr(target, groups, ga)
+		}
+		return
+	}
+
+	m[a.Key] = value(a.Value)
+}
+
+func value(v slog.Value) interface{} {
+	switch v.Kind() {
+	case slog.KindDuration:
+		return v.Duration().String()
+	case slog.KindAny:
+		// error сериализуется в JSON как {}, печатаем текст
+		if err, ok := v.Any().(error); ok {
+			return err.Error()
+		}
+	}
+
+	return v.Any()
+}
+
+// prune drops empty groups: группа без атрибутов не выводится.
+func prune(m map[string]interface{}) {
+	for k, v := range m {
+		if g, ok := v.(map[string]interface{}); ok {
+			prune(g)
+			if len(g) == 0 {
+				delete(m, k)
+			}
+		}
+	}
+}
+
+// source returns "dir/file.go:line" of the call site.
+func source(pc uintptr) string {
+	f, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+
+	return fmt.Sprintf("%s:%d", filepath.Join(filepath.Base(filepath.Dir(f.File)), filepath.Base(f.File)), f.Line)
+}
+
 func (h *PrettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	return &PrettyHandler{
-		Handler: h.Handler,
-		l:       h.l,
-		attrs:   attrs,
+	if len(attrs) == 0 {
+		return h
 	}
+
+	return h.with(groupOrAttrs{attrs: attrs})
 }
 
 func (h *PrettyHandler) WithGroup(name string) slog.Handler {
-	// TODO: implement
-	return &PrettyHandler{
-		Handler: h.Handler.WithGroup(name),
-		l:       h.l,
+	if name == "" {
+		return h
 	}
+
+	return h.with(groupOrAttrs{group: name})
+}
+
+// with returns a copy of h with goa appended. Срез копируется, чтобы производные логеры не делили массив.
+func (h *PrettyHandler) with(goa groupOrAttrs) *PrettyHandler {
+	h2 := *h
+	h2.goas = make([]groupOrAttrs, len(h.goas)+1)
+	copy(h2.goas, h.goas)
+	h2.goas[len(h.goas)] = goa
+
+	return &h2
 }
@@ -0,0 +1,167 @@
+package slogpretty_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/slogtest"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"main.go/internal/lib/logger/handlers/slogpretty"
+)
+
+// go test ./internal/lib/logger/handlers/slogpretty -update перезаписывает testdata/*.golden
+var update = flag.Bool("update", false, "update golden files")
+
+var at = time.Date(2024, 5, 17, 13, 7, 9, 123_000_000, time.UTC)
+
+func TestMain(m *testing.M) {
+	color.NoColor = true // цвета зависят от терминала, в golden-файлах их нет
+	os.Exit(m.Run())
+}
+
+func TestGolden(t *testing.T) {
+	cases := []struct {
+		name string
+		log  func(h slog.Handler)
+	}{
+		{
+			name: "levels",
+			log: func(h slog.Handler) {
+				for _, l := range []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError} {
+					handle(t, h, l, "message")
+				}
+			},
+		},
+		{
+			name: "attrs",
+			log: func(h slog.Handler) {
+				h = h.WithAttrs([]slog.Attr{slog.String("component", "test")})
+				h = h.WithAttrs([]slog.Attr{slog.String("request_id", "req-1"), {}})
+				handle(t, h, slog.LevelInfo, "request completed",
+					slog.Int("status", 200),
+					slog.Duration("duration", 1500*time.Millisecond),
+					slog.Any("error", errors.New("boom")),
+				)
+			},
+		},
+		{
+			name: "groups",
+			log: func(h slog.Handler) {
+				h = h.WithAttrs([]slog.Attr{slog.String("op", "handlers.url.save.New")})
+				h = h.WithGroup("request").WithAttrs([]slog.Attr{slog.String("method", "POST")})
+				h = h.WithGroup("body")
+				handle(t, h, slog.LevelInfo, "decoded",
+					slog.String("alias", "abc"),
+					slog.Group("rules", slog.Int("count", 2)),
+					slog.Group("", slog.Bool("inlined", true)),
+					slog.Group("empty"),
+				)
+				handle(t, h, slog.LevelInfo, "no attrs in open groups")
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tc.log(slogpretty.PrettyHandlerOptions{
+				SlogOpts: &slog.HandlerOptions{Level: slog.LevelDebug},
+			}.NewPrettyHandler(&buf))
+
+			golden := filepath.Join("testdata", tc.name+".golden")
+			if *update {
+				require.NoError(t, os.WriteFile(golden, buf.Bytes(), 0o644))
+			}
+
+			want, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			assert.Equal(t, string(want), buf.String())
+		})
+	}
+}
+
+func TestLevel(t *testing.T) {
+	var buf bytes.Buffer
+	level := new(slog.LevelVar)
+	level.Set(slog.LevelWarn)
+
+	log := slog.New(slogpretty.PrettyHandlerOptions{
+		SlogOpts: &slog.HandlerOptions{Level: level},
+	}.NewPrettyHandler(&buf))
+
+	log.Info("hidden")
+	log.Warn("shown")
+	assert.NotContains(t, buf.String(), "hidden")
+	assert.Contains(t, buf.String(), "shown")
+
+	// Уровень без SlogOpts - Info
+	h := slogpretty.PrettyHandlerOptions{}.NewPrettyHandler(&buf)
+	assert.False(t, h.Enabled(context.Background(), slog.LevelDebug))
+	assert.True(t, h.Enabled(context.Background(), slog.LevelInfo))
+}
+
+func TestSource(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slogpretty.PrettyHandlerOptions{
+		SlogOpts: &slog.HandlerOptions{AddSource: true},
+	}.NewPrettyHandler(&buf))
+
+	_, _, line, _ := runtime.Caller(0)
+	log.Info("with source")
+
+	assert.Contains(t, buf.String(), " slogpretty/slogpretty_test.go:"+strconv.Itoa(line+1)+" with source")
+}
+
+// TestSlogtest checks the handler against the slog.Handler contract.
+func TestSlogtest(t *testing.T) {
+	var buf bytes.Buffer
+
+	slogtest.Run(t, func(*testing.T) slog.Handler {
+		buf.Reset()
+		return slogpretty.PrettyHandlerOptions{}.NewPrettyHandler(&buf)
+	}, func(t *testing.T) map[string]any {
+		return parse(t, buf.String())
+	})
+}
+
+func handle(t *testing.T, h slog.Handler, level slog.Level, msg string, attrs ...slog.Attr) {
+	t.Helper()
+
+	r := slog.NewRecord(at, level, msg, 0)
+	r.AddAttrs(attrs...)
+	require.NoError(t, h.Handle(context.Background(), r))
+}
+
+// parse turns "[time] LEVEL: msg {json}" back into the map slogtest expects.
+func parse(t *testing.T, s string) map[string]any {
+	m := map[string]any{}
+
+	head := s
+	if i := strings.Index(s, "{"); i >= 0 {
+		head = s[:i]
+		require.NoError(t, json.Unmarshal([]byte(s[i:]), &m))
+	}
+
+	fields := strings.Fields(head)
+	if strings.HasPrefix(fields[0], "[") {
+		m[slog.TimeKey] = fields[0]
+		fields = fields[1:]
+	}
+	m[slog.LevelKey] = strings.TrimSuffix(fields[0], ":")
+	m[slog.MessageKey] = strings.Join(fields[1:], " ")
+
+	return m
+}
@@ -0,0 +1,126 @@
+// Куда пишутся логи: stdout или файл с ротацией
+
+package sink
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+	"log/slog"
+	"main.go/internal/lib/logger/handlers/slogpretty"
+)
+
+const (
+	TypeStdout = "stdout"
+	TypeFile   = "file"
+
+	FormatPretty = "pretty"
+	FormatJSON   = "json"
+	FormatText   = "text"
+)
+
+type Config struct {
+	Type   string       // stdout или file
+	Format string       // pretty (только stdout), json или text
+	Level  slog.Leveler // slog.LevelVar - чтобы менять уровень без рестарта
+
+	// Только для file
+	Path        string
+	MaxSizeMB   int           // ротация по размеру, 0 - 100 МБ
+	RotateEvery time.Duration // ротация по времени от старта, 0 - только по размеру
+	MaxBackups  int           // сколько старых файлов хранить, 0 - все
+	MaxAgeDays  int           // сколько дней хранить старые файлы, 0 - без ограничения
+	Compress    bool          // сжимать старые файлы gzip
+}
+
+// New builds the handler of one sink. Closer закрывает файл, для stdout ничего не делает.
+func New(cfg Config) (slog.Handler, io.Closer, error) {
+	const op = "lib.logger.sink.New"
+
+	var out io.Writer
+	var closer io.Closer = nopCloser{}
+
+	switch cfg.Type {
+	case TypeStdout:
+		out = os.Stdout
+	case TypeFile:
+		if cfg.Path == "" {
+			return nil, nil, fmt.Errorf("%s: file sink without path", op)
+		}
+		if cfg.Format == FormatPretty {
+			// Цвета и многострочный JSON в файле только мешают грепать
+			return nil, nil, fmt.Errorf("%s: pretty format is only for stdout", op)
+		}
+		f := newRotatingFile(cfg)
+		out, closer = f, f
+	default:
+		return nil, nil, fmt.Errorf("%s: unknown sink type %q", op, cfg.Type)
+	}
+
+	opts := &slog.HandlerOptions{Level: cfg.Level}
+
+	switch cfg.Format {
+	case FormatPretty:
+		return slogpretty.PrettyHandlerOptions{SlogOpts: opts}.NewPrettyHandler(out), closer, nil
+	case FormatJSON:
+		return slog.NewJSONHandler(out, opts), closer, nil
+	case FormatText:
+		return slog.NewTextHandler(out, opts), closer, nil
+	default:
+		_ = closer.Close()
+		return nil, nil, fmt.Errorf("%s: unknown format %q", op, cfg.Format)
+	}
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// rotatingFile rotates by size (lumberjack) and additionally every RotateEvery.
+type rotatingFile struct {
+	*lumberjack.Logger
+	stop chan struct{}
+}
+
+func newRotatingFile(cfg Config) *rotatingFile {
+	f := &rotatingFile{
+		Logger: &lumberjack.Logger{
+			Filename:   cfg.Path,
+			MaxSize:    cfg.MaxSizeMB,
+			MaxBackups: cfg.MaxBackups,
+			MaxAge:     cfg.MaxAgeDays,
+			Compress:   cfg.Compress,
+			LocalTime:  true,
+		},
+		stop: make(chan struct{}),
+	}
+
+	if cfg.RotateEvery > 0 {
+		go f.rotateEvery(cfg.RotateEvery)
+	}
+
+	return f
+}
+
+func (f *rotatingFile) rotateEvery(d time.Duration) {
+	t := time.NewTicker(d)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			// Ошибку писать некуда: это и есть лог. Следующая запись попробует открыть файл снова
+			_ = f.Rotate()
+		case <-f.stop:
+			return
+		}
+	}
+}
+
+func (f *rotatingFile) Close() error {
+	close(f.stop)
+	return f.Logger.Close()
+}
@@ -0,0 +1,77 @@
+package sink_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"main.go/internal/lib/logger/sink"
+)
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	h, closer, err := sink.New(sink.Config{Type: sink.TypeFile, Format: sink.FormatJSON, Level: slog.LevelWarn, Path: path})
+	require.NoError(t, err)
+
+	assert.False(t, h.Enabled(context.Background(), slog.LevelInfo))
+
+	log := slog.New(h)
+	log.Info("skipped")
+	log.Warn("written", slog.String("op", "test"))
+	require.NoError(t, closer.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "skipped")
+	assert.Contains(t, string(data), `"msg":"written","op":"test"`)
+}
+
+func TestFile_RotateEvery(t *testing.T) {
+	dir := t.TempDir()
+
+	h, closer, err := sink.New(sink.Config{
+		Type:        sink.TypeFile,
+		Format:      sink.FormatText,
+		Level:       slog.LevelInfo,
+		Path:        filepath.Join(dir, "app.log"),
+		RotateEvery: 20 * time.Millisecond,
+		Compress:    true,
+	})
+	require.NoError(t, err)
+	defer closer.Close()
+
+	slog.New(h).Info("before rotation")
+
+	// Старый файл переименовывается и сжимается в фоне
+	require.Eventually(t, func() bool {
+		gz, _ := filepath.Glob(filepath.Join(dir, "app-*.log.gz"))
+		return len(gz) > 0
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestNew_Invalid(t *testing.T) {
+	cases := []struct {
+		name string
+		cfg  sink.Config
+		want string
+	}{
+		{"Unknown type", sink.Config{Type: "syslog", Format: sink.FormatJSON, Level: slog.LevelInfo}, `unknown sink type "syslog"`},
+		{"Unknown format", sink.Config{Type: sink.TypeStdout, Format: "xml", Level: slog.LevelInfo}, `unknown format "xml"`},
+		{"File without path", sink.Config{Type: sink.TypeFile, Format: sink.FormatJSON, Level: slog.LevelInfo}, "without path"},
+		{"Pretty file", sink.Config{Type: sink.TypeFile, Format: sink.FormatPretty, Level: slog.LevelInfo, Path: "app.log"}, "only for stdout"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := sink.New(tc.cfg)
+			assert.ErrorContains(t, err, tc.want)
+		})
+	}
+}
@@ -1,8 +1,11 @@
 package sl
 
 import (
+	"context"
 	_ "github.com/mattn/go-sqlite3" // Драйвер для sqlite
 	"log/slog"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 func Err(err error) slog.Attr {
@@ -13,3 +16,29 @@ func Err(err error) slog.Attr {
 }
 
 // Выводит ошибку
+
+// TraceID returns the trace_id of the span in ctx. Без span атрибут пустой, и обработчик его пропускает.
+func TraceID(ctx context.Context) slog.Attr {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return slog.Attr{}
+	}
+
+	return slog.String("trace_id", sc.TraceID().String())
+}
+
+type ctxKey struct{}
+
+// WithLogger stores the request-scoped logger in ctx.
+func WithLogger(ctx context.Context, log *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, log)
+}
+
+// FromContext returns the request-scoped logger (с request_id и trace_id) or fallback when there is none.
+func FromContext(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if log, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return log
+	}
+
+	return fallback
+}
@@ -0,0 +1,93 @@
+// Метрики Prometheus: HTTP, хранилище, редиректы и рантайм Go
+
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"main.go/internal/storage"
+)
+
+const namespace = "url_shortener"
+
+// Metrics holds all collectors of the service in its own registry.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests  *prometheus.CounterVec
+	httpDuration  *prometheus.HistogramVec
+	storageOps    *prometheus.HistogramVec
+	storageErrors *prometheus.CounterVec
+	redirects     *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route pattern, method and status.",
+		}, []string{"route", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route pattern and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		storageOps: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "storage_operation_duration_seconds",
+			Help:      "Storage call latency by operation.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"op"}),
+		storageErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "storage_errors_total",
+			Help:      "Failed storage calls by operation. Not found, exists and similar results are not errors.",
+		}, []string{"op"}),
+		redirects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "redirects_total",
+			Help:      "Redirect requests by result: hit, miss, gone, preview.",
+		}, []string{"result"}),
+	}
+
+	m.registry.MustRegister(
+		m.httpRequests, m.httpDuration, m.storageOps, m.storageErrors, m.redirects,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveHTTP records a finished HTTP request. route - шаблон маршрута chi, а не путь,
+// иначе каждый alias станет отдельной серией.
+func (m *Metrics) ObserveHTTP(route, method string, status int, d time.Duration) {
+	m.httpRequests.WithLabelValues(route, method, strconv.Itoa(status)).Inc()
+	m.httpDuration.WithLabelValues(route, method).Observe(d.Seconds())
+}
+
+// ObserveStorage records a storage call. Реализует sqlite.Observer.
+func (m *Metrics) ObserveStorage(op string, d time.Duration, err error) {
+	m.storageOps.WithLabelValues(op).Observe(d.Seconds())
+	if err != nil && !storage.Expected(err) {
+		m.storageErrors.WithLabelValues(op).Inc()
+	}
+}
+
+// ObserveRedirect counts a redirect result. Реализует redirect.Observer.
+func (m *Metrics) ObserveRedirect(result string) {
+	m.redirects.WithLabelValues(result).Inc()
+}
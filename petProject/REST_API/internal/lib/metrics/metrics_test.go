@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"main.go/internal/storage"
+)
+
+func TestMetrics(t *testing.T) {
+	m := New()
+
+	m.ObserveHTTP("/{alias}", http.MethodGet, http.StatusFound, 5*time.Millisecond)
+	m.ObserveHTTP("/{alias}", http.MethodGet, http.StatusFound, 5*time.Millisecond)
+	m.ObserveStorage("storage.sqlite.GetURL", time.Millisecond, nil)
+	m.ObserveStorage("storage.sqlite.GetURL", time.Millisecond, fmt.Errorf("wrapped: %w", storage.ErrURLNotFound))
+	m.ObserveStorage("storage.sqlite.SaveURL", time.Millisecond, errors.New("disk I/O error"))
+	m.ObserveRedirect("hit")
+	m.ObserveRedirect("miss")
+
+	rr := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rr.Body.String()
+
+	assert.Contains(t, body, `url_shortener_http_requests_total{method="GET",route="/{alias}",status="302"} 2`)
+	assert.Contains(t, body, `url_shortener_http_request_duration_seconds_count{method="GET",route="/{alias}"} 2`)
+	assert.Contains(t, body, `url_shortener_storage_operation_duration_seconds_count{op="storage.sqlite.GetURL"} 2`)
+	assert.Contains(t, body, `url_shortener_storage_errors_total{op="storage.sqlite.SaveURL"} 1`)
+	assert.NotContains(t, body, `url_shortener_storage_errors_total{op="storage.sqlite.GetURL"}`) // not found - не ошибка
+	assert.Contains(t, body, `url_shortener_redirects_total{result="hit"} 1`)
+	assert.Contains(t, body, "go_goroutines")
+}
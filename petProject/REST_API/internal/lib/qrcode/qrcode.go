@@ -0,0 +1,197 @@
+// Генерация QR-кодов в PNG и SVG без внешних сервисов
+
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+	"strings"
+
+	"rsc.io/qr"
+)
+
+const (
+	MinSize = 64
+	MaxSize = 2048
+
+	MaxMargin = 16
+)
+
+var ErrInvalidColor = errors.New("color must be a hex value like ff0000")
+
+// Options describes how the QR code is rendered.
+type Options struct {
+	Size       int        // Ширина и высота картинки в пикселях (округляется вниз до целого размера модуля)
+	Level      qr.Level   // Уровень коррекции ошибок: L, M, Q, H
+	Margin     int        // Пустая рамка в модулях, по стандарту 4
+	Foreground color.RGBA // Цвет модулей
+	Background color.RGBA // Цвет фона
+}
+
+// DefaultOptions returns black-on-white 256px code with level M and standard margin.
+func DefaultOptions() Options {
+	return Options{
+		Size:       256,
+		Level:      qr.M,
+		Margin:     4,
+		Foreground: color.RGBA{A: 0xff},
+		Background: color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	}
+}
+
+// ParseLevel parses an error-correction level name (L, M, Q, H).
+func ParseLevel(s string) (qr.Level, error) {
+	switch strings.ToUpper(s) {
+	case "L":
+		return qr.L, nil
+	case "M":
+		return qr.M, nil
+	case "Q":
+		return qr.Q, nil
+	case "H":
+		return qr.H, nil
+	}
+
+	return 0, fmt.Errorf("unknown error correction level %q", s)
+}
+
+// ParseColor parses "rrggbb" or "#rrggbb".
+func ParseColor(s string) (color.RGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) != 6 {
+		return color.RGBA{}, ErrInvalidColor
+	}
+
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return color.RGBA{}, ErrInvalidColor
+	}
+
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+}
+
+// Validate checks size and margin limits.
+func (o Options) Validate() error {
+	if o.Size < MinSize || o.Size > MaxSize {
+		return fmt.Errorf("size must be between %d and %d", MinSize, MaxSize)
+	}
+	if o.Margin < 0 || o.Margin > MaxMargin {
+		return fmt.Errorf("margin must be between 0 and %d", MaxMargin)
+	}
+
+	return nil
+}
+
+// grid - закодированный QR с рамкой и размером модуля в пикселях
+type grid struct {
+	code   *qr.Code
+	margin int
+	scale  int
+	full   int // модулей по стороне вместе с рамкой
+}
+
+func encode(text string, o Options) (grid, error) {
+	if err := o.Validate(); err != nil {
+		return grid{}, err
+	}
+
+	code, err := qr.Encode(text, o.Level)
+	if err != nil {
+		return grid{}, err
+	}
+
+	full := code.Size + 2*o.Margin
+
+	return grid{
+		code:   code,
+		margin: o.Margin,
+		scale:  max(o.Size/full, 1),
+		full:   full,
+	}, nil
+}
+
+// black reports whether module (x, y) of the framed grid is dark.
+func (g grid) black(x, y int) bool {
+	x, y = x-g.margin, y-g.margin
+	if x < 0 || y < 0 || x >= g.code.Size || y >= g.code.Size {
+		return false
+	}
+
+	return g.code.Black(x, y)
+}
+
+// PNG renders text as a QR code PNG image.
+func PNG(text string, o Options) ([]byte, error) {
+	g, err := encode(text, o)
+	if err != nil {
+		return nil, err
+	}
+
+	side := g.full * g.scale
+	img := image.NewPaletted(image.Rect(0, 0, side, side), color.Palette{o.Background, o.Foreground})
+
+	for y := 0; y < g.full; y++ {
+		for x := 0; x < g.full; x++ {
+			if !g.black(x, y) {
+				continue
+			}
+			for dy := 0; dy < g.scale; dy++ {
+				for dx := 0; dx < g.scale; dx++ {
+					img.SetColorIndex(x*g.scale+dx, y*g.scale+dy, 1)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// SVG renders text as a QR code SVG image. Координаты в модулях, масштабирует viewBox.
+func SVG(text string, o Options) ([]byte, error) {
+	g, err := encode(text, o)
+	if err != nil {
+		return nil, err
+	}
+
+	side := g.full * g.scale
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		side, side, g.full, g.full)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="%s"/>`, g.full, g.full, hex(o.Background))
+	fmt.Fprintf(&buf, `<path fill="%s" d="`, hex(o.Foreground))
+
+	// Соседние темные модули в строке объединяем в один прямоугольник
+	for y := 0; y < g.full; y++ {
+		for x := 0; x < g.full; {
+			if !g.black(x, y) {
+				x++
+				continue
+			}
+
+			start := x
+			for x < g.full && g.black(x, y) {
+				x++
+			}
+			fmt.Fprintf(&buf, "M%d %dh%dv1h-%dz", start, y, x-start, x-start)
+		}
+	}
+
+	buf.WriteString(`"/></svg>`)
+
+	return buf.Bytes(), nil
+}
+
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
@@ -0,0 +1,85 @@
+package qrcode
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"rsc.io/qr"
+)
+
+func TestPNG(t *testing.T) {
+	o := DefaultOptions()
+	o.Foreground = color.RGBA{R: 0xff, A: 0xff}
+
+	data, err := PNG("http://localhost:8080/abc", o)
+	require.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	code, err := qr.Encode("http://localhost:8080/abc", qr.M)
+	require.NoError(t, err)
+
+	full := code.Size + 2*o.Margin
+	scale := o.Size / full
+	side := full * scale
+
+	b := img.Bounds()
+	assert.Equal(t, side, b.Dx())
+	assert.Equal(t, side, b.Dy())
+
+	// Рамка - цвет фона, левый верхний угол finder pattern - цвет модулей
+	r, g, bl, _ := img.At(0, 0).RGBA()
+	assert.Equal(t, [3]uint32{0xffff, 0xffff, 0xffff}, [3]uint32{r, g, bl})
+
+	r, g, bl, _ = img.At(o.Margin*scale, o.Margin*scale).RGBA()
+	assert.Equal(t, [3]uint32{0xffff, 0, 0}, [3]uint32{r, g, bl})
+}
+
+func TestSVG(t *testing.T) {
+	o := DefaultOptions()
+	o.Background = color.RGBA{R: 0x12, G: 0x34, B: 0x56, A: 0xff}
+
+	data, err := SVG("http://localhost:8080/abc", o)
+	require.NoError(t, err)
+
+	s := string(data)
+	assert.True(t, strings.HasPrefix(s, "<svg "))
+	assert.Contains(t, s, `fill="#123456"`)
+	assert.Contains(t, s, `fill="#000000"`)
+	// Первая строка finder pattern - 7 модулей подряд
+	assert.Contains(t, s, "M4 4h7v1h-7z")
+}
+
+func TestValidate(t *testing.T) {
+	o := DefaultOptions()
+	require.NoError(t, o.Validate())
+
+	o.Size = MaxSize + 1
+	assert.Error(t, o.Validate())
+
+	o = DefaultOptions()
+	o.Margin = -1
+	assert.Error(t, o.Validate())
+}
+
+func TestParse(t *testing.T) {
+	level, err := ParseLevel("h")
+	require.NoError(t, err)
+	assert.Equal(t, qr.H, level)
+
+	_, err = ParseLevel("X")
+	assert.Error(t, err)
+
+	c, err := ParseColor("#FF8000")
+	require.NoError(t, err)
+	assert.Equal(t, color.RGBA{R: 0xff, G: 0x80, A: 0xff}, c)
+
+	_, err = ParseColor("red")
+	assert.ErrorIs(t, err, ErrInvalidColor)
+}
@@ -0,0 +1,73 @@
+// Квоты на ссылки: сколько активных и сколько новых за месяц
+
+package quota
+
+import (
+	"errors"
+	"time"
+
+	"main.go/internal/lib/auth"
+	"main.go/internal/storage"
+)
+
+var (
+	ErrActiveLinks  = errors.New("quota exceeded: too many active links")
+	ErrMonthlyLinks = errors.New("quota exceeded: too many links created this month")
+)
+
+// Limits is a quota plan. 0 - без ограничения.
+type Limits struct {
+	MaxActive   int `yaml:"max_active" json:"max_active"`
+	MaxPerMonth int `yaml:"max_per_month" json:"max_per_month"`
+}
+
+// Config describes default limits and per-user or per-key overrides.
+type Config struct {
+	Default  Limits
+	Users    map[int64]Limits  // по id пользователя
+	Subjects map[string]Limits // по subject ключа или токена: "key:12", "jwt:alice"
+}
+
+// Quotas picks limits for principals. Безопасен для параллельного использования (только чтение).
+type Quotas struct {
+	cfg Config
+}
+
+func New(cfg Config) *Quotas {
+	return &Quotas{cfg: cfg}
+}
+
+// For returns the limits of the principal: переопределение для ключа важнее, чем для пользователя.
+func (q *Quotas) For(p auth.Principal) Limits {
+	if l, ok := q.cfg.Subjects[p.Subject]; ok {
+		return l
+	}
+	if l, ok := q.cfg.Users[p.UserID]; ok && p.UserID != 0 {
+		return l
+	}
+
+	return q.cfg.Default
+}
+
+// Allow reports whether one more link fits into the limits.
+func (l Limits) Allow(u storage.Usage) error {
+	if l.MaxActive > 0 && u.Active >= l.MaxActive {
+		return ErrActiveLinks
+	}
+	if l.MaxPerMonth > 0 && u.ThisMonth >= l.MaxPerMonth {
+		return ErrMonthlyLinks
+	}
+
+	return nil
+}
+
+// Unlimited reports whether the limits restrict nothing.
+func (l Limits) Unlimited() bool {
+	return l.MaxActive <= 0 && l.MaxPerMonth <= 0
+}
+
+// MonthStart returns the beginning of the quota month containing t (UTC).
+func MonthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
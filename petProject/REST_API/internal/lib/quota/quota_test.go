@@ -0,0 +1,37 @@
+package quota
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"main.go/internal/lib/auth"
+	"main.go/internal/storage"
+)
+
+func TestQuotas_For(t *testing.T) {
+	q := New(Config{
+		Default:  Limits{MaxActive: 10, MaxPerMonth: 100},
+		Users:    map[int64]Limits{3: {MaxActive: 50}},
+		Subjects: map[string]Limits{"key:7": {MaxPerMonth: 5}},
+	})
+
+	assert.Equal(t, Limits{MaxActive: 10, MaxPerMonth: 100}, q.For(auth.Principal{Subject: "key:1"}))
+	assert.Equal(t, Limits{MaxActive: 50}, q.For(auth.Principal{Subject: "key:2", UserID: 3}))
+	assert.Equal(t, Limits{MaxPerMonth: 5}, q.For(auth.Principal{Subject: "key:7", UserID: 3}))
+}
+
+func TestLimits_Allow(t *testing.T) {
+	l := Limits{MaxActive: 2, MaxPerMonth: 3}
+
+	assert.NoError(t, l.Allow(storage.Usage{Active: 1, ThisMonth: 2}))
+	assert.ErrorIs(t, l.Allow(storage.Usage{Active: 2, ThisMonth: 2}), ErrActiveLinks)
+	assert.ErrorIs(t, l.Allow(storage.Usage{Active: 1, ThisMonth: 3}), ErrMonthlyLinks)
+	assert.NoError(t, Limits{}.Allow(storage.Usage{Active: 1000, ThisMonth: 1000}))
+	assert.True(t, Limits{}.Unlimited())
+}
+
+func TestMonthStart(t *testing.T) {
+	msk := time.FixedZone("MSK", 3*60*60)
+	assert.Equal(t, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), MonthStart(time.Date(2025, 3, 1, 1, 0, 0, 0, msk)))
+}
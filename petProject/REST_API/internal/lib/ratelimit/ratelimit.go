@@ -0,0 +1,120 @@
+// Ограничение частоты запросов по алгоритму token bucket
+
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit is a token bucket: Burst requests at once, then Requests per Per.
+type Limit struct {
+	Requests int           // сколько запросов восстанавливается за Per, 0 - без ограничений
+	Per      time.Duration // окно восстановления
+	Burst    int           // емкость ведра, 0 - равна Requests
+}
+
+// Enabled reports whether the limit restricts anything.
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Per > 0
+}
+
+func (l Limit) capacity() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.Requests)
+}
+
+// rate returns tokens per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	Allowed    bool
+	Limit      int           // емкость ведра
+	Remaining  int           // сколько токенов осталось после запроса
+	Reset      time.Duration // через сколько ведро заполнится полностью
+	RetryAfter time.Duration // через сколько появится токен, если запрос отклонен
+}
+
+// Store keeps buckets by key. Реализация в памяти подходит для одного экземпляра,
+// для нескольких нужен общий Store (например в Redis) с тем же поведением.
+type Store interface {
+	Take(key string, limit Limit, now time.Time) (Result, error)
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// MemoryStore is an in-process Store. Safe for concurrent use.
+// Один MemoryStore рассчитан на один Limit: для каждой группы маршрутов свой.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// sweepInterval - как часто выбрасывать заполненные ведра, чтобы map не росла бесконечно.
+const sweepInterval = time.Minute
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+// Take removes one token from the bucket of key if there is one.
+func (s *MemoryStore) Take(key string, limit Limit, now time.Time) (Result, error) {
+	capacity, rate := limit.capacity(), limit.rate()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		s.sweep(limit, now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		s.buckets[key] = b
+	}
+
+	// Пополняем ведро за прошедшее время
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+elapsed*rate)
+		b.last = now
+	}
+
+	res := Result{Limit: int(capacity)}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+
+	res.Remaining = int(b.tokens)
+	res.Reset = seconds((capacity - b.tokens) / rate)
+
+	return res, nil
+}
+
+// sweep drops buckets that would be full by now: они ничем не отличаются от новых.
+func (s *MemoryStore) sweep(limit Limit, now time.Time) {
+	full := time.Duration(limit.capacity() / limit.rate() * float64(time.Second))
+	for key, b := range s.buckets {
+		if now.Sub(b.last) >= full {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
@@ -0,0 +1,56 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore_Take(t *testing.T) {
+	s := NewMemoryStore()
+	limit := Limit{Requests: 2, Per: time.Second, Burst: 3} // токен каждые 500ms
+	now := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 2; i >= 0; i-- {
+		res, err := s.Take("k", limit, now)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 3, res.Limit)
+		assert.Equal(t, i, res.Remaining)
+	}
+
+	res, err := s.Take("k", limit, now)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 500*time.Millisecond, res.RetryAfter)
+	assert.Equal(t, 1500*time.Millisecond, res.Reset)
+
+	// Другой ключ - свое ведро
+	res, _ = s.Take("other", limit, now)
+	assert.True(t, res.Allowed)
+
+	res, _ = s.Take("k", limit, now.Add(500*time.Millisecond))
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+}
+
+func TestMemoryStore_Sweep(t *testing.T) {
+	s := NewMemoryStore()
+	limit := Limit{Requests: 10, Per: time.Second}
+	now := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	_, _ = s.Take("a", limit, now)
+	_, _ = s.Take("b", limit, now.Add(time.Minute))
+
+	// На второй минуте "a" давно заполнилось и выброшено
+	assert.Len(t, s.buckets, 1)
+	assert.Contains(t, s.buckets, "b")
+}
+
+func TestLimit_Enabled(t *testing.T) {
+	assert.False(t, Limit{}.Enabled())
+	assert.False(t, Limit{Requests: 5}.Enabled())
+	assert.True(t, Limit{Requests: 5, Per: time.Minute}.Enabled())
+}
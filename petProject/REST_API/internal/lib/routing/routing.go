@@ -0,0 +1,236 @@
+// Правила условного редиректа: платформа, язык, заголовок, время суток
+
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Типы правил
+const (
+	TypePlatform = "platform" // Value: ios, android, mobile, windows, macos, linux
+	TypeLanguage = "language" // Value: языковой тег, "de" совпадает с "de-AT", "pt-BR" только с "pt-BR"
+	TypeHeader   = "header"   // Header: имя заголовка, Value: значение (пустое - достаточно наличия)
+	TypeTime     = "time"     // Value: "09:00-18:00", Timezone: IANA, по умолчанию UTC
+)
+
+var (
+	ErrInvalidType      = errors.New("unknown rule type")
+	ErrInvalidPlatform  = errors.New("unknown platform")
+	ErrInvalidTimeRange = errors.New("time range must look like 09:00-18:00")
+)
+
+// Rule sends matching requests to Target instead of the link's default URL.
+type Rule struct {
+	ID       int64  `json:"id,omitempty"`
+	Type     string `json:"type" validate:"required,oneof=platform language header time"`
+	Header   string `json:"header,omitempty"`
+	Value    string `json:"value"`
+	Timezone string `json:"timezone,omitempty"`
+	Target   string `json:"target" validate:"required,url"`
+}
+
+// Validate checks type-specific fields that struct tags cannot express.
+func (r Rule) Validate() error {
+	switch r.Type {
+	case TypePlatform:
+		if _, ok := platforms[r.Value]; !ok {
+			return fmt.Errorf("%w: %q", ErrInvalidPlatform, r.Value)
+		}
+	case TypeLanguage:
+		if r.Value == "" {
+			return errors.New("language must not be empty")
+		}
+	case TypeHeader:
+		if r.Header == "" {
+			return errors.New("header name must not be empty")
+		}
+	case TypeTime:
+		if _, _, err := parseRange(r.Value); err != nil {
+			return err
+		}
+		if _, err := loadLocation(r.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone %q: %w", r.Timezone, err)
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidType, r.Type)
+	}
+
+	if u, err := url.Parse(r.Target); err != nil || u.Scheme == "" || u.Host == "" {
+		return errors.New("target must be an absolute URL")
+	}
+
+	return nil
+}
+
+// First returns the first rule matching the request, or nil if none match.
+func First(rules []Rule, r *http.Request, now time.Time) *Rule {
+	for i := range rules {
+		if rules[i].Match(r, now) {
+			return &rules[i]
+		}
+	}
+
+	return nil
+}
+
+// Match reports whether the request satisfies the rule.
+func (r Rule) Match(req *http.Request, now time.Time) bool {
+	switch r.Type {
+	case TypePlatform:
+		match, ok := platforms[r.Value]
+		return ok && match(req.UserAgent())
+	case TypeLanguage:
+		return matchLanguage(r.Value, req.Header.Get("Accept-Language"))
+	case TypeHeader:
+		values, ok := req.Header[http.CanonicalHeaderKey(r.Header)]
+		if !ok {
+			return false
+		}
+		if r.Value == "" {
+			return true
+		}
+		for _, v := range values {
+			if strings.EqualFold(strings.TrimSpace(v), r.Value) {
+				return true
+			}
+		}
+		return false
+	case TypeTime:
+		return matchTime(r.Value, r.Timezone, now)
+	}
+
+	return false
+}
+
+var platforms = map[string]func(ua string) bool{
+	"ios":     isIOS,
+	"android": isAndroid,
+	"mobile": func(ua string) bool {
+		return isIOS(ua) || isAndroid(ua) || strings.Contains(ua, "Mobile")
+	},
+	"windows": func(ua string) bool { return strings.Contains(ua, "Windows") },
+	"macos": func(ua string) bool {
+		return strings.Contains(ua, "Macintosh") && !isIOS(ua)
+	},
+	"linux": func(ua string) bool {
+		return strings.Contains(ua, "Linux") && !isAndroid(ua)
+	},
+}
+
+func isIOS(ua string) bool {
+	return strings.Contains(ua, "iPhone") || strings.Contains(ua, "iPad") || strings.Contains(ua, "iPod")
+}
+
+func isAndroid(ua string) bool {
+	return strings.Contains(ua, "Android")
+}
+
+// matchLanguage сравнивает правило с самым предпочтительным языком из Accept-Language.
+func matchLanguage(want, header string) bool {
+	preferred := preferredLanguage(header)
+	if preferred == "" {
+		return false
+	}
+
+	if strings.EqualFold(want, preferred) {
+		return true
+	}
+
+	// "de" совпадает с "de-AT", но "de-AT" не совпадает с "de"
+	primary, _, _ := strings.Cut(preferred, "-")
+	return !strings.Contains(want, "-") && strings.EqualFold(want, primary)
+}
+
+func preferredLanguage(header string) string {
+	type lang struct {
+		tag string
+		q   float64
+	}
+
+	var langs []lang
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+
+		langs = append(langs, lang{tag: tag, q: q})
+	}
+
+	if len(langs) == 0 {
+		return ""
+	}
+
+	sort.SliceStable(langs, func(i, j int) bool { return langs[i].q > langs[j].q })
+
+	return langs[0].tag
+}
+
+func matchTime(value, timezone string, now time.Time) bool {
+	from, to, err := parseRange(value)
+	if err != nil {
+		return false
+	}
+
+	loc, err := loadLocation(timezone)
+	if err != nil {
+		return false
+	}
+
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+
+	if from <= to {
+		return minute >= from && minute < to
+	}
+
+	// Диапазон через полночь, например 22:00-06:00
+	return minute >= from || minute < to
+}
+
+// parseRange разбирает "09:00-18:00" в минуты от начала суток.
+func parseRange(value string) (int, int, error) {
+	fromStr, toStr, ok := strings.Cut(value, "-")
+	if !ok {
+		return 0, 0, ErrInvalidTimeRange
+	}
+
+	from, err := time.Parse("15:04", strings.TrimSpace(fromStr))
+	if err != nil {
+		return 0, 0, ErrInvalidTimeRange
+	}
+	to, err := time.Parse("15:04", strings.TrimSpace(toStr))
+	if err != nil {
+		return 0, 0, ErrInvalidTimeRange
+	}
+
+	return from.Hour()*60 + from.Minute(), to.Hour()*60 + to.Minute(), nil
+}
+
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+
+	return time.LoadLocation(name)
+}
@@ -0,0 +1,112 @@
+package routing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	uaIPhone  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148"
+	uaAndroid = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Chrome/120.0 Mobile Safari/537.36"
+	uaMac     = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 Version/17.0 Safari/605.1.15"
+	uaLinux   = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 Chrome/120.0 Safari/537.36"
+)
+
+func TestRule_Match(t *testing.T) {
+	noon := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		rule    Rule
+		headers map[string]string
+		now     time.Time
+		want    bool
+	}{
+		{name: "ios on iPhone", rule: Rule{Type: TypePlatform, Value: "ios"}, headers: map[string]string{"User-Agent": uaIPhone}, want: true},
+		{name: "ios on Android", rule: Rule{Type: TypePlatform, Value: "ios"}, headers: map[string]string{"User-Agent": uaAndroid}},
+		{name: "android on Android", rule: Rule{Type: TypePlatform, Value: "android"}, headers: map[string]string{"User-Agent": uaAndroid}, want: true},
+		{name: "linux is not android", rule: Rule{Type: TypePlatform, Value: "linux"}, headers: map[string]string{"User-Agent": uaAndroid}},
+		{name: "linux on desktop", rule: Rule{Type: TypePlatform, Value: "linux"}, headers: map[string]string{"User-Agent": uaLinux}, want: true},
+		{name: "macos is not ios", rule: Rule{Type: TypePlatform, Value: "macos"}, headers: map[string]string{"User-Agent": uaIPhone}},
+		{name: "macos on Mac", rule: Rule{Type: TypePlatform, Value: "macos"}, headers: map[string]string{"User-Agent": uaMac}, want: true},
+		{name: "mobile on iPhone", rule: Rule{Type: TypePlatform, Value: "mobile"}, headers: map[string]string{"User-Agent": uaIPhone}, want: true},
+
+		{name: "language primary tag", rule: Rule{Type: TypeLanguage, Value: "de"}, headers: map[string]string{"Accept-Language": "de-AT,de;q=0.9,en;q=0.5"}, want: true},
+		{name: "language exact region", rule: Rule{Type: TypeLanguage, Value: "pt-BR"}, headers: map[string]string{"Accept-Language": "pt-br"}, want: true},
+		{name: "language other region", rule: Rule{Type: TypeLanguage, Value: "pt-BR"}, headers: map[string]string{"Accept-Language": "pt-PT"}},
+		{name: "language by quality", rule: Rule{Type: TypeLanguage, Value: "fr"}, headers: map[string]string{"Accept-Language": "en;q=0.3, fr;q=0.8"}, want: true},
+		{name: "language not preferred", rule: Rule{Type: TypeLanguage, Value: "en"}, headers: map[string]string{"Accept-Language": "en;q=0.3, fr;q=0.8"}},
+		{name: "language missing header", rule: Rule{Type: TypeLanguage, Value: "en"}},
+
+		{name: "header value", rule: Rule{Type: TypeHeader, Header: "x-beta", Value: "Yes"}, headers: map[string]string{"X-Beta": "yes"}, want: true},
+		{name: "header other value", rule: Rule{Type: TypeHeader, Header: "X-Beta", Value: "yes"}, headers: map[string]string{"X-Beta": "no"}},
+		{name: "header presence", rule: Rule{Type: TypeHeader, Header: "X-Beta"}, headers: map[string]string{"X-Beta": ""}, want: true},
+		{name: "header absent", rule: Rule{Type: TypeHeader, Header: "X-Beta"}},
+
+		{name: "time inside", rule: Rule{Type: TypeTime, Value: "09:00-18:00"}, now: noon, want: true},
+		{name: "time end exclusive", rule: Rule{Type: TypeTime, Value: "09:00-12:00"}, now: noon},
+		{name: "time timezone", rule: Rule{Type: TypeTime, Value: "09:00-12:00", Timezone: "Europe/Moscow"}, now: noon.Add(-4 * time.Hour), want: true},
+		{name: "time over midnight", rule: Rule{Type: TypeTime, Value: "22:00-06:00"}, now: time.Date(2025, 3, 10, 2, 30, 0, 0, time.UTC), want: true},
+		{name: "time outside midnight range", rule: Rule{Type: TypeTime, Value: "22:00-06:00"}, now: noon},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/alias", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			assert.Equal(t, tt.want, tt.rule.Match(req, tt.now))
+		})
+	}
+}
+
+func TestFirst(t *testing.T) {
+	rules := []Rule{
+		{Type: TypePlatform, Value: "ios", Target: "https://apps.apple.com/app"},
+		{Type: TypePlatform, Value: "android", Target: "https://play.google.com/app"},
+		{Type: TypePlatform, Value: "mobile", Target: "https://m.example.com"},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/alias", nil)
+	req.Header.Set("User-Agent", uaIPhone)
+
+	// ios и mobile подходят оба, выигрывает первое по порядку
+	got := First(rules, req, time.Now())
+	if assert.NotNil(t, got) {
+		assert.Equal(t, "https://apps.apple.com/app", got.Target)
+	}
+
+	req.Header.Set("User-Agent", uaLinux)
+	assert.Nil(t, First(rules, req, time.Now()))
+}
+
+func TestRule_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr bool
+	}{
+		{name: "platform", rule: Rule{Type: TypePlatform, Value: "ios", Target: "https://example.com"}},
+		{name: "unknown platform", rule: Rule{Type: TypePlatform, Value: "symbian", Target: "https://example.com"}, wantErr: true},
+		{name: "header without name", rule: Rule{Type: TypeHeader, Value: "x", Target: "https://example.com"}, wantErr: true},
+		{name: "bad time range", rule: Rule{Type: TypeTime, Value: "9-18", Target: "https://example.com"}, wantErr: true},
+		{name: "bad timezone", rule: Rule{Type: TypeTime, Value: "09:00-18:00", Timezone: "Mars/Base", Target: "https://example.com"}, wantErr: true},
+		{name: "relative target", rule: Rule{Type: TypeLanguage, Value: "de", Target: "/de"}, wantErr: true},
+		{name: "unknown type", rule: Rule{Type: "geo", Value: "DE", Target: "https://example.com"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
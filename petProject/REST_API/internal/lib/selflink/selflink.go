@@ -0,0 +1,134 @@
+// Ссылки на наши же короткие ссылки: поиск цепочек и циклов
+
+package selflink
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+
+	"main.go/internal/lib/aliassig"
+)
+
+const (
+	DefaultMaxDepth = 5
+
+	previewSuffix = "+" // "/abc+" - предпросмотр той же ссылки
+)
+
+var (
+	ErrLoop     = errors.New("redirect loop detected")
+	ErrTooDeep  = errors.New("too many short link hops")
+	ErrStop     = errors.New("stop resolving") // Lookup возвращает, чтобы остановиться на текущем URL
+	ErrNotAlias = errors.New("url does not point to a short link")
+)
+
+// Lookup returns the destination of the short link with the given alias.
+type Lookup func(alias string) (string, error)
+
+// Resolver recognizes URLs on our own hosts and follows them to the final destination.
+type Resolver struct {
+	hosts    map[string]struct{}
+	maxDepth int
+}
+
+// New creates a resolver. Хост с портом ("localhost:8080") совпадает только с этим портом,
+// без порта ("sho.rt") - с любым.
+func New(hosts []string, maxDepth int) *Resolver {
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxDepth
+	}
+
+	r := &Resolver{hosts: make(map[string]struct{}, len(hosts)), maxDepth: maxDepth}
+	for _, h := range hosts {
+		if h = normalizeHost(h); h != "" {
+			r.hosts[h] = struct{}{}
+		}
+	}
+
+	return r
+}
+
+// Alias returns the alias if rawURL points to one of our hosts.
+func (r *Resolver) Alias(rawURL string) (string, bool) {
+	if r == nil || len(r.hosts) == 0 {
+		return "", false
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "", false
+	}
+
+	if !r.own(u) {
+		return "", false
+	}
+
+	// Первый сегмент пути - alias, остальное - хвост для forward_path
+	alias, _, _ := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+	alias = strings.TrimSuffix(alias, previewSuffix)
+	// Подписанный alias ("abc~sig") ведет на ту же ссылку
+	alias, _, _ = aliassig.Split(alias)
+
+	return alias, true
+}
+
+// Resolve follows rawURL through our short links until it leaves our hosts.
+// from - alias, с которого начинается цепочка (пустой, если его еще нет).
+// Если lookup вернул ErrStop, возвращается последний URL цепочки.
+func (r *Resolver) Resolve(from, rawURL string, lookup Lookup) (string, error) {
+	seen := map[string]struct{}{}
+	if from != "" {
+		seen[from] = struct{}{}
+	}
+
+	for depth := 0; ; depth++ {
+		alias, ok := r.Alias(rawURL)
+		if !ok {
+			return rawURL, nil
+		}
+		if alias == "" {
+			return "", ErrNotAlias
+		}
+
+		if _, ok := seen[alias]; ok {
+			return "", ErrLoop
+		}
+		seen[alias] = struct{}{}
+
+		if depth >= r.maxDepth {
+			return "", ErrTooDeep
+		}
+
+		next, err := lookup(alias)
+		if errors.Is(err, ErrStop) {
+			return rawURL, nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		rawURL = next
+	}
+}
+
+func (r *Resolver) own(u *url.URL) bool {
+	host := normalizeHost(u.Host)
+	if _, ok := r.hosts[host]; ok {
+		return true
+	}
+
+	_, ok := r.hosts[normalizeHost(u.Hostname())]
+
+	return ok
+}
+
+func normalizeHost(h string) string {
+	h = strings.ToLower(strings.TrimSpace(h))
+	if host, port, err := net.SplitHostPort(h); err == nil {
+		return net.JoinHostPort(strings.TrimSuffix(host, "."), port)
+	}
+
+	return strings.TrimSuffix(strings.Trim(h, "[]"), ".")
+}
@@ -0,0 +1,157 @@
+package selflink
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"main.go/internal/storage"
+)
+
+func TestAlias(t *testing.T) {
+	r := New([]string{"localhost:8080", "Sho.rt", "[::1]:8080"}, 0)
+
+	tests := []struct {
+		url   string
+		alias string
+		own   bool
+	}{
+		{url: "http://localhost:8080/abc", alias: "abc", own: true},
+		{url: "http://localhost:8080/abc+?preview=1", alias: "abc", own: true},
+		{url: "https://sho.rt/abc~Zm9vYmFy", alias: "abc", own: true},
+		{url: "https://sho.rt/abc~Zm9vYmFy+", alias: "abc", own: true},
+		{url: "http://localhost:9090/abc", own: false},
+		{url: "https://sho.rt/abc/extra/path", alias: "abc", own: true},
+		{url: "https://SHO.RT.:443/abc", alias: "abc", own: true},
+		{url: "http://[::1]:8080/abc", alias: "abc", own: true},
+		{url: "https://sho.rt", alias: "", own: true},
+		{url: "https://example.com/abc", own: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			alias, own := r.Alias(tt.url)
+			assert.Equal(t, tt.own, own)
+			assert.Equal(t, tt.alias, alias)
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	links := map[string]string{
+		"a":      "https://sho.rt/b",
+		"b":      "https://sho.rt/c",
+		"c":      "https://example.com/final",
+		"loop1":  "https://sho.rt/loop2",
+		"loop2":  "https://sho.rt/loop1",
+		"self":   "https://sho.rt/self",
+		"signed": "https://sho.rt/signed~Zm9vYmFy",
+		"secret": "https://example.com/secret",
+	}
+	lookup := func(alias string) (string, error) {
+		if alias == "secret" {
+			return "", ErrStop
+		}
+		dest, ok := links[alias]
+		if !ok {
+			return "", errors.New("not found")
+		}
+		return dest, nil
+	}
+
+	r := New([]string{"sho.rt"}, 3)
+
+	got, err := r.Resolve("", "https://sho.rt/a", lookup)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/final", got)
+
+	got, err = r.Resolve("", "https://example.com", lookup)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", got)
+
+	got, err = r.Resolve("", "https://sho.rt/secret", lookup)
+	require.NoError(t, err)
+	assert.Equal(t, "https://sho.rt/secret", got)
+
+	_, err = r.Resolve("", "https://sho.rt/loop1", lookup)
+	assert.ErrorIs(t, err, ErrLoop)
+
+	_, err = r.Resolve("self", links["self"], lookup)
+	assert.ErrorIs(t, err, ErrLoop)
+
+	// Подпись не прячет цикл: "signed~sig" - та же ссылка "signed"
+	_, err = r.Resolve("signed", links["signed"], lookup)
+	assert.ErrorIs(t, err, ErrLoop)
+
+	_, err = r.Resolve("", "https://sho.rt/", lookup)
+	assert.ErrorIs(t, err, ErrNotAlias)
+
+	_, err = r.Resolve("", "https://sho.rt/missing", lookup)
+	assert.EqualError(t, err, "not found")
+
+	_, err = New([]string{"sho.rt"}, 2).Resolve("", "https://sho.rt/a", lookup)
+	assert.ErrorIs(t, err, ErrTooDeep)
+}
+
+type getterFunc func(alias string) (storage.URL, error)
+
+func (f getterFunc) GetURL(_ context.Context, alias string) (storage.URL, error) {
+	return f(alias)
+}
+
+func TestResolveStatic(t *testing.T) {
+	links := map[string]storage.URL{
+		"static":   {Alias: "static", URL: "https://example.com/final"},
+		"password": {Alias: "password", URL: "https://example.com/secret", URLOptions: storage.URLOptions{PasswordHash: "hash"}},
+	}
+	getter := getterFunc(func(alias string) (storage.URL, error) {
+		if alias == "broken" {
+			return storage.URL{}, errors.New("db is down")
+		}
+		link, ok := links[alias]
+		if !ok {
+			return storage.URL{}, storage.ErrURLNotFound
+		}
+		return link, nil
+	})
+
+	r := New([]string{"sho.rt"}, 0)
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		dest      string
+		reject    bool
+		want      string
+		wantErr   error
+		rejection bool
+	}{
+		{name: "Foreign host", dest: "https://example.com", want: "https://example.com"},
+		{name: "Static link", dest: "https://sho.rt/static", want: "https://example.com/final"},
+		{name: "Rejected", dest: "https://sho.rt/static", reject: true, wantErr: ErrOwnHost, rejection: true},
+		{name: "Unknown link", dest: "https://sho.rt/missing", wantErr: ErrUnknownLink, rejection: true},
+		{name: "Dynamic link", dest: "https://sho.rt/password", wantErr: ErrDynamicLink, rejection: true},
+		{name: "Root of our host", dest: "https://sho.rt/", wantErr: ErrOwnHost, rejection: true},
+		{name: "Loop", dest: "https://sho.rt/self", wantErr: ErrLoop, rejection: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.ResolveStatic(ctx, getter, "self", tt.dest, tt.reject)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Equal(t, tt.rejection, IsRejection(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	// Сбой хранилища - не отказ клиенту
+	_, err := r.ResolveStatic(ctx, getter, "", "https://sho.rt/broken", false)
+	require.Error(t, err)
+	assert.False(t, IsRejection(err))
+}
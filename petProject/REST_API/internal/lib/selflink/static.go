@@ -0,0 +1,67 @@
+// Проверка адресов назначения, указывающих на наши же короткие ссылки
+
+package selflink
+
+import (
+	"context"
+	"errors"
+
+	"main.go/internal/storage"
+)
+
+// Ошибки, которые показываем клиенту, если адрес назначения - наша же ссылка
+var (
+	ErrOwnHost     = errors.New("destination points to this url shortener")
+	ErrUnknownLink = errors.New("destination points to an unknown short link")
+	ErrDynamicLink = errors.New("destination points to a short link with its own rules")
+)
+
+// Getter returns a stored link by alias.
+type Getter interface {
+	GetURL(ctx context.Context, alias string) (storage.URL, error)
+}
+
+// ResolveStatic returns the final destination for dest if it points to our own short link.
+// Цепочка раскрывается только через статические ссылки (без пароля, правил и т.п.),
+// при reject любая наша ссылка отклоняется. from - alias сохраняемой ссылки (пустой, если его еще нет).
+func (r *Resolver) ResolveStatic(ctx context.Context, getter Getter, from, dest string, reject bool) (string, error) {
+	if _, ok := r.Alias(dest); !ok {
+		return dest, nil
+	}
+
+	if reject {
+		return "", ErrOwnHost
+	}
+
+	res, err := r.Resolve(from, dest, func(alias string) (string, error) {
+		link, err := getter.GetURL(ctx, alias)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			return "", ErrUnknownLink
+		}
+		if err != nil {
+			return "", err
+		}
+		if !link.Static() {
+			return "", ErrDynamicLink
+		}
+
+		return link.URL, nil
+	})
+	if errors.Is(err, ErrNotAlias) {
+		return "", ErrOwnHost
+	}
+
+	return res, err
+}
+
+// IsRejection reports whether err from ResolveStatic should be shown to the client.
+// Остальные ошибки - сбой хранилища.
+func IsRejection(err error) bool {
+	for _, target := range []error{ErrOwnHost, ErrUnknownLink, ErrDynamicLink, ErrLoop, ErrTooDeep} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,80 @@
+// A/B-тесты: несколько адресов с весами за одной ссылкой
+
+package split
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+)
+
+// Variant is one weighted destination of a split link.
+type Variant struct {
+	Name   string `json:"name,omitempty" validate:"omitempty,max=32"` // Имя варианта в статистике, по умолчанию v1, v2...
+	URL    string `json:"url" validate:"required,url"`
+	Weight int    `json:"weight" validate:"min=1,max=10000"`
+}
+
+var ErrDuplicateName = errors.New("duplicate variant name")
+
+// Normalize fills default names and checks that names are unique.
+func Normalize(variants []Variant) ([]Variant, error) {
+	if len(variants) == 0 {
+		return nil, nil
+	}
+
+	res := make([]Variant, len(variants))
+	seen := make(map[string]bool, len(variants))
+
+	for i, v := range variants {
+		if v.Name == "" {
+			v.Name = "v" + strconv.Itoa(i+1)
+		}
+		if seen[v.Name] {
+			return nil, fmt.Errorf("%w: %q", ErrDuplicateName, v.Name)
+		}
+		seen[v.Name] = true
+		res[i] = v
+	}
+
+	return res, nil
+}
+
+// Find returns the variant with the given name.
+func Find(variants []Variant, name string) (Variant, bool) {
+	for _, v := range variants {
+		if v.Name == name {
+			return v, true
+		}
+	}
+
+	return Variant{}, false
+}
+
+// Pick chooses a variant with probability proportional to its weight.
+// rnd(n) должен возвращать число в [0, n), nil - math/rand.
+func Pick(variants []Variant, rnd func(n int) int) Variant {
+	if rnd == nil {
+		rnd = rand.IntN
+	}
+
+	total := 0
+	for _, v := range variants {
+		total += max(v.Weight, 0)
+	}
+	if total == 0 {
+		return variants[rnd(len(variants))]
+	}
+
+	n := rnd(total)
+	for _, v := range variants {
+		w := max(v.Weight, 0)
+		if n < w {
+			return v
+		}
+		n -= w
+	}
+
+	return variants[len(variants)-1]
+}
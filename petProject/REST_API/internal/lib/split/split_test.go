@@ -0,0 +1,52 @@
+package split
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPick(t *testing.T) {
+	variants := []Variant{
+		{Name: "a", URL: "https://example.com/a", Weight: 1},
+		{Name: "b", URL: "https://example.com/b", Weight: 3},
+	}
+
+	// Перебираем все значения rnd: "a" выпадает 1 раз из 4, "b" - 3 раза
+	got := map[string]int{}
+	for i := 0; i < 4; i++ {
+		v := Pick(variants, func(n int) int {
+			require.Equal(t, 4, n)
+			return i
+		})
+		got[v.Name]++
+	}
+
+	assert.Equal(t, map[string]int{"a": 1, "b": 3}, got)
+}
+
+func TestPick_Random(t *testing.T) {
+	variants := []Variant{
+		{Name: "a", Weight: 1},
+		{Name: "b", Weight: 1},
+	}
+
+	got := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		got[Pick(variants, nil).Name]++
+	}
+
+	// Грубая проверка, что выпадают оба варианта
+	assert.Greater(t, got["a"], 100)
+	assert.Greater(t, got["b"], 100)
+}
+
+func TestNormalize(t *testing.T) {
+	got, err := Normalize([]Variant{{URL: "https://a"}, {Name: "landing", URL: "https://b"}, {URL: "https://c"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v1", "landing", "v3"}, []string{got[0].Name, got[1].Name, got[2].Name})
+
+	_, err = Normalize([]Variant{{Name: "v2", URL: "https://a"}, {URL: "https://b"}})
+	assert.ErrorIs(t, err, ErrDuplicateName)
+}
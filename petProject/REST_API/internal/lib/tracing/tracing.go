@@ -0,0 +1,86 @@
+// Трассировка OpenTelemetry: провайдер, экспортер и W3C traceparent
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+type Config struct {
+	Exporter    string  // none, stdout или otlp
+	Endpoint    string  // host:port коллектора OTLP/HTTP, пустой - из OTEL_EXPORTER_OTLP_ENDPOINT
+	Insecure    bool    // OTLP без TLS
+	ServiceName string  // service.name в ресурсе
+	SampleRatio float64 // доля новых трасс (0..1), для входящего traceparent решает вызывающий
+}
+
+// Setup installs the global tracer provider and the W3C trace context propagator.
+// Shutdown сбрасывает накопленные span'ы, его нужно вызвать при остановке.
+// С экспортером none span'ы не пишутся, но traceparent из запроса все равно прокидывается в логи.
+func Setup(ctx context.Context, cfg Config) (shutdown func(context.Context) error, err error) {
+	const op = "lib.tracing.Setup"
+
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var exporter sdktrace.SpanExporter
+	switch cfg.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		opts := []otlptracehttp.Option{}
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("%s: unknown exporter %q", op, cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", cfg.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sampler(cfg.SampleRatio)),
+	)
+	otel.SetTracerProvider(tp)
+
+	return tp.Shutdown, nil
+}
+
+// sampler keeps the decision of the caller if there is one.
+func sampler(ratio float64) sdktrace.Sampler {
+	if ratio >= 1 {
+		return sdktrace.ParentBased(sdktrace.AlwaysSample())
+	}
+	return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))
+}
@@ -0,0 +1,32 @@
+package tracing_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"main.go/internal/lib/tracing"
+)
+
+func TestSetup(t *testing.T) {
+	ctx := context.Background()
+
+	shutdown, err := tracing.Setup(ctx, tracing.Config{Exporter: tracing.ExporterNone})
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(ctx))
+	assert.Equal(t, []string{"traceparent", "tracestate"}, otel.GetTextMapPropagator().Fields())
+	assert.IsType(t, propagation.TraceContext{}, otel.GetTextMapPropagator())
+
+	shutdown, err = tracing.Setup(ctx, tracing.Config{Exporter: tracing.ExporterStdout, ServiceName: "url-shortener", SampleRatio: 1})
+	require.NoError(t, err)
+	_, span := otel.Tracer("test").Start(ctx, "op")
+	assert.True(t, span.SpanContext().IsSampled())
+	span.End()
+	assert.NoError(t, shutdown(ctx))
+
+	_, err = tracing.Setup(ctx, tracing.Config{Exporter: "zipkin"})
+	assert.ErrorContains(t, err, `unknown exporter "zipkin"`)
+}
@@ -0,0 +1,264 @@
+// Политика безопасности адресов назначения: схемы, списки доменов, приватные IP
+
+package urlpolicy
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"main.go/internal/lib/logger/sl"
+)
+
+var (
+	ErrInvalidURL       = errors.New("invalid url")
+	ErrSchemeNotAllowed = errors.New("url scheme is not allowed")
+	ErrDomainNotAllowed = errors.New("domain is not in the allowlist")
+	ErrDomainDenied     = errors.New("domain is denied")
+	ErrPrivateAddress   = errors.New("private and loopback addresses are not allowed")
+	ErrBlocklisted      = errors.New("domain is in the phishing/malware blocklist")
+)
+
+// Config describes the destination policy.
+//
+// Шаблоны доменов: "example.com" - только сам домен, "*.example.com" - любой поддомен
+// (без самого example.com). В блоклисте из файла домен блокирует и все свои поддомены.
+type Config struct {
+	AllowedSchemes []string // пустой - только http и https
+	AllowDomains   []string // непустой - разрешены только эти домены
+	DenyDomains    []string
+	BlockPrivate   bool   // запрещать IP-литералы из приватных, loopback и link-local сетей, а также localhost
+	BlocklistPath  string // файл с доменами, по одному на строку, "#" - комментарий
+}
+
+// Policy checks destination URLs. Safe for concurrent use.
+type Policy struct {
+	cfg       Config
+	schemes   []string
+	blocklist atomic.Pointer[blocklist]
+}
+
+type blocklist struct {
+	domains map[string]struct{}
+	modTime time.Time
+}
+
+// New builds the policy and loads the blocklist file if it is configured.
+func New(cfg Config) (*Policy, error) {
+	const op = "lib.urlpolicy.New"
+
+	p := &Policy{cfg: cfg, schemes: []string{"http", "https"}}
+	if len(cfg.AllowedSchemes) > 0 {
+		p.schemes = make([]string, 0, len(cfg.AllowedSchemes))
+		for _, s := range cfg.AllowedSchemes {
+			p.schemes = append(p.schemes, strings.ToLower(s))
+		}
+	}
+
+	p.blocklist.Store(&blocklist{})
+	if cfg.BlocklistPath != "" {
+		if err := p.Reload(); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return p, nil
+}
+
+// Check returns nil if the URL may be used as a link destination.
+func (p *Policy) Check(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ErrInvalidURL
+	}
+
+	if !slices.Contains(p.schemes, strings.ToLower(u.Scheme)) {
+		return ErrSchemeNotAllowed
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return ErrInvalidURL
+	}
+
+	if p.cfg.BlockPrivate && isPrivate(host) {
+		return ErrPrivateAddress
+	}
+
+	if len(p.cfg.AllowDomains) > 0 && !matchAny(p.cfg.AllowDomains, host) {
+		return ErrDomainNotAllowed
+	}
+
+	if matchAny(p.cfg.DenyDomains, host) {
+		return ErrDomainDenied
+	}
+
+	if p.blocklist.Load().contains(host) {
+		return ErrBlocklisted
+	}
+
+	return nil
+}
+
+// Reload re-reads the blocklist file.
+func (p *Policy) Reload() error {
+	const op = "lib.urlpolicy.Reload"
+
+	f, err := os.Open(p.cfg.BlocklistPath)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	domains := make(map[string]struct{})
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		line = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(line)), ".")
+		if line != "" {
+			domains[line] = struct{}{}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	p.blocklist.Store(&blocklist{domains: domains, modTime: info.ModTime()})
+
+	return nil
+}
+
+// Watch polls the blocklist file and reloads it when it changes, until ctx is done.
+func (p *Policy) Watch(ctx context.Context, log *slog.Logger, interval time.Duration) {
+	if p.cfg.BlocklistPath == "" || interval <= 0 {
+		return
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		info, err := os.Stat(p.cfg.BlocklistPath)
+		if err != nil {
+			log.Error("failed to stat blocklist", sl.Err(err))
+			continue
+		}
+		if info.ModTime().Equal(p.blocklist.Load().modTime) {
+			continue
+		}
+
+		// При ошибке остается старый список
+		if err := p.Reload(); err != nil {
+			log.Error("failed to reload blocklist", sl.Err(err))
+			continue
+		}
+
+		log.Info("blocklist reloaded", slog.Int("domains", len(p.blocklist.Load().domains)))
+	}
+}
+
+// contains checks the host and all its parent domains.
+func (b *blocklist) contains(host string) bool {
+	if len(b.domains) == 0 {
+		return false
+	}
+
+	for {
+		if _, ok := b.domains[host]; ok {
+			return true
+		}
+
+		i := strings.IndexByte(host, '.')
+		if i < 0 {
+			return false
+		}
+		host = host[i+1:]
+	}
+}
+
+func matchAny(patterns []string, host string) bool {
+	for _, p := range patterns {
+		p = strings.ToLower(p)
+
+		if suffix, ok := strings.CutPrefix(p, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+			continue
+		}
+
+		if host == p {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isPrivate reports whether host is localhost or an IP literal from a non-public range.
+func isPrivate(host string) bool {
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+
+	addr, ok := parseIP(host)
+	if !ok {
+		return false
+	}
+	addr = addr.Unmap()
+
+	return addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast()
+}
+
+// parseIP understands regular literals and the inet_aton forms that browsers
+// also open: "2130706433", "0x7f.1", "0177.0.0.1" - все это 127.0.0.1.
+func parseIP(host string) (netip.Addr, bool) {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return addr, true
+	}
+
+	parts := strings.Split(host, ".")
+	if len(parts) > 4 {
+		return netip.Addr{}, false
+	}
+
+	var n uint64
+	for i, part := range parts {
+		// Последняя часть занимает все оставшиеся байты: "127.1" = 127.0.0.1
+		bits := 8
+		if i == len(parts)-1 {
+			bits = 8 * (4 - i)
+		}
+
+		v, err := strconv.ParseUint(part, 0, bits)
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		n = n<<bits | v
+	}
+
+	return netip.AddrFrom4([4]byte{byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)}), true
+}
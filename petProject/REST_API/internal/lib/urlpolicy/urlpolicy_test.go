@@ -0,0 +1,115 @@
+package urlpolicy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"main.go/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestCheck(t *testing.T) {
+	p, err := New(Config{
+		AllowDomains: []string{"example.com", "*.example.com", "*.example.org", "127.0.0.1"},
+		DenyDomains:  []string{"bad.example.com", "*.evil.example.org"},
+		BlockPrivate: true,
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		url  string
+		want error
+	}{
+		{url: "https://example.com/page", want: nil},
+		{url: "http://www.Example.com.", want: nil},
+		{url: "https://a.b.example.org", want: nil},
+		{url: "https://example.org", want: ErrDomainNotAllowed},
+		{url: "https://notexample.com", want: ErrDomainNotAllowed},
+		{url: "https://bad.example.com", want: ErrDomainDenied},
+		{url: "https://x.evil.example.org", want: ErrDomainDenied},
+		{url: "javascript:alert(1)", want: ErrSchemeNotAllowed},
+		{url: "file:///etc/passwd", want: ErrSchemeNotAllowed},
+		{url: "data:text/html,hi", want: ErrSchemeNotAllowed},
+		{url: "http:///path", want: ErrInvalidURL},
+		// Приватные адреса запрещены даже если домен в allowlist
+		{url: "http://127.0.0.1:8080/", want: ErrPrivateAddress},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			assert.ErrorIs(t, p.Check(tt.url), tt.want)
+		})
+	}
+}
+
+func TestCheck_Private(t *testing.T) {
+	p, err := New(Config{BlockPrivate: true})
+	require.NoError(t, err)
+
+	for _, u := range []string{
+		"http://localhost:8080/abc",
+		"http://api.localhost",
+		"http://10.0.0.1",
+		"http://192.168.1.10/admin",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]/",
+		"http://[::ffff:127.0.0.1]/",
+		"http://0.0.0.0",
+		"http://2130706433",
+		"http://0x7f.1",
+		"http://0177.0.0.1",
+	} {
+		assert.ErrorIs(t, p.Check(u), ErrPrivateAddress, u)
+	}
+
+	for _, u := range []string{"http://8.8.8.8", "https://example.com", "http://1.2.3"} {
+		assert.NoError(t, p.Check(u), u)
+	}
+
+	p, err = New(Config{})
+	require.NoError(t, err)
+	assert.NoError(t, p.Check("http://localhost:8080/abc"))
+}
+
+func TestCheck_Schemes(t *testing.T) {
+	p, err := New(Config{AllowedSchemes: []string{"HTTPS", "tg"}})
+	require.NoError(t, err)
+
+	assert.NoError(t, p.Check("https://example.com"))
+	assert.NoError(t, p.Check("tg://resolve?domain=example"))
+	assert.ErrorIs(t, p.Check("http://example.com"), ErrSchemeNotAllowed)
+}
+
+func TestBlocklist_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# phishing\nphish.example\n\nMalware.test # comment\n"), 0o644))
+
+	p, err := New(Config{BlocklistPath: path})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, p.Check("https://phish.example/login"), ErrBlocklisted)
+	assert.ErrorIs(t, p.Check("https://cdn.malware.test"), ErrBlocklisted)
+	assert.NoError(t, p.Check("https://example.com"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Watch(ctx, slogdiscard.NewDiscardLogger(), 10*time.Millisecond)
+
+	require.NoError(t, os.WriteFile(path, []byte("example.com\n"), 0o644))
+	// Меняем mtime явно: на некоторых ФС разрешение времени модификации - секунда
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+
+	require.Eventually(t, func() bool {
+		return p.Check("https://example.com") != nil
+	}, time.Second, 10*time.Millisecond)
+	assert.NoError(t, p.Check("https://phish.example/login"))
+}
+
+func TestNew_MissingBlocklist(t *testing.T) {
+	_, err := New(Config{BlocklistPath: filepath.Join(t.TempDir(), "missing.txt")})
+	assert.Error(t, err)
+}
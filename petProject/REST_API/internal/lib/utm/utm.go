@@ -0,0 +1,75 @@
+// Метки UTM для рекламных кампаний
+
+package utm
+
+import (
+	"net/url"
+)
+
+// Params holds UTM tags appended to the destination URL at redirect time.
+type Params struct {
+	Source   string `json:"source,omitempty" yaml:"source"`
+	Medium   string `json:"medium,omitempty" yaml:"medium"`
+	Campaign string `json:"campaign,omitempty" yaml:"campaign"`
+	Term     string `json:"term,omitempty" yaml:"term"`
+	Content  string `json:"content,omitempty" yaml:"content"`
+}
+
+// IsZero reports whether no tag is set.
+func (p Params) IsZero() bool {
+	return p == Params{}
+}
+
+// Merge returns p with empty fields filled from base (например, из пресета в конфиге).
+func (p Params) Merge(base Params) Params {
+	if p.Source == "" {
+		p.Source = base.Source
+	}
+	if p.Medium == "" {
+		p.Medium = base.Medium
+	}
+	if p.Campaign == "" {
+		p.Campaign = base.Campaign
+	}
+	if p.Term == "" {
+		p.Term = base.Term
+	}
+	if p.Content == "" {
+		p.Content = base.Content
+	}
+
+	return p
+}
+
+// Apply appends the tags to u's query. Параметры, которые уже есть в URL, не дублируются.
+func Apply(u *url.URL, p Params) {
+	if p.IsZero() {
+		return
+	}
+
+	existing := u.Query()
+	add := url.Values{}
+
+	for _, tag := range []struct{ key, value string }{
+		{"utm_source", p.Source},
+		{"utm_medium", p.Medium},
+		{"utm_campaign", p.Campaign},
+		{"utm_term", p.Term},
+		{"utm_content", p.Content},
+	} {
+		if tag.value == "" || existing.Has(tag.key) {
+			continue
+		}
+
+		add.Set(tag.key, tag.value)
+	}
+
+	if len(add) == 0 {
+		return
+	}
+
+	if u.RawQuery != "" {
+		u.RawQuery += "&"
+	}
+	u.RawQuery += add.Encode()
+}
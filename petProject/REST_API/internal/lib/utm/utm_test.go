@@ -0,0 +1,60 @@
+package utm
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name   string
+		url    string
+		params Params
+		want   string
+	}{
+		{
+			name: "no params",
+			url:  "https://example.com/?a=1",
+			want: "https://example.com/?a=1",
+		},
+		{
+			name:   "all params",
+			url:    "https://example.com/page",
+			params: Params{Source: "newsletter", Medium: "email", Campaign: "spring sale", Term: "shoes", Content: "top"},
+			want:   "https://example.com/page?utm_campaign=spring+sale&utm_content=top&utm_medium=email&utm_source=newsletter&utm_term=shoes",
+		},
+		{
+			name:   "existing params are not duplicated",
+			url:    "https://example.com/?utm_source=twitter&a=1",
+			params: Params{Source: "newsletter", Medium: "email"},
+			want:   "https://example.com/?utm_source=twitter&a=1&utm_medium=email",
+		},
+		{
+			name:   "all params already present",
+			url:    "https://example.com/?utm_source=x&utm_medium=y",
+			params: Params{Source: "newsletter", Medium: "email"},
+			want:   "https://example.com/?utm_source=x&utm_medium=y",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			require.NoError(t, err)
+
+			Apply(u, tt.params)
+
+			assert.Equal(t, tt.want, u.String())
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	preset := Params{Source: "newsletter", Medium: "email", Campaign: "spring"}
+
+	got := Params{Campaign: "summer", Content: "banner"}.Merge(preset)
+
+	assert.Equal(t, Params{Source: "newsletter", Medium: "email", Campaign: "summer", Content: "banner"}, got)
+}
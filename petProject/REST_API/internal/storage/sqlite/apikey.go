@@ -0,0 +1,153 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"main.go/internal/storage"
+)
+
+// SaveAPIKey stores a new API key by its hash.
+func (s *Storage) SaveAPIKey(ctx context.Context, key storage.APIKey, hash string) (_ int64, err error) {
+	const op = "storage.sqlite.SaveAPIKey"
+	ctx, done := s.track(ctx, op)
+	defer done(&err)
+
+	if key.UserID != 0 {
+		var exists bool
+		err := s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = ?)", key.UserID).Scan(&exists)
+		if err != nil {
+			return 0, fmt.Errorf("%s: check user: %w", op, err)
+		}
+		if !exists {
+			return 0, storage.ErrUserNotFound
+		}
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+    INSERT INTO api_key(user_id, name, prefix, hash, scopes, created_at, expires_at)
+    VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		key.UserID, key.Name, key.Prefix, hash, strings.Join(key.Scopes, ","), toUnix(key.CreatedAt), toUnix(key.ExpiresAt))
+	if err != nil {
+		return 0, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get last insert id: %w", op, err)
+	}
+
+	return id, nil
+}
+
+const apiKeyColumns = "id, user_id, name, prefix, scopes, created_at, expires_at, last_used_at, revoked_at"
+
+// GetAPIKeyByHash returns the key with the given hash, including revoked and expired ones.
+func (s *Storage) GetAPIKeyByHash(ctx context.Context, hash string) (_ storage.APIKey, err error) {
+	const op = "storage.sqlite.GetAPIKeyByHash"
+	ctx, done := s.track(ctx, op)
+	defer done(&err)
+
+	key, err := scanAPIKey(s.db.QueryRowContext(ctx, "SELECT "+apiKeyColumns+" FROM api_key WHERE hash = ?", hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.APIKey{}, storage.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return storage.APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return key, nil
+}
+
+// ListAPIKeys returns all issued keys, newest first.
+func (s *Storage) ListAPIKeys(ctx context.Context) (_ []storage.APIKey, err error) {
+	const op = "storage.sqlite.ListAPIKeys"
+	ctx, done := s.track(ctx, op)
+	defer done(&err)
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+apiKeyColumns+" FROM api_key ORDER BY id DESC")
+	if err != nil {
+		return nil, fmt.Errorf("%s: query keys: %w", op, err)
+	}
+	defer rows.Close()
+
+	var keys []storage.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: query keys: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+// TouchAPIKey records when the key was last used.
+func (s *Storage) TouchAPIKey(ctx context.Context, id int64, at time.Time) (err error) {
+	const op = "storage.sqlite.TouchAPIKey"
+	ctx, done := s.track(ctx, op)
+	defer done(&err)
+
+	if _, err := s.db.ExecContext(ctx, "UPDATE api_key SET last_used_at = ? WHERE id = ?", at.Unix(), id); err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return nil
+}
+
+// RevokeAPIKey marks the key as revoked. Запись остается, чтобы было видно, кто и когда ей пользовался.
+func (s *Storage) RevokeAPIKey(ctx context.Context, id int64, at time.Time) (err error) {
+	const op = "storage.sqlite.RevokeAPIKey"
+	ctx, done := s.track(ctx, op)
+	defer done(&err)
+
+	res, err := s.db.ExecContext(ctx, "UPDATE api_key SET revoked_at = ? WHERE id = ? AND revoked_at = 0", at.Unix(), id)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: get rows affected: %w", op, err)
+	}
+	if n == 0 {
+		return storage.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAPIKey(row scanner) (storage.APIKey, error) {
+	var (
+		key                                     storage.APIKey
+		scopes                                  string
+		createdAt, expiresAt, lastUsed, revoked int64
+	)
+
+	err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &scopes, &createdAt, &expiresAt, &lastUsed, &revoked)
+	if err != nil {
+		return storage.APIKey{}, err
+	}
+
+	if scopes != "" {
+		key.Scopes = strings.Split(scopes, ",")
+	}
+	key.CreatedAt = fromUnix(createdAt)
+	key.ExpiresAt = fromUnix(expiresAt)
+	key.LastUsedAt = fromUnix(lastUsed)
+	key.RevokedAt = fromUnix(revoked)
+
+	return key, nil
+}
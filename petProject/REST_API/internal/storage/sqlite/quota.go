@@ -0,0 +1,34 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"main.go/internal/storage"
+)
+
+// GetUsage counts links of the user, or of the API key if userID is 0.
+// Активные - не истекшие по not_after и не исчерпавшие max_clicks на момент now.
+func (s *Storage) GetUsage(ctx context.Context, userID int64, createdBy string, since, now time.Time) (_ storage.Usage, err error) {
+	const op = "storage.sqlite.GetUsage"
+	ctx, done := s.track(ctx, op)
+	defer done(&err)
+
+	where, arg := "owner_id = ?", any(userID)
+	if userID == 0 {
+		where, arg = "created_by = ?", createdBy
+	}
+
+	var u storage.Usage
+	err = s.db.QueryRowContext(ctx, `
+    SELECT
+        COALESCE(SUM(CASE WHEN (not_after = 0 OR not_after > ?) AND (max_clicks = 0 OR clicks < max_clicks) THEN 1 ELSE 0 END), 0),
+        COALESCE(SUM(CASE WHEN created_at >= ? THEN 1 ELSE 0 END), 0)
+    FROM url WHERE `+where, now.Unix(), since.Unix(), arg).Scan(&u.Active, &u.ThisMonth)
+	if err != nil {
+		return storage.Usage{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return u, nil
+}
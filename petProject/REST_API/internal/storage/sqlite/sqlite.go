@@ -1,22 +1,57 @@
 package sqlite
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/mattn/go-sqlite3"
 	_ "github.com/mattn/go-sqlite3" // init sqlite driver
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"main.go/internal/lib/routing"
+	"main.go/internal/lib/split"
 	"main.go/internal/storage"
+	"strings"
+	"time"
 )
 
+// tracer создает дочерние спаны для вызовов хранилища. Пока трассировка не настроена - no-op.
+var tracer = otel.Tracer("main.go/internal/storage/sqlite")
+
 type Storage struct {
-	db *sql.DB
+	db       *sql.DB
+	observer Observer
+}
+
+// Observer is notified about every storage call: для метрик задержек и ошибок.
+type Observer interface {
+	ObserveStorage(op string, d time.Duration, err error)
+}
+
+// Option configures the storage.
+type Option func(*Storage)
+
+// WithObserver reports the duration and result of every storage call to o.
+func WithObserver(o Observer) Option {
+	return func(s *Storage) {
+		s.observer = o
+	}
 }
 
-func New(storagePath string) (*Storage, error) {
+func New(storagePath string, opts ...Option) (*Storage, error) {
 	const op = "storage.sqlite.New" // Имя текущей функции для логов и ошибок
 
-	db, err := sql.Open("sqlite3", storagePath) // Подключаемся к БД
+	// busy_timeout - параллельные записи (например, ConsumeClick) ждут блокировку, а не падают с "database is locked"
+	// foreign_keys - чтобы правила удалялись вместе со ссылкой (ON DELETE CASCADE)
+	dsn := storagePath
+	if !strings.Contains(dsn, "?") {
+		dsn += "?_busy_timeout=5000&_foreign_keys=on"
+	}
+
+	db, err := sql.Open("sqlite3", dsn) // Подключаемся к БД
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
@@ -38,18 +73,149 @@ func New(storagePath string) (*Storage, error) {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return &Storage{db: db}, nil
+	if err := migrate(db); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s := &Storage{db: db}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, nil
 }
 
-func (s *Storage) SaveURL(urlToSave string, alias string) (int64, error) { // Метод реализует Storage
+// track starts a child span of ctx and times the op. Использование:
+//
+//	ctx, done := s.track(ctx, op)
+//	defer done(&err)
+func (s *Storage) track(ctx context.Context, op string) (context.Context, func(err *error)) {
+	ctx, span := tracer.Start(ctx, op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "sqlite"),
+			attribute.String("db.operation", strings.TrimPrefix(op, "storage.sqlite.")),
+		),
+	)
+
+	start := time.Now()
+	return ctx, func(err *error) {
+		if *err != nil && !storage.Expected(*err) {
+			span.RecordError(*err)
+			span.SetStatus(codes.Error, (*err).Error())
+		}
+		span.End()
+
+		if s.observer != nil {
+			s.observer.ObserveStorage(op, time.Since(start), *err)
+		}
+	}
+}
+
+// migrations добавляют новые колонки в уже существующую таблицу url и новые таблицы.
+// CREATE TABLE IF NOT EXISTS не меняет старые базы, поэтому колонки добавляются отдельно.
+var migrations = []string{
+	"ALTER TABLE url ADD COLUMN forward_query INTEGER NOT NULL DEFAULT 0",
+	"ALTER TABLE url ADD COLUMN forward_path INTEGER NOT NULL DEFAULT 0",
+	"ALTER TABLE url ADD COLUMN utm_source TEXT NOT NULL DEFAULT ''",
+	"ALTER TABLE url ADD COLUMN utm_medium TEXT NOT NULL DEFAULT ''",
+	"ALTER TABLE url ADD COLUMN utm_campaign TEXT NOT NULL DEFAULT ''",
+	"ALTER TABLE url ADD COLUMN utm_term TEXT NOT NULL DEFAULT ''",
+	"ALTER TABLE url ADD COLUMN utm_content TEXT NOT NULL DEFAULT ''",
+	"ALTER TABLE url ADD COLUMN password_hash TEXT NOT NULL DEFAULT ''",
+	"ALTER TABLE url ADD COLUMN max_clicks INTEGER NOT NULL DEFAULT 0",
+	"ALTER TABLE url ADD COLUMN clicks INTEGER NOT NULL DEFAULT 0",
+	"ALTER TABLE url ADD COLUMN not_before INTEGER NOT NULL DEFAULT 0", // unix-время, 0 - не задано
+	"ALTER TABLE url ADD COLUMN not_after INTEGER NOT NULL DEFAULT 0",
+	`CREATE TABLE IF NOT EXISTS url_rule(
+        id INTEGER PRIMARY KEY,
+        url_id INTEGER NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+        position INTEGER NOT NULL,
+        type TEXT NOT NULL,
+        header TEXT NOT NULL DEFAULT '',
+        value TEXT NOT NULL DEFAULT '',
+        timezone TEXT NOT NULL DEFAULT '',
+        target TEXT NOT NULL)`,
+	"CREATE INDEX IF NOT EXISTS idx_url_rule_url_id ON url_rule(url_id, position)",
+	"ALTER TABLE url ADD COLUMN sticky_variant INTEGER NOT NULL DEFAULT 0",
+	`CREATE TABLE IF NOT EXISTS url_variant(
+        id INTEGER PRIMARY KEY,
+        url_id INTEGER NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+        position INTEGER NOT NULL,
+        name TEXT NOT NULL,
+        url TEXT NOT NULL,
+        weight INTEGER NOT NULL,
+        UNIQUE(url_id, name))`,
+	`CREATE TABLE IF NOT EXISTS url_click(
+        id INTEGER PRIMARY KEY,
+        url_id INTEGER NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+        variant TEXT NOT NULL DEFAULT '',
+        created_at INTEGER NOT NULL)`,
+	"CREATE INDEX IF NOT EXISTS idx_url_click_url_id ON url_click(url_id, variant)",
+	"ALTER TABLE url ADD COLUMN interstitial INTEGER NOT NULL DEFAULT 0",
+	"ALTER TABLE url ADD COLUMN created_at INTEGER NOT NULL DEFAULT 0",
+	`CREATE TABLE IF NOT EXISTS api_key(
+        id INTEGER PRIMARY KEY,
+        name TEXT NOT NULL,
+        prefix TEXT NOT NULL,
+        hash TEXT NOT NULL UNIQUE,
+        scopes TEXT NOT NULL,
+        created_at INTEGER NOT NULL,
+        expires_at INTEGER NOT NULL DEFAULT 0,
+        last_used_at INTEGER NOT NULL DEFAULT 0,
+        revoked_at INTEGER NOT NULL DEFAULT 0)`,
+	`CREATE TABLE IF NOT EXISTS users(
+        id INTEGER PRIMARY KEY,
+        name TEXT NOT NULL UNIQUE,
+        created_at INTEGER NOT NULL)`,
+	"ALTER TABLE url ADD COLUMN owner_id INTEGER NOT NULL DEFAULT 0", // 0 - ссылки, созданные до появления пользователей
+	"CREATE INDEX IF NOT EXISTS idx_url_owner_id ON url(owner_id, id)",
+	"ALTER TABLE api_key ADD COLUMN user_id INTEGER NOT NULL DEFAULT 0",
+	"ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'editor'",
+	"ALTER TABLE url ADD COLUMN created_by TEXT NOT NULL DEFAULT ''",
+	"CREATE INDEX IF NOT EXISTS idx_url_created_by ON url(created_by, created_at)",
+	"ALTER TABLE url ADD COLUMN private INTEGER NOT NULL DEFAULT 0",
+}
+
+func migrate(db *sql.DB) error {
+	for _, m := range migrations {
+		if _, err := db.Exec(m); err != nil {
+			// Колонка уже есть - миграция применялась раньше
+			if strings.Contains(err.Error(), "duplicate column name") {
+				continue
+			}
+			return fmt.Errorf("migrate %q: %w", m, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) SaveURL(ctx context.Context, urlToSave string, alias string, opts storage.URLOptions) (_ int64, err error) { // Метод реализует Storage
 	const op = "storage.sqlite.SaveURL"
+	ctx, done := s.track(ctx, op)
+	defer done(&err)
+
+	// Ссылка и ее варианты сохраняются вместе или не сохраняются вовсе
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
 
-	stmt, err := s.db.Prepare("INSERT INTO url(url, alias) VALUES (?, ?)") // Подготавливает запрос к запуску
+	stmt, err := tx.PrepareContext(ctx, `
+    INSERT INTO url(url, alias, forward_query, forward_path,
+        utm_source, utm_medium, utm_campaign, utm_term, utm_content, password_hash, max_clicks,
+        not_before, not_after, sticky_variant, interstitial, created_at, owner_id, created_by, private)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`) // Подготавливает запрос к запуску
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
+	defer stmt.Close()
 
-	res, err := stmt.Exec(urlToSave, alias)
+	res, err := stmt.ExecContext(ctx, urlToSave, alias, opts.ForwardQuery, opts.ForwardPath,
+		opts.UTM.Source, opts.UTM.Medium, opts.UTM.Campaign, opts.UTM.Term, opts.UTM.Content, opts.PasswordHash, opts.MaxClicks,
+		toUnix(opts.NotBefore), toUnix(opts.NotAfter), opts.StickyVariant, opts.Interstitial, time.Now().Unix(), opts.OwnerID, opts.CreatedBy, opts.Private)
 	if err != nil {
 		// err.(sqlite3.Error) - преобразуем ошибку внутреннему типу sqlite
 		// if sqliteErr.ExtendedCode равен sqlite3.Err..., то проблема с Constraints
@@ -65,43 +231,508 @@ func (s *Storage) SaveURL(urlToSave string, alias string) (int64, error) { // М
 		return 0, fmt.Errorf("%s: failed to get last insert id: %w", op, err)
 	}
 
+	for i, v := range opts.Variants {
+		_, err := tx.ExecContext(ctx, "INSERT INTO url_variant(url_id, position, name, url, weight) VALUES (?, ?, ?, ?, ?)",
+			id, i, v.Name, v.URL, v.Weight)
+		if err != nil {
+			return 0, fmt.Errorf("%s: save variant: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: commit: %w", op, err)
+	}
+
 	return id, nil
 }
 
-func (s *Storage) GetURL(alias string) (string, error) {
+func (s *Storage) GetURL(ctx context.Context, alias string) (_ storage.URL, err error) {
 	const op = "storage.sqlite.GetURL"
+	ctx, done := s.track(ctx, op)
+	defer done(&err)
 
 	// SELECT url - выбираем данные из колонки url
 	// FROM url - из таблицы urls
 	// WHERE alias = ? - ищем строку, где alias(колонка) совпадает с переданным значением (?)
 	// stmt - выражение, подготовленный файл
-	stmt, err := s.db.Prepare("SELECT url FROM url WHERE alias = ?")
+	stmt, err := s.db.PrepareContext(ctx, `
+    SELECT id, alias, url, forward_query, forward_path,
+        utm_source, utm_medium, utm_campaign, utm_term, utm_content, password_hash, max_clicks, clicks,
+        not_before, not_after, sticky_variant, interstitial, created_at, owner_id, created_by, private
+    FROM url WHERE alias = ?`)
+	if err != nil {
+		return storage.URL{}, fmt.Errorf("%s: prepare state statement: %w", op, err)
+	}
+
+	var res storage.URL // Переменная в которую положим найденную ссылку
+	var notBefore, notAfter, createdAt int64
+	err = stmt.QueryRowContext(ctx, alias).Scan(&res.ID, &res.Alias, &res.URL, &res.ForwardQuery, &res.ForwardPath,
+		&res.UTM.Source, &res.UTM.Medium, &res.UTM.Campaign, &res.UTM.Term, &res.UTM.Content, &res.PasswordHash,
+		&res.MaxClicks, &res.Clicks, &notBefore, &notAfter, &res.StickyVariant,
+		&res.Interstitial, &createdAt, &res.OwnerID, &res.CreatedBy, &res.Private)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.URL{}, storage.ErrURLNotFound
+	}
+	if err != nil {
+		return storage.URL{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	res.NotBefore = fromUnix(notBefore)
+	res.NotAfter = fromUnix(notAfter)
+	res.CreatedAt = fromUnix(createdAt)
+
+	res.Rules, err = s.rules(ctx, res.ID)
+	if err != nil {
+		return storage.URL{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res.Variants, err = s.variants(ctx, res.ID)
+	if err != nil {
+		return storage.URL{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return res, nil
+}
+
+// GetURLOwner returns the user and the key (subject) that created the link.
+func (s *Storage) GetURLOwner(ctx context.Context, alias string) (_ storage.Owner, err error) {
+	const op = "storage.sqlite.GetURLOwner"
+	ctx, done := s.track(ctx, op)
+	defer done(&err)
+
+	var owner storage.Owner
+	err = s.db.QueryRowContext(ctx, "SELECT owner_id, created_by FROM url WHERE alias = ?", alias).Scan(&owner.UserID, &owner.CreatedBy)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.Owner{}, storage.ErrURLNotFound
+	}
+	if err != nil {
+		return storage.Owner{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return owner, nil
+}
+
+// ListURLs returns links selected by the filter, newest first.
+// Правила и варианты не загружаются, только основные поля.
+func (s *Storage) ListURLs(ctx context.Context, filter storage.ListFilter) (_ []storage.URL, err error) {
+	const op = "storage.sqlite.ListURLs"
+	ctx, done := s.track(ctx, op)
+	defer done(&err)
+
+	query := `
+    SELECT id, alias, url, password_hash, max_clicks, clicks, not_before, not_after, created_at, owner_id, private
+    FROM url`
+	var args []any
+	if !filter.AllOwners {
+		query += " WHERE owner_id = ?"
+		args = append(args, filter.OwnerID)
+		if filter.OwnerID == 0 {
+			// Ссылки без владельца различаем по ключу, которым они созданы
+			query += " AND created_by = ?"
+			args = append(args, filter.CreatedBy)
+		}
+	}
+	query += " ORDER BY id DESC LIMIT ? OFFSET ?"
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return "", fmt.Errorf("%s: prepare state statement: %w", op, err)
+		return nil, fmt.Errorf("%s: query urls: %w", op, err)
+	}
+	defer rows.Close()
+
+	var urls []storage.URL
+	for rows.Next() {
+		var (
+			u                              storage.URL
+			notBefore, notAfter, createdAt int64
+		)
+		err := rows.Scan(&u.ID, &u.Alias, &u.URL, &u.PasswordHash, &u.MaxClicks, &u.Clicks,
+			&notBefore, &notAfter, &createdAt, &u.OwnerID, &u.Private)
+		if err != nil {
+			return nil, fmt.Errorf("%s: scan url: %w", op, err)
+		}
+
+		u.NotBefore = fromUnix(notBefore)
+		u.NotAfter = fromUnix(notAfter)
+		u.CreatedAt = fromUnix(createdAt)
+		urls = append(urls, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: query urls: %w", op, err)
+	}
+
+	return urls, nil
+}
+
+func (s *Storage) variants(ctx context.Context, urlID int64) ([]split.Variant, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT name, url, weight FROM url_variant WHERE url_id = ? ORDER BY position", urlID)
+	if err != nil {
+		return nil, fmt.Errorf("query variants: %w", err)
+	}
+	defer rows.Close()
+
+	var variants []split.Variant
+	for rows.Next() {
+		var v split.Variant
+		if err := rows.Scan(&v.Name, &v.URL, &v.Weight); err != nil {
+			return nil, fmt.Errorf("scan variant: %w", err)
+		}
+		variants = append(variants, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query variants: %w", err)
 	}
 
-	var resURL string                        // Переменная в которую положим возвращаемый URL
-	err = stmt.QueryRow(alias).Scan(&resURL) // Подготавливает запрос к запуску
+	return variants, nil
+}
+
+// SaveClick records a redirect of the link and the A/B variant that was served.
+func (s *Storage) SaveClick(ctx context.Context, alias string, variant string) (err error) {
+	const op = "storage.sqlite.SaveClick"
+	ctx, done := s.track(ctx, op)
+	defer done(&err)
+
+	res, err := s.db.ExecContext(ctx, `
+    INSERT INTO url_click(url_id, variant, created_at)
+    SELECT id, ?, ? FROM url WHERE alias = ?`, variant, time.Now().Unix(), alias)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: get rows affected: %w", op, err)
+	}
+	if n == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+// GetClickStats returns the number of recorded redirects of the link.
+func (s *Storage) GetClickStats(ctx context.Context, alias string) (_ storage.ClickStats, err error) {
+	const op = "storage.sqlite.GetClickStats"
+	ctx, done := s.track(ctx, op)
+	defer done(&err)
+
+	var id int64
+	err = s.db.QueryRowContext(ctx, "SELECT id FROM url WHERE alias = ?", alias).Scan(&id)
 	if errors.Is(err, sql.ErrNoRows) {
-		return "", storage.ErrURLNotFound
+		return storage.ClickStats{}, storage.ErrURLNotFound
+	}
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT variant, COUNT(*) FROM url_click WHERE url_id = ? GROUP BY variant", id)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: query clicks: %w", op, err)
+	}
+	defer rows.Close()
+
+	var stats storage.ClickStats
+	for rows.Next() {
+		var (
+			variant string
+			count   int64
+		)
+		if err := rows.Scan(&variant, &count); err != nil {
+			return storage.ClickStats{}, fmt.Errorf("%s: scan clicks: %w", op, err)
+		}
+
+		stats.Total += count
+		if variant != "" {
+			if stats.Variants == nil {
+				stats.Variants = make(map[string]int64)
+			}
+			stats.Variants[variant] = count
+		}
 	}
+	if err := rows.Err(); err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: query clicks: %w", op, err)
+	}
+
+	return stats, nil
+}
+
+// toUnix и fromUnix переводят время в колонку INTEGER, где 0 - значение не задано
+func toUnix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+func fromUnix(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0).UTC()
+}
+
+// ConsumeClick atomically counts one redirect for a link with a click limit.
+// Проверка и увеличение счетчика делаются одним UPDATE, поэтому два параллельных
+// запроса не могут оба забрать последний клик.
+func (s *Storage) ConsumeClick(ctx context.Context, alias string) (err error) {
+	const op = "storage.sqlite.ConsumeClick"
+	ctx, done := s.track(ctx, op)
+	defer done(&err)
+
+	res, err := s.db.ExecContext(ctx, `
+    UPDATE url SET clicks = clicks + 1
+    WHERE alias = ? AND (max_clicks = 0 OR clicks < max_clicks)`, alias)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: get rows affected: %w", op, err)
+	}
+	if n > 0 {
+		return nil
+	}
+
+	// Ничего не обновили: либо ссылки нет, либо клики закончились
+	var exists bool
+	err = s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM url WHERE alias = ?)", alias).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("%s: check existence: %w", op, err)
+	}
+	if !exists {
+		return storage.ErrURLNotFound
+	}
+
+	return storage.ErrURLGone
+}
+
+// GetRules returns the link's redirect rules in evaluation order.
+func (s *Storage) GetRules(ctx context.Context, alias string) (_ []routing.Rule, err error) {
+	const op = "storage.sqlite.GetRules"
+	ctx, done := s.track(ctx, op)
+	defer done(&err)
+
+	var id int64
+	err = s.db.QueryRowContext(ctx, "SELECT id FROM url WHERE alias = ?", alias).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrURLNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	rules, err := s.rules(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return rules, nil
+}
+
+// AddRule appends a rule to the end of the link's rule list.
+func (s *Storage) AddRule(ctx context.Context, alias string, rule routing.Rule) (_ int64, err error) {
+	const op = "storage.sqlite.AddRule"
+	ctx, done := s.track(ctx, op)
+	defer done(&err)
+
+	// position считаем в том же INSERT, чтобы параллельные вставки не получили одинаковый номер
+	res, err := s.db.ExecContext(ctx, `
+    INSERT INTO url_rule(url_id, position, type, header, value, timezone, target)
+    SELECT u.id, COALESCE((SELECT MAX(position) FROM url_rule WHERE url_id = u.id), 0) + 1, ?, ?, ?, ?, ?
+    FROM url u WHERE u.alias = ?`,
+		rule.Type, rule.Header, rule.Value, rule.Timezone, rule.Target, alias)
+	if err != nil {
+		return 0, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: get rows affected: %w", op, err)
+	}
+	if n == 0 {
+		return 0, storage.ErrURLNotFound
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get last insert id: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// UpdateRule replaces the rule with rule.ID. При position > 0 правило переносится
+// на это место (с 1, больше числа правил - в конец), остальные сдвигаются.
+func (s *Storage) UpdateRule(ctx context.Context, alias string, rule routing.Rule, position int) (err error) {
+	const op = "storage.sqlite.UpdateRule"
+	ctx, done := s.track(ctx, op)
+	defer done(&err)
+
+	// Правка и перестановка применяются вместе, чтобы порядок правил не разъехался
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var urlID int64
+	err = tx.QueryRowContext(ctx, `
+    SELECT r.url_id FROM url_rule r JOIN url u ON u.id = r.url_id
+    WHERE r.id = ? AND u.alias = ?`, rule.ID, alias).Scan(&urlID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.ErrRuleNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("%s: find rule: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+    UPDATE url_rule SET type = ?, header = ?, value = ?, timezone = ?, target = ?
+    WHERE id = ?`,
+		rule.Type, rule.Header, rule.Value, rule.Timezone, rule.Target, rule.ID)
+	if err != nil {
+		return fmt.Errorf("%s: update rule: %w", op, err)
+	}
+
+	if position > 0 {
+		if err := moveRule(ctx, tx, urlID, rule.ID, position); err != nil {
+			return fmt.Errorf("%s: move rule: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit: %w", op, err)
+	}
+
+	return nil
+}
+
+// moveRule puts the rule at position and renumbers the link's rules from 1.
+func moveRule(ctx context.Context, tx *sql.Tx, urlID, id int64, position int) error {
+	rows, err := tx.QueryContext(ctx, "SELECT id FROM url_rule WHERE url_id = ? AND id != ? ORDER BY position", urlID, id)
+	if err != nil {
+		return err
+	}
+	var ids []int64
+	for rows.Next() {
+		var other int64
+		if err := rows.Scan(&other); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, other)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	i := min(position-1, len(ids))
+	ids = append(ids[:i], append([]int64{id}, ids[i:]...)...)
+
+	for pos, ruleID := range ids {
+		if _, err := tx.ExecContext(ctx, "UPDATE url_rule SET position = ? WHERE id = ?", pos+1, ruleID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteRule removes one rule of the link.
+func (s *Storage) DeleteRule(ctx context.Context, alias string, id int64) (err error) {
+	const op = "storage.sqlite.DeleteRule"
+	ctx, done := s.track(ctx, op)
+	defer done(&err)
+
+	res, err := s.db.ExecContext(ctx, `
+    DELETE FROM url_rule
+    WHERE id = ? AND url_id = (SELECT id FROM url WHERE alias = ?)`, id, alias)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: get rows affected: %w", op, err)
+	}
+	if n == 0 {
+		return storage.ErrRuleNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) rules(ctx context.Context, urlID int64) ([]routing.Rule, error) {
+	rows, err := s.db.QueryContext(ctx, `
+    SELECT id, type, header, value, timezone, target
+    FROM url_rule WHERE url_id = ? ORDER BY position`, urlID)
+	if err != nil {
+		return nil, fmt.Errorf("query rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []routing.Rule
+	for rows.Next() {
+		var r routing.Rule
+		if err := rows.Scan(&r.ID, &r.Type, &r.Header, &r.Value, &r.Timezone, &r.Target); err != nil {
+			return nil, fmt.Errorf("scan rule: %w", err)
+		}
+		rules = append(rules, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+// DeleteURLs removes links by aliases together with their rules, variants and clicks.
+// Возвращает, сколько ссылок было удалено: несуществующие alias пропускаются.
+func (s *Storage) DeleteURLs(ctx context.Context, aliases []string) (_ int64, err error) {
+	const op = "storage.sqlite.DeleteURLs"
+	ctx, done := s.track(ctx, op)
+	defer done(&err)
+
+	if len(aliases) == 0 {
+		return 0, nil
+	}
+
+	args := make([]any, len(aliases))
+	for i, a := range aliases {
+		args[i] = a
+	}
+
+	query := "DELETE FROM url WHERE alias IN (?" + strings.Repeat(", ?", len(aliases)-1) + ")"
+
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
 	if err != nil {
-		return "", fmt.Errorf("%s: execute statement: %w", op, err)
+		return 0, fmt.Errorf("%s: get rows affected: %w", op, err)
 	}
 
-	return resURL, nil
+	return n, nil
 }
 
-func (s *Storage) DeleteURL(alias string) error {
+// DeleteURL removes the link together with its rules, variants and clicks.
+func (s *Storage) DeleteURL(ctx context.Context, alias string) (err error) {
 	const op = "storage.sqlite.DeleteURL"
+	ctx, done := s.track(ctx, op)
+	defer done(&err)
 
-	stmt, err := s.db.Prepare("DELETE url FROM url WHERE alias = ?")
+	stmt, err := s.db.PrepareContext(ctx, "DELETE FROM url WHERE alias = ?")
 	if err != nil {
 		return fmt.Errorf("%s: prepare state statement: %w", op, err)
 	}
 	defer stmt.Close()
 
-	res, err := stmt.Exec(alias)
+	res, err := stmt.ExecContext(ctx, alias)
 	if err != nil {
 		return fmt.Errorf("%s: Exec is not responding %w", op, err)
 	}
@@ -0,0 +1,499 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"main.go/internal/lib/routing"
+	"main.go/internal/lib/split"
+	"main.go/internal/storage"
+)
+
+func TestConsumeClick_Concurrent(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	const maxClicks = 3
+	_, err = s.SaveURL(ctx, "https://example.com", "limited", storage.URLOptions{MaxClicks: maxClicks})
+	require.NoError(t, err)
+
+	// Много параллельных редиректов - засчитаться должно ровно maxClicks
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		consumed  int
+		gone      int
+		otherErrs []error
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := s.ConsumeClick(ctx, "limited")
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				consumed++
+			case errors.Is(err, storage.ErrURLGone):
+				gone++
+			default:
+				otherErrs = append(otherErrs, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Empty(t, otherErrs)
+	assert.Equal(t, maxClicks, consumed)
+	assert.Equal(t, 20-maxClicks, gone)
+
+	u, err := s.GetURL(ctx, "limited")
+	require.NoError(t, err)
+	assert.True(t, u.Exhausted())
+}
+
+func TestConsumeClick_NotFound(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, s.ConsumeClick(ctx, "missing"), storage.ErrURLNotFound)
+}
+
+func TestSaveGetURL(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	opts := storage.URLOptions{
+		ForwardQuery: true,
+		Private:      true,
+		MaxClicks:    5,
+		NotBefore:    time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	id, err := s.SaveURL(ctx, "https://example.com", "alias", opts)
+	require.NoError(t, err)
+
+	u, err := s.GetURL(ctx, "alias")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), u.CreatedAt, time.Minute)
+
+	u.CreatedAt = time.Time{}
+	assert.Equal(t, storage.URL{ID: id, Alias: "alias", URL: "https://example.com", URLOptions: opts}, u)
+
+	_, err = s.SaveURL(ctx, "https://example.com", "alias", storage.URLOptions{})
+	assert.ErrorIs(t, err, storage.ErrURLExists)
+
+	_, err = s.GetURL(ctx, "missing")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+func TestRules(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	_, err = s.SaveURL(ctx, "https://example.com", "alias", storage.URLOptions{})
+	require.NoError(t, err)
+
+	ios := routing.Rule{Type: routing.TypePlatform, Value: "ios", Target: "https://apps.apple.com/app"}
+	de := routing.Rule{Type: routing.TypeLanguage, Value: "de", Target: "https://example.com/de"}
+
+	ios.ID, err = s.AddRule(ctx, "alias", ios)
+	require.NoError(t, err)
+	de.ID, err = s.AddRule(ctx, "alias", de)
+	require.NoError(t, err)
+
+	_, err = s.AddRule(ctx, "missing", ios)
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	u, err := s.GetURL(ctx, "alias")
+	require.NoError(t, err)
+	assert.Equal(t, []routing.Rule{ios, de}, u.Rules)
+
+	require.NoError(t, s.DeleteRule(ctx, "alias", ios.ID))
+	assert.ErrorIs(t, s.DeleteRule(ctx, "alias", ios.ID), storage.ErrRuleNotFound)
+
+	rules, err := s.GetRules(ctx, "alias")
+	require.NoError(t, err)
+	assert.Equal(t, []routing.Rule{de}, rules)
+}
+
+func TestUpdateRule(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	for _, alias := range []string{"alias", "other"} {
+		_, err = s.SaveURL(ctx, "https://example.com", alias, storage.URLOptions{})
+		require.NoError(t, err)
+	}
+
+	rules := []routing.Rule{
+		{Type: routing.TypePlatform, Value: "ios", Target: "https://example.com/ios"},
+		{Type: routing.TypePlatform, Value: "android", Target: "https://example.com/android"},
+		{Type: routing.TypeLanguage, Value: "de", Target: "https://example.com/de"},
+	}
+	for i := range rules {
+		rules[i].ID, err = s.AddRule(ctx, "alias", rules[i])
+		require.NoError(t, err)
+	}
+	ios, android, de := rules[0], rules[1], rules[2]
+
+	// Правка без позиции не меняет порядок
+	android.Target = "https://example.com/android-new"
+	require.NoError(t, s.UpdateRule(ctx, "alias", android, 0))
+	got, err := s.GetRules(ctx, "alias")
+	require.NoError(t, err)
+	assert.Equal(t, []routing.Rule{ios, android, de}, got)
+
+	// Перенос в начало и за конец списка
+	require.NoError(t, s.UpdateRule(ctx, "alias", de, 1))
+	got, err = s.GetRules(ctx, "alias")
+	require.NoError(t, err)
+	assert.Equal(t, []routing.Rule{de, ios, android}, got)
+
+	require.NoError(t, s.UpdateRule(ctx, "alias", de, 10))
+	got, err = s.GetRules(ctx, "alias")
+	require.NoError(t, err)
+	assert.Equal(t, []routing.Rule{ios, android, de}, got)
+
+	// Правило другой ссылки не найти через чужой alias
+	assert.ErrorIs(t, s.UpdateRule(ctx, "other", ios, 0), storage.ErrRuleNotFound)
+	assert.ErrorIs(t, s.UpdateRule(ctx, "alias", routing.Rule{ID: 100, Type: routing.TypeLanguage, Value: "de"}, 0), storage.ErrRuleNotFound)
+
+	// Новое правило после перестановки добавляется в конец
+	fr := routing.Rule{Type: routing.TypeLanguage, Value: "fr", Target: "https://example.com/fr"}
+	fr.ID, err = s.AddRule(ctx, "alias", fr)
+	require.NoError(t, err)
+	got, err = s.GetRules(ctx, "alias")
+	require.NoError(t, err)
+	assert.Equal(t, []routing.Rule{ios, android, de, fr}, got)
+}
+
+func TestVariantsAndClicks(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	opts := storage.URLOptions{
+		Variants: []split.Variant{
+			{Name: "a", URL: "https://example.com/a", Weight: 70},
+			{Name: "b", URL: "https://example.com/b", Weight: 30},
+		},
+		StickyVariant: true,
+	}
+	_, err = s.SaveURL(ctx, "https://example.com", "alias", opts)
+	require.NoError(t, err)
+
+	u, err := s.GetURL(ctx, "alias")
+	require.NoError(t, err)
+	assert.Equal(t, opts, u.URLOptions)
+
+	for _, v := range []string{"a", "a", "b", ""} {
+		require.NoError(t, s.SaveClick(ctx, "alias", v))
+	}
+	assert.ErrorIs(t, s.SaveClick(ctx, "missing", ""), storage.ErrURLNotFound)
+
+	stats, err := s.GetClickStats(ctx, "alias")
+	require.NoError(t, err)
+	assert.Equal(t, storage.ClickStats{Total: 4, Variants: map[string]int64{"a": 2, "b": 1}}, stats)
+}
+
+func TestAPIKeys(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	created := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	id, err := s.SaveAPIKey(ctx, storage.APIKey{
+		Name:      "ci",
+		Prefix:    "sk_abcdefgh",
+		Scopes:    []string{"create", "read"},
+		CreatedAt: created,
+		ExpiresAt: created.Add(24 * time.Hour),
+	}, "hash1")
+	require.NoError(t, err)
+
+	key, err := s.GetAPIKeyByHash(ctx, "hash1")
+	require.NoError(t, err)
+	assert.Equal(t, storage.APIKey{
+		ID:        id,
+		Name:      "ci",
+		Prefix:    "sk_abcdefgh",
+		Scopes:    []string{"create", "read"},
+		CreatedAt: created,
+		ExpiresAt: created.Add(24 * time.Hour),
+	}, key)
+
+	_, err = s.GetAPIKeyByHash(ctx, "missing")
+	assert.ErrorIs(t, err, storage.ErrAPIKeyNotFound)
+
+	require.NoError(t, s.TouchAPIKey(ctx, id, created.Add(time.Hour)))
+	require.NoError(t, s.RevokeAPIKey(ctx, id, created.Add(2*time.Hour)))
+	assert.ErrorIs(t, s.RevokeAPIKey(ctx, id, created.Add(3*time.Hour)), storage.ErrAPIKeyNotFound)
+
+	keys, err := s.ListAPIKeys(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, created.Add(time.Hour), keys[0].LastUsedAt)
+	assert.Equal(t, created.Add(2*time.Hour), keys[0].RevokedAt)
+}
+
+func TestUsersAndOwnership(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	alice, err := s.SaveUser(ctx, "alice", "admin")
+	require.NoError(t, err)
+	bob, err := s.SaveUser(ctx, "bob", "editor")
+	require.NoError(t, err)
+
+	_, err = s.SaveUser(ctx, "alice", "viewer")
+	assert.ErrorIs(t, err, storage.ErrUserExists)
+
+	users, err := s.ListUsers(ctx)
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	assert.Equal(t, "bob", users[1].Name)
+	assert.Equal(t, "editor", users[1].Role)
+
+	require.NoError(t, s.SetUserRole(ctx, bob, "viewer"))
+	user, err := s.GetUser(ctx, bob)
+	require.NoError(t, err)
+	assert.Equal(t, "viewer", user.Role)
+
+	assert.ErrorIs(t, s.SetUserRole(ctx, 100, "viewer"), storage.ErrUserNotFound)
+	_, err = s.GetUser(ctx, 100)
+	assert.ErrorIs(t, err, storage.ErrUserNotFound)
+
+	for i, owner := range []int64{alice, bob, alice} {
+		_, err := s.SaveURL(ctx, "https://example.com", "link"+string(rune('a'+i)), storage.URLOptions{OwnerID: owner})
+		require.NoError(t, err)
+	}
+
+	owner, err := s.GetURLOwner(ctx, "linkb")
+	require.NoError(t, err)
+	assert.Equal(t, storage.Owner{UserID: bob}, owner)
+
+	_, err = s.GetURLOwner(ctx, "missing")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	mine, err := s.ListURLs(ctx, storage.ListFilter{OwnerID: alice, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, mine, 2)
+	assert.Equal(t, "linkc", mine[0].Alias)
+
+	all, err := s.ListURLs(ctx, storage.ListFilter{AllOwners: true, Limit: 2, Offset: 1})
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, "linkb", all[0].Alias)
+
+	// Ключи без пользователя видят только свои ссылки без владельца
+	for i, subject := range []string{"key:1", "key:2", "key:1"} {
+		_, err := s.SaveURL(ctx, "https://example.com", "anon"+string(rune('a'+i)), storage.URLOptions{CreatedBy: subject})
+		require.NoError(t, err)
+	}
+	_, err = s.SaveURL(ctx, "https://example.com", "owned", storage.URLOptions{OwnerID: alice, CreatedBy: "key:1"})
+	require.NoError(t, err)
+
+	anon, err := s.ListURLs(ctx, storage.ListFilter{CreatedBy: "key:1", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, anon, 2)
+	assert.Equal(t, "anonc", anon[0].Alias)
+	assert.Equal(t, "anona", anon[1].Alias)
+
+	anon, err = s.ListURLs(ctx, storage.ListFilter{CreatedBy: "key:2", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, anon, 1)
+	assert.Equal(t, "anonb", anon[0].Alias)
+
+	owner, err = s.GetURLOwner(ctx, "anonb")
+	require.NoError(t, err)
+	assert.Equal(t, storage.Owner{CreatedBy: "key:2"}, owner)
+
+	// Ключ можно привязать только к существующему пользователю
+	_, err = s.SaveAPIKey(ctx, storage.APIKey{UserID: 100, Name: "x", Prefix: "sk_x", Scopes: []string{"read"}}, "hash")
+	assert.ErrorIs(t, err, storage.ErrUserNotFound)
+
+	_, err = s.SaveAPIKey(ctx, storage.APIKey{UserID: alice, Name: "x", Prefix: "sk_x", Scopes: []string{"read"}}, "hash")
+	require.NoError(t, err)
+
+	key, err := s.GetAPIKeyByHash(ctx, "hash")
+	require.NoError(t, err)
+	assert.Equal(t, alice, key.UserID)
+}
+
+func TestDeleteURLs(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	for _, alias := range []string{"a", "b", "c"} {
+		_, err := s.SaveURL(ctx, "https://example.com", alias, storage.URLOptions{})
+		require.NoError(t, err)
+	}
+	_, err = s.AddRule(ctx, "a", routing.Rule{Type: routing.TypePlatform, Value: "ios", Target: "https://example.com/ios"})
+	require.NoError(t, err)
+	require.NoError(t, s.SaveClick(ctx, "a", ""))
+
+	n, err := s.DeleteURLs(ctx, []string{"a", "b", "missing"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	_, err = s.GetURL(ctx, "a")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+	_, err = s.GetURL(ctx, "c")
+	assert.NoError(t, err)
+
+	// Правила и клики удаляются вместе со ссылкой
+	var rules, clicks int
+	require.NoError(t, s.db.QueryRow("SELECT COUNT(*) FROM url_rule").Scan(&rules))
+	require.NoError(t, s.db.QueryRow("SELECT COUNT(*) FROM url_click").Scan(&clicks))
+	assert.Zero(t, rules)
+	assert.Zero(t, clicks)
+}
+
+func TestDeleteURL(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	_, err = s.SaveURL(ctx, "https://example.com", "a", storage.URLOptions{})
+	require.NoError(t, err)
+	_, err = s.AddRule(ctx, "a", routing.Rule{Type: routing.TypePlatform, Value: "ios", Target: "https://example.com/ios"})
+	require.NoError(t, err)
+
+	require.NoError(t, s.DeleteURL(ctx, "a"))
+
+	_, err = s.GetURL(ctx, "a")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	var rules int
+	require.NoError(t, s.db.QueryRow("SELECT COUNT(*) FROM url_rule").Scan(&rules))
+	assert.Zero(t, rules)
+
+	assert.ErrorIs(t, s.DeleteURL(ctx, "a"), storage.ErrURLNotFound)
+}
+
+func TestGetUsage(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	now := time.Now()
+	for alias, opts := range map[string]storage.URLOptions{
+		"active":  {OwnerID: 1, CreatedBy: "key:1"},
+		"expired": {OwnerID: 1, CreatedBy: "key:1", NotAfter: now.Add(-time.Hour)},
+		"used":    {OwnerID: 1, CreatedBy: "key:2", MaxClicks: 1},
+		"nouser":  {CreatedBy: "key:3"},
+	} {
+		_, err := s.SaveURL(ctx, "https://example.com", alias, opts)
+		require.NoError(t, err)
+	}
+	require.NoError(t, s.ConsumeClick(ctx, "used"))
+
+	// Одна ссылка создана в прошлом месяце
+	_, err = s.db.Exec("UPDATE url SET created_at = ? WHERE alias = 'active'", now.AddDate(0, -2, 0).Unix())
+	require.NoError(t, err)
+
+	since := now.AddDate(0, -1, 0)
+
+	u, err := s.GetUsage(ctx, 1, "", since, now)
+	require.NoError(t, err)
+	assert.Equal(t, storage.Usage{Active: 1, ThisMonth: 2}, u)
+
+	u, err = s.GetUsage(ctx, 0, "key:3", since, now)
+	require.NoError(t, err)
+	assert.Equal(t, storage.Usage{Active: 1, ThisMonth: 1}, u)
+
+	u, err = s.GetUsage(ctx, 0, "key:missing", since, now)
+	require.NoError(t, err)
+	assert.Equal(t, storage.Usage{}, u)
+
+	link, err := s.GetURL(ctx, "nouser")
+	require.NoError(t, err)
+	assert.Equal(t, "key:3", link.CreatedBy)
+}
+
+type observerFunc func(op string, d time.Duration, err error)
+
+func (f observerFunc) ObserveStorage(op string, d time.Duration, err error) { f(op, d, err) }
+
+func TestObserver(t *testing.T) {
+	ctx := context.Background()
+
+	type call struct {
+		op  string
+		err error
+	}
+	var calls []call
+
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"), WithObserver(observerFunc(func(op string, d time.Duration, err error) {
+		calls = append(calls, call{op, err})
+	})))
+	require.NoError(t, err)
+
+	_, err = s.SaveURL(ctx, "https://example.com", "alias", storage.URLOptions{})
+	require.NoError(t, err)
+	_, err = s.GetURL(ctx, "missing")
+	require.Error(t, err)
+
+	require.Len(t, calls, 2)
+	assert.Equal(t, "storage.sqlite.SaveURL", calls[0].op)
+	assert.NoError(t, calls[0].err)
+	assert.Equal(t, "storage.sqlite.GetURL", calls[1].op)
+	assert.ErrorIs(t, calls[1].err, storage.ErrURLNotFound)
+}
+
+func TestTracing(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	// Спаны хранилища - дочерние для span'а запроса из контекста
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "GET /{alias}")
+	_, err = s.GetURL(ctx, "missing")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+	parent.End()
+
+	spans := rec.Ended()
+	require.Len(t, spans, 2)
+
+	get := spans[0]
+	assert.Equal(t, "storage.sqlite.GetURL", get.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), get.Parent().SpanID())
+	assert.Contains(t, get.Attributes(), attribute.String("db.operation", "GetURL"))
+	assert.Equal(t, codes.Unset, get.Status().Code, "not found is not a storage failure")
+}
@@ -0,0 +1,110 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"main.go/internal/storage"
+)
+
+// SaveUser creates a user with a unique name.
+func (s *Storage) SaveUser(ctx context.Context, name, role string) (_ int64, err error) {
+	const op = "storage.sqlite.SaveUser"
+	ctx, done := s.track(ctx, op)
+	defer done(&err)
+
+	res, err := s.db.ExecContext(ctx, "INSERT INTO users(name, role, created_at) VALUES (?, ?, ?)", name, role, time.Now().Unix())
+	if err != nil {
+		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+		}
+		return 0, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get last insert id: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// ListUsers returns all users ordered by id.
+func (s *Storage) ListUsers(ctx context.Context) (_ []storage.User, err error) {
+	const op = "storage.sqlite.ListUsers"
+	ctx, done := s.track(ctx, op)
+	defer done(&err)
+
+	rows, err := s.db.QueryContext(ctx, "SELECT id, name, role, created_at FROM users ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("%s: query users: %w", op, err)
+	}
+	defer rows.Close()
+
+	var users []storage.User
+	for rows.Next() {
+		var (
+			u         storage.User
+			createdAt int64
+		)
+		if err := rows.Scan(&u.ID, &u.Name, &u.Role, &createdAt); err != nil {
+			return nil, fmt.Errorf("%s: scan user: %w", op, err)
+		}
+		u.CreatedAt = fromUnix(createdAt)
+		users = append(users, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: query users: %w", op, err)
+	}
+
+	return users, nil
+}
+
+// GetUser returns the user by id.
+func (s *Storage) GetUser(ctx context.Context, id int64) (_ storage.User, err error) {
+	const op = "storage.sqlite.GetUser"
+	ctx, done := s.track(ctx, op)
+	defer done(&err)
+
+	var (
+		u         storage.User
+		createdAt int64
+	)
+	err = s.db.QueryRowContext(ctx, "SELECT id, name, role, created_at FROM users WHERE id = ?", id).
+		Scan(&u.ID, &u.Name, &u.Role, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.User{}, storage.ErrUserNotFound
+	}
+	if err != nil {
+		return storage.User{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	u.CreatedAt = fromUnix(createdAt)
+
+	return u, nil
+}
+
+// SetUserRole changes the role of the user.
+func (s *Storage) SetUserRole(ctx context.Context, id int64, role string) (err error) {
+	const op = "storage.sqlite.SetUserRole"
+	ctx, done := s.track(ctx, op)
+	defer done(&err)
+
+	res, err := s.db.ExecContext(ctx, "UPDATE users SET role = ? WHERE id = ?", role, id)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: get rows affected: %w", op, err)
+	}
+	if n == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	return nil
+}
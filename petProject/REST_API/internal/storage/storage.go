@@ -2,9 +2,125 @@ package storage
 
 import (
 	"errors"
+	"time"
+
+	"main.go/internal/lib/routing"
+	"main.go/internal/lib/split"
+	"main.go/internal/lib/utm"
 )
 
 var (
-	ErrURLNotFound = errors.New("url not found")
-	ErrURLExists   = errors.New("url exists")
+	ErrURLNotFound  = errors.New("url not found")
+	ErrURLExists    = errors.New("url exists")
+	ErrURLGone      = errors.New("url click limit reached")
+	ErrRuleNotFound = errors.New("rule not found")
+
+	ErrAPIKeyNotFound = errors.New("api key not found")
+
+	ErrUserNotFound = errors.New("user not found")
+	ErrUserExists   = errors.New("user exists")
 )
+
+// Expected reports whether err is a normal outcome (не найдено, уже существует и т.п.),
+// а не сбой хранилища.
+func Expected(err error) bool {
+	for _, target := range []error{
+		ErrURLNotFound, ErrURLExists, ErrURLGone, ErrRuleNotFound,
+		ErrAPIKeyNotFound, ErrUserNotFound, ErrUserExists,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// URLOptions describes per-link redirect behaviour and ownership.
+type URLOptions struct {
+	ForwardQuery  bool // Добавлять query string входящего запроса к целевому URL
+	ForwardPath   bool // Добавлять хвост пути после alias к целевому URL
+	UTM           utm.Params
+	PasswordHash  string          // bcrypt-хеш пароля, пустой - ссылка без пароля
+	MaxClicks     int             // После стольких редиректов ссылка перестает работать, 0 - без ограничений
+	NotBefore     time.Time       // Ссылка начинает работать с этого момента, нулевое значение - сразу
+	NotAfter      time.Time       // Ссылка перестает работать в этот момент, нулевое значение - никогда
+	Variants      []split.Variant // A/B-тест: вместо URL выбирается один из вариантов по весу
+	StickyVariant bool            // Запоминать выбранный вариант в cookie посетителя
+	Interstitial  bool            // Всегда показывать страницу предпросмотра перед редиректом
+	OwnerID       int64           // Пользователь, создавший ссылку. 0 - ссылка без владельца, ей управляет создавший ключ
+	CreatedBy     string          // Subject ключа или токена, которым создана ссылка ("key:12"), для квот и ссылок без владельца
+	Private       bool            // Редирект только по подписанному alias ("abc123~sig")
+}
+
+// URL is a stored short link.
+type URL struct {
+	ID    int64
+	Alias string
+	URL   string
+	URLOptions
+	Clicks    int            // Сколько редиректов уже засчитано (только для ссылок с MaxClicks)
+	Rules     []routing.Rule // Условные редиректы по порядку проверки
+	CreatedAt time.Time      // Нулевое значение у ссылок, созданных до появления колонки
+}
+
+// ClickStats is the number of redirects of a link, in total and per A/B variant.
+type ClickStats struct {
+	Total    int64            `json:"total"`
+	Variants map[string]int64 `json:"variants,omitempty"`
+}
+
+// Exhausted reports whether the link has used up all of its clicks.
+func (u URL) Exhausted() bool {
+	return u.MaxClicks > 0 && u.Clicks >= u.MaxClicks
+}
+
+// Static reports whether the link always redirects to URL as is: без пароля, лимита,
+// расписания, правил, A/B-теста, предпросмотра и проброса запроса.
+func (u URL) Static() bool {
+	return u.PasswordHash == "" && !u.Private && u.MaxClicks == 0 && u.NotBefore.IsZero() && u.NotAfter.IsZero() &&
+		len(u.Rules) == 0 && len(u.Variants) == 0 && !u.Interstitial &&
+		!u.ForwardQuery && !u.ForwardPath && u.UTM.IsZero()
+}
+
+// APIKey is an issued API key. Сам ключ не хранится, только его хеш.
+type APIKey struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"user_id,omitempty"` // владелец ключа, 0 - ключ без пользователя
+	Name       string    `json:"name"`
+	Prefix     string    `json:"prefix"` // начало ключа, чтобы его можно было узнать
+	Scopes     []string  `json:"scopes"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at,omitzero"` // нулевое значение - бессрочный
+	LastUsedAt time.Time `json:"last_used_at,omitzero"`
+	RevokedAt  time.Time `json:"revoked_at,omitzero"`
+}
+
+// User is an account that owns links and API keys.
+type User struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"` // viewer, editor, admin
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Usage is how many links a user or API key has, for quotas.
+type Usage struct {
+	Active    int `json:"active"`     // ссылки, которые еще работают: не истекли и не исчерпали клики
+	ThisMonth int `json:"this_month"` // создано с начала текущего месяца (UTC)
+}
+
+// Owner identifies who may manage a link.
+type Owner struct {
+	UserID    int64
+	CreatedBy string
+}
+
+// ListFilter selects links for listing.
+type ListFilter struct {
+	OwnerID   int64  // Ссылки этого пользователя
+	CreatedBy string // При OwnerID == 0 - ссылки без владельца, созданные этим subject
+	AllOwners bool   // Игнорировать OwnerID и вернуть все ссылки
+	Limit     int
+	Offset    int
+}
@@ -14,7 +14,8 @@ import (
 )
 
 const (
-	host = "localhost:8080"
+	host   = "localhost:8080"
+	apiKey = "sk_local-bootstrap-change-me" // auth.bootstrap_key из config/local.yaml
 )
 
 func TestURLShortener_HappyPath(t *testing.T) { // Для понимания метода
@@ -29,7 +30,7 @@ func TestURLShortener_HappyPath(t *testing.T) { // Для понимания м
 			URL:   gofakeit.URL(),             // генерация url
 			Alias: random.NewRandomString(10), // Длина нашего сокр ссылки
 		}).
-		WithBasicAuth("myuser", "mypass").
+		WithHeader("X-API-Key", apiKey).
 		Expect().            // что ожидать от ответа
 		Status(200).         // ожидаем 200
 		JSON().              // Формируем его в json
@@ -81,7 +82,7 @@ func TestURLShortener_SaveRedirect(t *testing.T) { // Сценарий тест
 					URL:   tc.url,
 					Alias: tc.alias,
 				}).
-				WithBasicAuth("myuser", "mypass").
+				WithHeader("X-API-Key", apiKey).
 				Expect().Status(http.StatusOK).
 				JSON().Object()
 